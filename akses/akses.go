@@ -0,0 +1,121 @@
+// Package akses memetakan role ke izin (permission) yang disimpan di tabel
+// role_permissions.
+package akses
+
+import (
+	"sync"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+)
+
+// Kode izin yang diperiksa handler. Daftar lengkap beserta deskripsinya ada
+// di tabel permissions.
+const (
+	AbsensiCreateMapel = "absensi.create.mapel"
+	AbsensiCreateKelas = "absensi.create.kelas"
+	AbsensiReadAll     = "absensi.read.all"
+	AbsensiReadKelas   = "absensi.read.kelas"
+	AbsensiReadMapel   = "absensi.read.mapel"
+	AbsensiUpdateAll   = "absensi.update.all"
+	AbsensiUpdateOwn   = "absensi.update.own"
+	AbsensiUpdateKelas = "absensi.update.kelas"
+	AbsensiDeleteAll   = "absensi.delete.all"
+	AbsensiDeleteOwn   = "absensi.delete.own"
+	AbsensiDeleteKelas = "absensi.delete.kelas"
+	RBACManage         = "rbac.manage"
+	AdminManage        = "admin.manage"
+	AkunDisable        = "akun.disable"
+	SesiRevoke         = "sesi.revoke"
+	AkunResetPassword  = "akun.reset_password"
+	SiswaAssign        = "siswa.assign"
+	AbsensiExport      = "absensi.export"
+	GuruManage         = "guru.manage"
+	KelasManage        = "kelas.manage"
+	MapelManage        = "mapel.manage"
+	SiswaManage        = "siswa.manage"
+	OrangTuaManage     = "orang_tua.manage"
+	KalenderManage     = "kalender.manage"
+	NotifManage        = "notif.manage"
+	PengumumanRead     = "pengumuman.read"
+	PengumumanAll      = "pengumuman.create.all"
+	PengumumanKelas    = "pengumuman.create.kelas"
+)
+
+// Inti adalah izin administrasi yang harus selalu dimiliki minimal satu role;
+// tanpanya tidak ada yang bisa memulihkan pemetaan atau akun.
+var Inti = []string{RBACManage, AdminManage, AkunDisable, SesiRevoke}
+
+// Roles adalah semua role yang bisa diberi izin.
+var Roles = []string{"admin", "guru", "wali_kelas", "siswa", "orang_tua"}
+
+// RoleValid melaporkan apakah role dikenal.
+func RoleValid(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Pemetaan di-cache sebentar agar pemeriksaan izin tidak membaca database di
+// setiap request; instance lain melihat perubahan paling lambat setelah ttl.
+const ttl = time.Minute
+
+var (
+	mu     sync.RWMutex
+	peta   map[string]map[string]bool
+	dimuat time.Time
+)
+
+func pemetaan() (map[string]map[string]bool, error) {
+	mu.RLock()
+	if peta != nil && time.Since(dimuat) < ttl {
+		p := peta
+		mu.RUnlock()
+		return p, nil
+	}
+	mu.RUnlock()
+
+	var rows []models.RolePermission
+	if err := database.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	baru := map[string]map[string]bool{}
+	for _, r := range rows {
+		if baru[r.Role] == nil {
+			baru[r.Role] = map[string]bool{}
+		}
+		baru[r.Role][r.Permission] = true
+	}
+
+	mu.Lock()
+	peta, dimuat = baru, time.Now()
+	mu.Unlock()
+	return baru, nil
+}
+
+// Punya melaporkan apakah salah satu roles memiliki salah satu perms.
+func Punya(roles []string, perms ...string) (bool, error) {
+	p, err := pemetaan()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		for _, perm := range perms {
+			if p[r][perm] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Reset membuang cache setelah pemetaan diubah.
+func Reset() {
+	mu.Lock()
+	peta = nil
+	mu.Unlock()
+}
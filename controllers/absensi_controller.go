@@ -1,17 +1,22 @@
 package controllers
 
 import (
-	"context"
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"abs-be/akses"
 	"abs-be/database"
 	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
 
@@ -32,46 +37,32 @@ func CreateAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
-	roleVal, ok := c.Get("role")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "role tidak ditemukan di context")
-		return
-	}
-	role := roleVal.(string)
-
-	userIDVal, ok := c.Get("user_id")
+	// Pencatat absensi selalu akun guru sesi ini; guru_id tidak diambil dari
+	// body agar tidak bisa mengatasnamakan guru lain.
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "user_id tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	case float64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
+	if me.UserType != models.UserTypeGuru {
+		utils.ErrorResponse(c, http.StatusForbidden, "Hanya akun guru yang dapat mengisi absensi")
 		return
 	}
+	userID := me.ID
 
-	switch role {
-	case "guru":
-		if req.TipeAbsensi != "mapel" {
-			utils.ErrorResponse(c, http.StatusForbidden, "Guru hanya dapat mengisi absen mapel")
-			return
-		}
-	case "wali_kelas":
-		if req.TipeAbsensi != "kelas" {
-			utils.ErrorResponse(c, http.StatusForbidden, "Wali kelas hanya dapat mengisi absen kelas")
-			return
-		}
-	default:
-		utils.ErrorResponse(c, http.StatusForbidden, "Role tidak diizinkan")
+	// Izin dihitung dari semua role sesi, bukan role aktif: guru yang juga
+	// wali kelas bisa mengisi absen mapel maupun absen kelas.
+	izin := akses.AbsensiCreateMapel
+	if req.TipeAbsensi == "kelas" {
+		izin = akses.AbsensiCreateKelas
+	}
+	boleh, err := punyaIzin(c, izin)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa izin akses")
+		return
+	}
+	if !boleh {
+		utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk mengisi absen "+req.TipeAbsensi)
 		return
 	}
 
@@ -93,6 +84,22 @@ func CreateAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
+	if req.TipeAbsensi == "kelas" {
+		wali, err := waliDariKelas(userID, req.KelasID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Kelas tidak ditemukan")
+			return
+		}
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa wali kelas: "+err.Error())
+			return
+		}
+		if !wali {
+			utils.ErrorResponse(c, http.StatusForbidden, "Anda bukan wali kelas dari kelas yang diminta")
+			return
+		}
+	}
+
 	if req.TipeAbsensi == "mapel" {
 		if req.MapelID == nil {
 			utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id harus diisi untuk absen mapel")
@@ -134,11 +141,6 @@ func CreateAbsensiSiswa(c *gin.Context) {
 		}
 	}
 
-	guruIDForInsert := req.GuruID
-	if role == "guru" {
-		guruIDForInsert = userID
-	}
-
 	var exist models.AbsensiSiswa
 	q := database.DB.
 		Where("siswa_id = ? AND DATE(tanggal) = ? AND tipe_absensi = ? AND kelas_id = ?",
@@ -150,7 +152,7 @@ func CreateAbsensiSiswa(c *gin.Context) {
 		q = q.Where("mapel_id IS NULL")
 	}
 
-	if role == "guru" {
+	if req.TipeAbsensi == "mapel" {
 		q = q.Where("guru_id = ?", userID)
 	}
 
@@ -163,7 +165,7 @@ func CreateAbsensiSiswa(c *gin.Context) {
 		SiswaID:     req.SiswaID,
 		KelasID:     req.KelasID,
 		MapelID:     req.MapelID,
-		GuruID:      guruIDForInsert,
+		GuruID:      userID,
 		TipeAbsensi: req.TipeAbsensi,
 		Tanggal:     tanggal,
 		Status:      req.Status,
@@ -260,17 +262,6 @@ func UpdateAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
-	roleVal, ok := c.Get("role")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: role tidak ditemukan di context")
-		return
-	}
-	role, ok := roleVal.(string)
-	if !ok {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format role tidak valid")
-		return
-	}
-
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user_id tidak ditemukan di context")
@@ -292,25 +283,14 @@ func UpdateAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
-	switch role {
-	case "guru":
-		if absensi.GuruID != userID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk mengedit absensi ini")
-			return
-		}
-	case "wali_kelas":
-		var kelas models.Kelas
-		if err := database.DB.First(&kelas, absensi.KelasID).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data kelas terkait")
-			return
-		}
-		if kelas.WaliKelasID == nil || *kelas.WaliKelasID != userID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk mengedit absensi ini")
-			return
-		}
-	case "admin":
-	default:
-		utils.ErrorResponse(c, http.StatusForbidden, "Role tidak diizinkan")
+	boleh, err := bolehKelolaAbsensi(c, absensi, userID,
+		akses.AbsensiUpdateAll, akses.AbsensiUpdateOwn, akses.AbsensiUpdateKelas)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa izin akses")
+		return
+	}
+	if !boleh {
+		utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk mengedit absensi ini")
 		return
 	}
 
@@ -428,17 +408,6 @@ func DeleteAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
-	roleVal, ok := c.Get("role")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: role tidak ditemukan di context")
-		return
-	}
-	role, ok := roleVal.(string)
-	if !ok {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format role tidak valid")
-		return
-	}
-
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user_id tidak ditemukan di context")
@@ -460,26 +429,14 @@ func DeleteAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
-	switch role {
-	case "guru":
-		if absensi.GuruID != userID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk menghapus absensi ini")
-			return
-		}
-	case "wali_kelas":
-		var kelas models.Kelas
-		if err := database.DB.First(&kelas, absensi.KelasID).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data kelas terkait")
-			return
-		}
-		if kelas.WaliKelasID == nil || *kelas.WaliKelasID != userID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk menghapus absensi ini")
-			return
-		}
-	case "admin":
-
-	default:
-		utils.ErrorResponse(c, http.StatusForbidden, "Role tidak diizinkan")
+	boleh, err := bolehKelolaAbsensi(c, absensi, userID,
+		akses.AbsensiDeleteAll, akses.AbsensiDeleteOwn, akses.AbsensiDeleteKelas)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa izin akses")
+		return
+	}
+	if !boleh {
+		utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk menghapus absensi ini")
 		return
 	}
 
@@ -498,6 +455,9 @@ func ListStudentsForMapel(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id & kelas_id wajib")
 		return
 	}
+	if !cekCakupanAbsensi(c, kelasID, mapelID) {
+		return
+	}
 
 	var siswa []models.Siswa
 	if err := database.DB.Where("kelas_id = ?", kelasID).Find(&siswa).Error; err != nil {
@@ -524,6 +484,9 @@ func ListStudentsForKelas(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "kelas_id wajib")
 		return
 	}
+	if !cekCakupanAbsensi(c, kelasID, "") {
+		return
+	}
 
 	var siswa []models.Siswa
 	if err := database.DB.Where("kelas_id = ?", kelasID).Find(&siswa).Error; err != nil {
@@ -558,6 +521,9 @@ func RecapAbsensiMapel(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal salah")
 		return
 	}
+	if !cekCakupanAbsensi(c, kelasID, mapelID) {
+		return
+	}
 
 	type RecapAbsensiMapelResponse struct {
 		SiswaID     uint   `json:"siswa_id"`
@@ -613,6 +579,9 @@ func RecapAbsensiKelas(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal salah (gunakan YYYY-MM-DD)")
 		return
 	}
+	if !cekCakupanAbsensi(c, kelasID, "") {
+		return
+	}
 
 	type RecapAbsensiKelasResponse struct {
 		SiswaID     uint   `json:"siswa_id"`
@@ -665,6 +634,9 @@ func ExportRecapAbsensiMapelCSV(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal salah (gunakan YYYY-MM-DD)")
 		return
 	}
+	if !cekCakupanAbsensi(c, kelasID, mapelID) {
+		return
+	}
 
 	userIDVal, ok := c.Get("user_id")
 	var requesterID uint
@@ -732,7 +704,10 @@ func ExportRecapAbsensiMapelCSV(c *gin.Context) {
 	c.Header("Content-Type", "text/csv; charset=utf-8")
 	c.Writer.Write([]byte("\xEF\xBB\xBF"))
 
-	w := csv.NewWriter(c.Writer)
+	// salinan CSV untuk dilampirkan pada email notifikasi
+	var csvBuf bytes.Buffer
+	csvBuf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(io.MultiWriter(c.Writer, &csvBuf))
 	defer w.Flush()
 	if err := w.Write([]string{
 		"Nama Siswa", "Status", "Kelas", "Mapel", "Guru", "Tahun Ajaran", "Semester", "Tanggal",
@@ -766,21 +741,16 @@ func ExportRecapAbsensiMapelCSV(c *gin.Context) {
 	}
 
 	if requesterID != 0 {
-		go func(reqID uint, mid, kid uint, tanggal, filename string, rowsCount int) {
-			title := "Export Rekap Mapel Selesai"
-			body := fmt.Sprintf("Rekap absensi mapel untuk tanggal %s telah selesai (%s).", tanggal, filename)
-			payload := map[string]interface{}{
-				"type":         "export_rekap_mapel",
-				"mapel_id":     fmt.Sprintf("%d", mid),
-				"kelas_id":     fmt.Sprintf("%d", kid),
-				"tanggal":      tanggal,
-				"filename":     filename,
-				"record_count": fmt.Sprintf("%d", rowsCount),
-			}
-			if err := firebaseclient.SendNotify(context.Background(), "export_rekap_mapel", title, body, payload, []uint{reqID}); err != nil {
-				log.Printf("ExportMapel: SendNotify error for user %d: %v", reqID, err)
-			}
-		}(requesterID, mid, kid, tgl, filename, len(rows))
+		if err := enqueuePerRole(database.DB, notifikasi.ExportRekapMapel{
+			MapelID:     mid,
+			KelasID:     kid,
+			Tanggal:     tgl,
+			Filename:    filename,
+			RecordCount: len(rows),
+		}, map[string][]uint{currentRole(c): {requesterID}},
+			firebaseclient.Lampiran{Nama: filename, ContentType: "text/csv", Data: csvBuf.Bytes()}); err != nil {
+			log.Printf("ExportMapel: gagal menyimpan notifikasi: %v", err)
+		}
 	} else {
 		log.Printf("ExportMapel: user_id not found in context, skipping personal notification")
 	}
@@ -798,6 +768,9 @@ func ExportRecapAbsensiKelasCSV(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal salah (gunakan YYYY-MM-DD)")
 		return
 	}
+	if !cekCakupanAbsensi(c, kelasID, "") {
+		return
+	}
 
 	userIDVal, ok := c.Get("user_id")
 	var requesterID uint
@@ -860,7 +833,10 @@ func ExportRecapAbsensiKelasCSV(c *gin.Context) {
 	c.Header("Content-Type", "text/csv; charset=utf-8")
 	c.Writer.Write([]byte("\xEF\xBB\xBF"))
 
-	w := csv.NewWriter(c.Writer)
+	// salinan CSV untuk dilampirkan pada email notifikasi
+	var csvBuf bytes.Buffer
+	csvBuf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(io.MultiWriter(c.Writer, &csvBuf))
 	defer w.Flush()
 	if err := w.Write([]string{
 		"Nama Siswa", "Status", "Kelas", "Wali Kelas", "Tahun Ajaran", "Semester", "Tanggal",
@@ -893,20 +869,15 @@ func ExportRecapAbsensiKelasCSV(c *gin.Context) {
 	}
 
 	if requesterID != 0 {
-		go func(reqID uint, kid uint, tanggal, filename string, rowsCount int) {
-			title := "Export Rekap Kelas Selesai"
-			body := fmt.Sprintf("Rekap absensi kelas untuk tanggal %s telah selesai (%s).", tanggal, filename)
-			payload := map[string]interface{}{
-				"type":         "export_rekap_kelas",
-				"kelas_id":     fmt.Sprintf("%d", kid),
-				"tanggal":      tanggal,
-				"filename":     filename,
-				"record_count": fmt.Sprintf("%d", rowsCount),
-			}
-			if err := firebaseclient.SendNotify(context.Background(), "export_rekap_kelas", title, body, payload, []uint{reqID}); err != nil {
-				log.Printf("ExportKelas: SendNotify error for user %d: %v", reqID, err)
-			}
-		}(requesterID, kid, tgl, filename, len(rows))
+		if err := enqueuePerRole(database.DB, notifikasi.ExportRekapKelas{
+			KelasID:     kid,
+			Tanggal:     tgl,
+			Filename:    filename,
+			RecordCount: len(rows),
+		}, map[string][]uint{currentRole(c): {requesterID}},
+			firebaseclient.Lampiran{Nama: filename, ContentType: "text/csv", Data: csvBuf.Bytes()}); err != nil {
+			log.Printf("ExportKelas: gagal menyimpan notifikasi: %v", err)
+		}
 	} else {
 		log.Printf("ExportKelas: user_id not found in context, skipping personal notification")
 	}
@@ -948,34 +919,41 @@ func GetAbsensi(c *gin.Context) {
 	}
 	dateStr := tanggal.Format("2006-01-02")
 
-	roleVal, ok := c.Get("role")
+	userID, ok := currentUserID(c)
 	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: role tidak ditemukan di context")
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user_id tidak ditemukan di context")
 		return
 	}
-	role, ok := roleVal.(string)
-	if !ok {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format role tidak valid")
-		return
+
+	var kelasID, mapelID uint
+	if kelasIDStr != "" {
+		kid, err := strconv.ParseUint(kelasIDStr, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "kelas_id tidak valid")
+			return
+		}
+		kelasID = uint(kid)
+	}
+	if mapelIDStr != "" {
+		mid, err := strconv.ParseUint(mapelIDStr, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id tidak valid")
+			return
+		}
+		mapelID = uint(mid)
 	}
 
-	userIDVal, ok := c.Get("user_id")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user_id tidak ditemukan di context")
+	cakupan, err := cakupanBacaAbsensi(c, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa izin akses: "+err.Error())
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	case float64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
+	if !cakupan.semua && len(cakupan.kelasWali) == 0 && len(cakupan.pengajaran) == 0 {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda belum ditetapkan sebagai wali kelas atau pengajar kelas apapun")
+		return
+	}
+	if kelasID != 0 && !cakupan.memuatKelas(kelasID) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda bukan wali kelas atau pengajar di kelas yang diminta")
 		return
 	}
 
@@ -996,132 +974,14 @@ func GetAbsensi(c *gin.Context) {
 			return
 		}
 	}
-
-	switch role {
-	case "admin":
-		if kelasIDStr != "" {
-			if kid, err := strconv.ParseUint(kelasIDStr, 10, 64); err == nil {
-				where = where.Where("absensi_siswas.kelas_id = ?", uint(kid))
-			} else {
-				utils.ErrorResponse(c, http.StatusBadRequest, "kelas_id tidak valid")
-				return
-			}
-		}
-		if mapelIDStr != "" {
-			if mid, err := strconv.ParseUint(mapelIDStr, 10, 64); err == nil {
-				where = where.Where("absensi_siswas.mapel_id = ?", uint(mid))
-			} else {
-				utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id tidak valid")
-				return
-			}
-		}
-	case "wali_kelas":
-		var kelas models.Kelas
-		if kelasIDStr == "" {
-			if err := db.Where("wali_kelas_id = ?", userID).First(&kelas).Error; err != nil {
-				utils.ErrorResponse(c, http.StatusForbidden, "Anda belum ditetapkan sebagai wali kelas (tidak ada kelas ditemukan untuk user ini)")
-				return
-			}
-			where = where.Where("absensi_siswas.kelas_id = ?", kelas.ID)
-		} else {
-			kid, err := strconv.ParseUint(kelasIDStr, 10, 64)
-			if err != nil {
-				utils.ErrorResponse(c, http.StatusBadRequest, "kelas_id tidak valid")
-				return
-			}
-			if err := db.First(&kelas, uint(kid)).Error; err != nil {
-				utils.ErrorResponse(c, http.StatusNotFound, "kelas tidak ditemukan")
-				return
-			}
-			if kelas.WaliKelasID == nil || *kelas.WaliKelasID != userID {
-				utils.ErrorResponse(c, http.StatusForbidden, "Anda bukan wali kelas untuk kelas yang diminta")
-				return
-			}
-			where = where.Where("absensi_siswas.kelas_id = ?", uint(kid))
-		}
-		if mapelIDStr != "" {
-			mid, err := strconv.ParseUint(mapelIDStr, 10, 64)
-			if err != nil {
-				utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id tidak valid")
-				return
-			}
-			where = where.Where("absensi_siswas.mapel_id = ?", uint(mid))
-		}
-	case "guru":
-		curTA := getTahunAjaranNow()
-		curSem := getSemesterNow()
-
-		if kelasIDStr != "" {
-			kid, err := strconv.ParseUint(kelasIDStr, 10, 64)
-			if err != nil {
-				utils.ErrorResponse(c, http.StatusBadRequest, "kelas_id tidak valid")
-				return
-			}
-			mapelIDs, err := getMapelIDsByGuruAndKelas(db, userID, uint(kid), curTA, curSem)
-			if err != nil {
-				utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa data pengajaran guru: "+err.Error())
-				return
-			}
-			if len(mapelIDs) == 0 {
-				utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengajar di kelas yang diminta")
-				return
-			}
-			if mapelIDStr != "" {
-				mid, err := strconv.ParseUint(mapelIDStr, 10, 64)
-				if err != nil {
-					utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id tidak valid")
-					return
-				}
-				found := false
-				for _, m := range mapelIDs {
-					if m == uint(mid) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengajar mapel ini di kelas yang diminta")
-					return
-				}
-				where = where.Where("absensi_siswas.kelas_id = ? AND absensi_siswas.mapel_id = ?", uint(kid), uint(mid))
-			} else {
-				where = where.Where("absensi_siswas.kelas_id = ? AND absensi_siswas.mapel_id IN ?", uint(kid), mapelIDs)
-			}
-		} else {
-			mapelIDsAll, kelasIDsAll, err := getMapelAndKelasByGuru(db, userID, curTA, curSem)
-			if err != nil {
-				utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa data pengajaran guru: "+err.Error())
-				return
-			}
-			if len(kelasIDsAll) == 0 {
-				utils.ErrorResponse(c, http.StatusForbidden, "Anda belum ditetapkan mengajar di kelas apapun")
-				return
-			}
-			if mapelIDStr != "" {
-				mid, err := strconv.ParseUint(mapelIDStr, 10, 64)
-				if err != nil {
-					utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id tidak valid")
-					return
-				}
-				found := false
-				for _, m := range mapelIDsAll {
-					if m == uint(mid) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengajar mapel ini")
-					return
-				}
-				where = where.Where("absensi_siswas.mapel_id = ? AND absensi_siswas.kelas_id IN ?", uint(mid), kelasIDsAll)
-			} else {
-				where = where.Where("absensi_siswas.kelas_id IN ? AND absensi_siswas.mapel_id IN ?", kelasIDsAll, mapelIDsAll)
-			}
-		}
-	default:
-		utils.ErrorResponse(c, http.StatusForbidden, "role tidak diizinkan")
-		return
+	if kelasID != 0 {
+		where = where.Where("absensi_siswas.kelas_id = ?", kelasID)
+	}
+	if mapelID != 0 {
+		where = where.Where("absensi_siswas.mapel_id = ?", mapelID)
+	}
+	if !cakupan.semua {
+		where = cakupan.terapkan(where)
 	}
 
 	var results []models.AbsensiResult
@@ -1164,12 +1024,14 @@ func getMapelIDsByGuruAndKelas(db *gorm.DB, guruID uint, kelasID uint, ta string
 	return out, nil
 }
 
-func getMapelAndKelasByGuru(db *gorm.DB, guruID uint, ta string, semester string) ([]uint, []uint, error) {
+// getPengajaranGuru mengembalikan pasangan (kelas_id, mapel_id) yang diajar
+// guru, untuk argumen "(kelas_id, mapel_id) IN ?".
+func getPengajaranGuru(db *gorm.DB, guruID uint, ta string, semester string) ([][]interface{}, error) {
 	var rows []struct {
 		MapelID uint `gorm:"column:mapel_id"`
 		KelasID uint `gorm:"column:kelas_id"`
 	}
-	q := db.Table("guru_mapel_kelas").Select("mapel_id, kelas_id").Where("guru_id = ?", guruID)
+	q := db.Table("guru_mapel_kelas").Select("DISTINCT kelas_id, mapel_id").Where("guru_id = ?", guruID)
 	if ta != "" {
 		q = q.Where("tahun_ajaran = ?", ta)
 	}
@@ -1177,23 +1039,143 @@ func getMapelAndKelasByGuru(db *gorm.DB, guruID uint, ta string, semester string
 		q = q.Where("semester = ?", semester)
 	}
 	if err := q.Find(&rows).Error; err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	mapelSet := map[uint]struct{}{}
-	kelasSet := map[uint]struct{}{}
+	hasil := make([][]interface{}, 0, len(rows))
 	for _, r := range rows {
-		mapelSet[r.MapelID] = struct{}{}
-		kelasSet[r.KelasID] = struct{}{}
+		hasil = append(hasil, []interface{}{r.KelasID, r.MapelID})
 	}
-	mapelIDs := make([]uint, 0, len(mapelSet))
-	kelasIDs := make([]uint, 0, len(kelasSet))
-	for k := range mapelSet {
-		mapelIDs = append(mapelIDs, k)
+	return hasil, nil
+}
+
+// cakupanAbsensi adalah gabungan absensi yang boleh dilihat dari semua izin
+// baca milik role-role sesi: semua absensi, kelas yang diwali, dan/atau
+// pasangan kelas-mapel yang diajar semester ini.
+type cakupanAbsensi struct {
+	semua      bool
+	kelasWali  []uint
+	pengajaran [][]interface{}
+}
+
+func cakupanBacaAbsensi(c *gin.Context, userID uint) (cakupanAbsensi, error) {
+	var hasil cakupanAbsensi
+	var err error
+	if hasil.semua, err = punyaIzin(c, akses.AbsensiReadAll); err != nil || hasil.semua {
+		return hasil, err
+	}
+	if ok, err := punyaIzin(c, akses.AbsensiReadKelas); err != nil {
+		return hasil, err
+	} else if ok {
+		if err := database.DB.Model(&models.Kelas{}).Where("wali_kelas_id = ?", userID).
+			Pluck("id", &hasil.kelasWali).Error; err != nil {
+			return hasil, err
+		}
 	}
-	for k := range kelasSet {
-		kelasIDs = append(kelasIDs, k)
+	if ok, err := punyaIzin(c, akses.AbsensiReadMapel); err != nil {
+		return hasil, err
+	} else if ok {
+		if hasil.pengajaran, err = getPengajaranGuru(database.DB, userID, getTahunAjaranNow(), getSemesterNow()); err != nil {
+			return hasil, err
+		}
 	}
-	return mapelIDs, kelasIDs, nil
+	return hasil, nil
+}
+
+func (k cakupanAbsensi) memuatKelas(kelasID uint) bool {
+	if k.semua {
+		return true
+	}
+	for _, id := range k.kelasWali {
+		if id == kelasID {
+			return true
+		}
+	}
+	for _, p := range k.pengajaran {
+		if p[0] == kelasID {
+			return true
+		}
+	}
+	return false
+}
+
+// memuatAbsenKelas melaporkan apakah absensi tipe kelas kelasID termasuk
+// cakupan; pengajar mapel tidak melihat absen kelas.
+func (k cakupanAbsensi) memuatAbsenKelas(kelasID uint) bool {
+	if k.semua {
+		return true
+	}
+	for _, id := range k.kelasWali {
+		if id == kelasID {
+			return true
+		}
+	}
+	return false
+}
+
+// memuatMapel melaporkan apakah absensi mapel mapelID di kelasID termasuk
+// cakupan.
+func (k cakupanAbsensi) memuatMapel(kelasID, mapelID uint) bool {
+	if k.memuatAbsenKelas(kelasID) {
+		return true
+	}
+	for _, p := range k.pengajaran {
+		if p[0] == kelasID && p[1] == mapelID {
+			return true
+		}
+	}
+	return false
+}
+
+// cekCakupanAbsensi mengurai kelas_id (dan mapel_id bila tidak kosong) lalu
+// memastikan keduanya termasuk cakupan baca sesi. Bila tidak, respons error
+// sudah ditulis dan hasilnya false.
+func cekCakupanAbsensi(c *gin.Context, kelasIDStr, mapelIDStr string) bool {
+	kelasID, err := strconv.ParseUint(kelasIDStr, 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "kelas_id tidak valid")
+		return false
+	}
+	var mapelID uint64
+	if mapelIDStr != "" {
+		if mapelID, err = strconv.ParseUint(mapelIDStr, 10, 64); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "mapel_id tidak valid")
+			return false
+		}
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user_id tidak ditemukan di context")
+		return false
+	}
+	cakupan, err := cakupanBacaAbsensi(c, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa izin akses: "+err.Error())
+		return false
+	}
+	boleh := cakupan.memuatAbsenKelas(uint(kelasID))
+	if mapelIDStr != "" {
+		boleh = cakupan.memuatMapel(uint(kelasID), uint(mapelID))
+	}
+	if !boleh {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda bukan wali kelas atau pengajar di kelas/mapel yang diminta")
+		return false
+	}
+	return true
+}
+
+// terapkan membatasi query absensi_siswas ke gabungan cakupan.
+func (k cakupanAbsensi) terapkan(q *gorm.DB) *gorm.DB {
+	var syarat []string
+	var args []interface{}
+	if len(k.kelasWali) > 0 {
+		syarat = append(syarat, "absensi_siswas.kelas_id IN ?")
+		args = append(args, k.kelasWali)
+	}
+	if len(k.pengajaran) > 0 {
+		syarat = append(syarat, "(absensi_siswas.kelas_id, absensi_siswas.mapel_id) IN ?")
+		args = append(args, k.pengajaran)
+	}
+	return q.Where("("+strings.Join(syarat, " OR ")+")", args...)
 }
 
 func GetAbsensiByID(c *gin.Context) {
@@ -1211,17 +1193,6 @@ func GetAbsensiByID(c *gin.Context) {
 		return
 	}
 
-	roleVal, ok := c.Get("role")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "role tidak ditemukan di context")
-		return
-	}
-	role, ok := roleVal.(string)
-	if !ok {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format role tidak valid")
-		return
-	}
-
 	userIDVal, ok := c.Get("user_id")
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "user_id tidak ditemukan di context")
@@ -1242,49 +1213,82 @@ func GetAbsensiByID(c *gin.Context) {
 		return
 	}
 
-	switch role {
-	case "admin":
-	case "wali_kelas":
-		var kelas models.Kelas
-		if err := database.DB.First(&kelas, absensi.KelasID).Error; err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data kelas")
-			return
-		}
-		if kelas.WaliKelasID == nil || *kelas.WaliKelasID != userID {
-			utils.ErrorResponse(c, http.StatusForbidden, "Anda bukan wali kelas untuk absensi ini")
-			return
-		}
-	case "guru":
-		curTA := getTahunAjaranNow()
-		curSem := getSemesterNow()
-		mapelIDs, err := getMapelIDsByGuruAndKelas(database.DB, userID, absensi.KelasID, curTA, curSem)
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa hubungan guru/mapel: "+err.Error())
-			return
-		}
-
-		if absensi.MapelID != nil {
-			found := false
-			for _, mid := range mapelIDs {
-				if mid == *absensi.MapelID {
-					found = true
-					break
-				}
-			}
-			if !found {
-				utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengajar mapel ini di kelas terkait")
-				return
-			}
-		} else {
-			if len(mapelIDs) == 0 {
-				utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengajar di kelas ini")
-				return
-			}
-		}
-	default:
-		utils.ErrorResponse(c, http.StatusForbidden, "Role tidak diizinkan")
+	boleh, err := bolehLihatAbsensi(c, absensi, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa hubungan guru/kelas: "+err.Error())
+		return
+	}
+	if !boleh {
+		utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk melihat absensi ini")
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Detail absensi", absensi)
 }
+
+// waliDariKelas melaporkan apakah userID adalah wali kelas kelasID.
+func waliDariKelas(userID, kelasID uint) (bool, error) {
+	var kelas models.Kelas
+	if err := database.DB.First(&kelas, kelasID).Error; err != nil {
+		return false, err
+	}
+	return kelas.WaliKelasID != nil && *kelas.WaliKelasID == userID, nil
+}
+
+// semesterBerjalan melaporkan apakah absensi tercatat pada semester ini.
+func semesterBerjalan(absensi models.AbsensiSiswa) bool {
+	return absensi.TahunAjaran == getTahunAjaranNow() && absensi.Semester == getSemesterNow()
+}
+
+// bolehKelolaAbsensi memeriksa izin mengubah/menghapus absensi. semua
+// berlaku untuk absensi mana pun; milik (absensi yang dicatat sendiri) dan
+// kelas (absensi kelas yang diwali) hanya berlaku pada semester berjalan.
+func bolehKelolaAbsensi(c *gin.Context, absensi models.AbsensiSiswa, userID uint, semua, milik, kelas string) (bool, error) {
+	if ok, err := punyaIzin(c, semua); err != nil || ok {
+		return ok, err
+	}
+	if !semesterBerjalan(absensi) {
+		return false, nil
+	}
+	if absensi.GuruID == userID {
+		if ok, err := punyaIzin(c, milik); err != nil || ok {
+			return ok, err
+		}
+	}
+	if ok, err := punyaIzin(c, kelas); err != nil || !ok {
+		return false, err
+	}
+	return waliDariKelas(userID, absensi.KelasID)
+}
+
+// bolehLihatAbsensi memeriksa izin melihat absensi: semua absensi, absensi
+// kelas yang diwali, atau absensi mapel yang diajar semester ini.
+func bolehLihatAbsensi(c *gin.Context, absensi models.AbsensiSiswa, userID uint) (bool, error) {
+	if ok, err := punyaIzin(c, akses.AbsensiReadAll); err != nil || ok {
+		return ok, err
+	}
+	if ok, err := punyaIzin(c, akses.AbsensiReadKelas); err != nil {
+		return false, err
+	} else if ok {
+		wali, err := waliDariKelas(userID, absensi.KelasID)
+		if err != nil || wali {
+			return wali, err
+		}
+	}
+	if ok, err := punyaIzin(c, akses.AbsensiReadMapel); err != nil || !ok {
+		return false, err
+	}
+	mapelIDs, err := getMapelIDsByGuruAndKelas(database.DB, userID, absensi.KelasID, getTahunAjaranNow(), getSemesterNow())
+	if err != nil {
+		return false, err
+	}
+	if absensi.MapelID == nil {
+		return len(mapelIDs) > 0, nil
+	}
+	for _, mid := range mapelIDs {
+		if mid == *absensi.MapelID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
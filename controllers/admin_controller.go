@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"abs-be/akses"
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errUndanganTidakValid = errors.New("undangan tidak valid atau sudah dipakai")
+	errUndanganKadaluarsa = errors.New("undangan sudah kadaluarsa")
+	errUndanganEmail      = errors.New("email tidak sesuai dengan undangan")
+	errAdminSudahAda      = errors.New("admin pertama sudah dibuat")
+)
+
+// ttlUndanganAdmin dibaca dari ADMIN_INVITE_TTL_JAM (default 72 jam).
+func ttlUndanganAdmin() time.Duration {
+	return time.Duration(envAngka("ADMIN_INVITE_TTL_JAM", 72)) * time.Hour
+}
+
+func buatAdmin(tx *gorm.DB, nama, email, password string, mustChange bool) (models.Admin, error) {
+	if err := pastikanEmailBebas(tx, email, models.Principal{}); err != nil {
+		return models.Admin{}, err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return models.Admin{}, err
+	}
+	admin := models.Admin{Nama: nama, Email: email, Password: hashed, MustChangePassword: mustChange}
+	return admin, tx.Create(&admin).Error
+}
+
+// BootstrapAdmin membuat admin pertama. Hanya bisa dipakai selama belum ada
+// admin sama sekali dan dengan token dari env ADMIN_BOOTSTRAP_TOKEN.
+func BootstrapAdmin(c *gin.Context) {
+	var req requests.AdminBootstrapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	rahasia := os.Getenv("ADMIN_BOOTSTRAP_TOKEN")
+	if rahasia == "" {
+		utils.ErrorResponse(c, http.StatusForbidden, "bootstrap admin tidak diaktifkan")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.BootstrapToken), []byte(rahasia)) != 1 {
+		utils.ErrorResponse(c, http.StatusForbidden, "bootstrap token salah")
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		utils.ErrorResponse(c, http.StatusBadRequest, "password dan konfirmasi tidak cocok")
+		return
+	}
+
+	var admin models.Admin
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var jumlah int64
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Model(&models.Admin{}).Count(&jumlah).Error; err != nil {
+			return err
+		}
+		if jumlah > 0 {
+			return errAdminSudahAda
+		}
+		var err error
+		admin, err = buatAdmin(tx, req.Nama, req.Email, req.Password, false)
+		return err
+	})
+	if errors.Is(err, errAdminSudahAda) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan admin")
+		return
+	}
+
+	resp, err := buatSesi(c, admin.ID, "admin", gin.H{
+		"admin": requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "admin pertama berhasil dibuat", resp)
+}
+
+// RegisterAdmin mendaftarkan admin baru memakai undangan sekali pakai.
+func RegisterAdmin(c *gin.Context) {
+	var req requests.AdminRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	if req.Password != req.ConfirmPassword {
+		utils.ErrorResponse(c, http.StatusBadRequest, "password dan konfirmasi tidak cocok")
+		return
+	}
+
+	var admin models.Admin
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var undangan models.AdminInvite
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL", utils.HashToken(req.InviteToken)).
+			First(&undangan).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errUndanganTidakValid
+			}
+			return err
+		}
+		now := time.Now()
+		if now.After(undangan.ExpiresAt) {
+			return errUndanganKadaluarsa
+		}
+		if undangan.Email != "" && !strings.EqualFold(undangan.Email, req.Email) {
+			return errUndanganEmail
+		}
+		var err error
+		if admin, err = buatAdmin(tx, req.Nama, req.Email, req.Password, false); err != nil {
+			return err
+		}
+		return tx.Model(&undangan).Updates(map[string]interface{}{"used_at": now, "used_by": admin.ID}).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errUndanganTidakValid), errors.Is(err, errUndanganKadaluarsa), errors.Is(err, errUndanganEmail):
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, errEmailTerdaftar):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan admin")
+		return
+	}
+
+	resp, err := buatSesi(c, admin.ID, "admin", gin.H{
+		"admin": requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "registrasi admin berhasil", resp)
+}
+
+// CreateAdminInvite membuat undangan admin. Token hanya ditampilkan sekali.
+func CreateAdminInvite(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	var req requests.AdminInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	ttl := ttlUndanganAdmin()
+	if req.BerlakuJam > 0 {
+		ttl = time.Duration(req.BerlakuJam) * time.Hour
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat undangan")
+		return
+	}
+	undangan := models.AdminInvite{
+		TokenHash:  utils.HashToken(token),
+		Email:      req.Email,
+		DibuatOleh: me.ID,
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+	if err := database.DB.Create(&undangan).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan undangan")
+		return
+	}
+
+	data := gin.H{"undangan": undangan, "token": token}
+	// ADMIN_INVITE_URL, mis. https://abs.example/admin/daftar, dipakai
+	// untuk membentuk tautan undangan siap kirim.
+	if base := os.Getenv("ADMIN_INVITE_URL"); base != "" {
+		data["link"] = base + "?token=" + token
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "undangan admin dibuat", data)
+}
+
+// GetAdminInvites menampilkan undangan admin terbaru lebih dulu.
+func GetAdminInvites(c *gin.Context) {
+	var rows []models.AdminInvite
+	q := database.DB.Order("id DESC")
+	if c.Query("aktif") == "1" {
+		q = q.Where("used_at IS NULL AND expires_at > ?", time.Now())
+	}
+	if err := q.Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil undangan")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "daftar undangan admin", rows)
+}
+
+// DeleteAdminInvite membatalkan undangan yang belum dipakai.
+func DeleteAdminInvite(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	res := database.DB.Where("id = ? AND used_at IS NULL", id).Delete(&models.AdminInvite{})
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membatalkan undangan")
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "undangan tidak ditemukan atau sudah dipakai")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "undangan dibatalkan", nil)
+}
+
+func GetAdmins(c *gin.Context) {
+	var admins []models.Admin
+	if err := database.DB.Order("nama").Find(&admins).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil data admin")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "daftar admin", admins)
+}
+
+func GetAdminByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	var admin models.Admin
+	if err := database.DB.First(&admin, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "admin tidak ditemukan")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "berhasil mengambil data admin", admin)
+}
+
+// CreateAdmin membuat admin baru oleh admin lain; password awal wajib
+// diganti saat login pertama.
+func CreateAdmin(c *gin.Context) {
+	var req requests.CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	var admin models.Admin
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		admin, err = buatAdmin(tx, req.Nama, req.Email, req.Password, true)
+		return err
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan admin")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "admin berhasil dibuat", admin)
+}
+
+func UpdateAdmin(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	var req requests.UpdateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	var admin models.Admin
+	if err := database.DB.First(&admin, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "admin tidak ditemukan")
+		return
+	}
+
+	gantiEmail := req.Email != "" && req.Email != admin.Email
+	if gantiEmail {
+		admin.Email = req.Email
+	}
+	if req.Nama != "" {
+		admin.Nama = req.Nama
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if gantiEmail {
+			if err := pastikanEmailBebas(tx, admin.Email, models.Principal{UserType: models.UserTypeAdmin, ID: admin.ID}); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&admin).Error
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memperbarui admin")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "admin berhasil diperbarui", admin)
+}
+
+// cekBukanAdminTerakhir menolak tindakan yang akan membuat sistem tanpa admin
+// aktif, termasuk terhadap akun sendiri.
+func cekBukanAdminTerakhir(c *gin.Context, p models.Principal) bool {
+	if p.UserType != models.UserTypeAdmin {
+		return true
+	}
+	if me, ok := currentPrincipal(c); ok && me == p {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tidak bisa menonaktifkan atau menghapus akun sendiri")
+		return false
+	}
+	var lain int64
+	if err := database.DB.Model(&models.Admin{}).
+		Where("id <> ? AND disabled_at IS NULL", p.ID).Count(&lain).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa admin aktif")
+		return false
+	}
+	if lain == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "harus tersisa setidaknya satu admin aktif")
+		return false
+	}
+	return true
+}
+
+// cekBolehKelolaAkun menolak tindakan terhadap akun admin oleh pemanggil
+// tanpa izin admin.manage, sehingga izin reset password atau buka kunci
+// tidak bisa dipakai untuk mengambil alih akun admin.
+func cekBolehKelolaAkun(c *gin.Context, p models.Principal) bool {
+	if p.UserType != models.UserTypeAdmin {
+		return true
+	}
+	boleh, err := punyaIzin(c, akses.AdminManage)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa izin akses")
+		return false
+	}
+	if !boleh {
+		utils.ErrorResponse(c, http.StatusForbidden, "akses ditolak: membutuhkan izin "+akses.AdminManage+" untuk akun admin")
+		return false
+	}
+	return true
+}
+
+func DeleteAdmin(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	p := models.Principal{UserType: models.UserTypeAdmin, ID: uint(id)}
+	var admin models.Admin
+	if err := database.DB.First(&admin, p.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "admin tidak ditemukan")
+		return
+	}
+	if !cekBukanAdminTerakhir(c, p) {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := cabutSesiUser(tx, p, 0); err != nil {
+			return err
+		}
+		return tx.Delete(&admin).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menghapus admin")
+		return
+	}
+	lepasSemuaDevice(p)
+	utils.SuccessResponse(c, http.StatusOK, "admin berhasil dihapus", nil)
+}
+
+// principalParam membaca :user_type/:id dan memastikan akunnya ada.
+func principalParam(c *gin.Context) (models.Principal, bool) {
+	p := models.Principal{UserType: c.Param("user_type")}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 || models.TabelAkun(p.UserType) == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "user tidak valid")
+		return p, false
+	}
+	p.ID = uint(id)
+	if _, err := ambilAkunPassword(database.DB, p); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "akun tidak ditemukan")
+		return p, false
+	}
+	return p, true
+}
+
+// DisableAkun menonaktifkan akun jenis apa pun dan mengakhiri semua sesinya.
+func DisableAkun(c *gin.Context) {
+	p, ok := principalParam(c)
+	if !ok || !cekBukanAdminTerakhir(c, p) {
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(models.TabelAkun(p.UserType)).
+			Where("id = ? AND disabled_at IS NULL", p.ID).
+			Update("disabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return cabutSesiUser(tx, p, 0)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menonaktifkan akun")
+		return
+	}
+	lepasSemuaDevice(p)
+	utils.SuccessResponse(c, http.StatusOK, "akun dinonaktifkan", gin.H{"user_type": p.UserType, "user_id": p.ID})
+}
+
+// EnableAkun mengaktifkan kembali akun yang dinonaktifkan.
+func EnableAkun(c *gin.Context) {
+	p, ok := principalParam(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Table(models.TabelAkun(p.UserType)).
+		Where("id = ?", p.ID).
+		Update("disabled_at", nil).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengaktifkan akun")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "akun diaktifkan kembali", gin.H{"user_type": p.UserType, "user_id": p.ID})
+}
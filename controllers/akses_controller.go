@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"abs-be/akses"
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errIzinIntiHabis = errors.New("izin inti harus tetap dimiliki minimal satu role")
+
+type permissionDenganRole struct {
+	models.Permission
+	Roles []string `json:"roles"`
+}
+
+// GetPermissions menampilkan semua izin beserta role yang memilikinya.
+func GetPermissions(c *gin.Context) {
+	var perms []models.Permission
+	if err := database.DB.Order("kode").Find(&perms).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil daftar izin")
+		return
+	}
+	var rows []models.RolePermission
+	if err := database.DB.Order("role").Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pemetaan role")
+		return
+	}
+	roles := map[string][]string{}
+	for _, r := range rows {
+		roles[r.Permission] = append(roles[r.Permission], r.Role)
+	}
+
+	hasil := make([]permissionDenganRole, 0, len(perms))
+	for _, p := range perms {
+		rs := roles[p.Kode]
+		if rs == nil {
+			rs = []string{}
+		}
+		hasil = append(hasil, permissionDenganRole{Permission: p, Roles: rs})
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Daftar izin", hasil)
+}
+
+// GetRolePermissions menampilkan izin yang dimiliki setiap role.
+func GetRolePermissions(c *gin.Context) {
+	var rows []models.RolePermission
+	if err := database.DB.Order("permission").Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pemetaan role")
+		return
+	}
+	hasil := make(map[string][]string, len(akses.Roles))
+	for _, r := range akses.Roles {
+		hasil[r] = []string{}
+	}
+	for _, r := range rows {
+		hasil[r.Role] = append(hasil[r.Role], r.Permission)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pemetaan role ke izin", hasil)
+}
+
+// SetRolePermissions mengganti seluruh izin sebuah role.
+func SetRolePermissions(c *gin.Context) {
+	role := c.Param("role")
+	if !akses.RoleValid(role) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Role tidak dikenal")
+		return
+	}
+	var req requests.RolePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	unik := map[string]bool{}
+	for _, p := range req.Permissions {
+		unik[p] = true
+	}
+	perms := make([]string, 0, len(unik))
+	for p := range unik {
+		perms = append(perms, p)
+	}
+	sort.Strings(perms)
+
+	// Admin tidak boleh kehilangan izin mengelola pemetaan, agar tidak ada
+	// yang terkunci di luar.
+	if role == "admin" && !unik[akses.RBACManage] {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Izin "+akses.RBACManage+" tidak boleh dicabut dari admin")
+		return
+	}
+
+	if len(perms) > 0 {
+		var ada int64
+		if err := database.DB.Model(&models.Permission{}).Where("kode IN ?", perms).Count(&ada).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa izin")
+			return
+		}
+		if int(ada) != len(perms) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Terdapat izin yang tidak dikenal")
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := cekIzinIntiTersisa(tx, role, unik); err != nil {
+			return err
+		}
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(perms) == 0 {
+			return nil
+		}
+		rows := make([]models.RolePermission, 0, len(perms))
+		for _, p := range perms {
+			rows = append(rows, models.RolePermission{Role: role, Permission: p})
+		}
+		return tx.Create(&rows).Error
+	})
+	if errors.Is(err, errIzinIntiHabis) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan izin role")
+		return
+	}
+	akses.Reset()
+
+	utils.SuccessResponse(c, http.StatusOK, "Izin role diperbarui", gin.H{
+		"role":        role,
+		"permissions": perms,
+	})
+}
+
+// cekIzinIntiTersisa menolak perubahan yang membuat salah satu izin inti
+// tidak dimiliki role mana pun. Baris izin inti dikunci agar dua perubahan
+// bersamaan tidak sama-sama mencabut pemegang terakhirnya.
+func cekIzinIntiTersisa(tx *gorm.DB, role string, baru map[string]bool) error {
+	var rows []models.RolePermission
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("permission IN ?", akses.Inti).Find(&rows).Error; err != nil {
+		return err
+	}
+	dimiliki := map[string]bool{}
+	for _, r := range rows {
+		if r.Role != role {
+			dimiliki[r.Permission] = true
+		}
+	}
+	for _, p := range akses.Inti {
+		if !dimiliki[p] && !baru[p] {
+			return fmt.Errorf("%w: %s", errIzinIntiHabis, p)
+		}
+	}
+	return nil
+}
@@ -2,16 +2,14 @@ package controllers
 
 import (
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
-	"context"
-	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func AssignMapelKelas(c *gin.Context) {
@@ -91,71 +89,26 @@ func AssignMapelKelas(c *gin.Context) {
 		return
 	}
 
+	if err := enqueuePerRole(tx, notifikasi.AssignGuruMapel{
+		GuruID:      full.GuruID,
+		GuruNama:    full.Guru.Nama,
+		MapelID:     full.MapelID,
+		MapelNama:   full.MataPelajaran.Nama,
+		KelasID:     full.KelasID,
+		KelasNama:   full.Kelas.Nama,
+		TahunAjaran: full.TahunAjaran,
+		Semester:    full.Semester,
+	}, penerimaGuruMapel(tx, full)); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
 
-	go func(assign models.GuruMapelKelas) {
-		var recipientIDs []uint
-
-		recipientIDs = append(recipientIDs, assign.GuruID)
-
-		var kelas models.Kelas
-		if err := database.DB.First(&kelas, assign.KelasID).Error; err == nil {
-			if kelas.WaliKelasID != nil {
-				recipientIDs = append(recipientIDs, *kelas.WaliKelasID)
-			}
-		}
-
-		var adminIDs []uint
-		rows, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penugasan Guru ke Mata Pelajaran"
-		body := fmt.Sprintf(
-			"Guru %s telah resmi ditugaskan untuk mengajar mata pelajaran %s di kelas %s pada Tahun Ajaran %s (Semester %s).",
-			assign.Guru.Nama,
-			assign.MataPelajaran.Nama,
-			assign.Kelas.Nama,
-			assign.TahunAjaran,
-			assign.Semester,
-		)
-
-		payload := map[string]interface{}{
-			"type":         "assign_guru_mapel",
-			"guru_id":      fmt.Sprintf("%d", assign.GuruID),
-			"mapel_id":     fmt.Sprintf("%d", assign.MapelID),
-			"kelas_id":     fmt.Sprintf("%d", assign.KelasID),
-			"tahun_ajaran": assign.TahunAjaran,
-			"semester":     assign.Semester,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "assign_guru_mapel", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error: %v", err)
-		}
-	}(full)
-
 	utils.SuccessResponse(c, http.StatusCreated, "Guru berhasil ditetapkan ke mapel dan kelas", full)
 }
 
@@ -277,71 +230,26 @@ func DeleteAssignMapelKelas(c *gin.Context) {
 		return
 	}
 
+	if err := enqueuePerRole(tx, notifikasi.DeleteGuruMapel{
+		GuruID:      assignment.GuruID,
+		GuruNama:    assignment.Guru.Nama,
+		MapelID:     assignment.MapelID,
+		MapelNama:   assignment.MataPelajaran.Nama,
+		KelasID:     assignment.KelasID,
+		KelasNama:   assignment.Kelas.Nama,
+		TahunAjaran: assignment.TahunAjaran,
+		Semester:    assignment.Semester,
+	}, penerimaGuruMapel(tx, assignment)); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
 
-	go func(assign models.GuruMapelKelas) {
-		var recipientIDs []uint
-		recipientIDs = append(recipientIDs, assign.GuruID)
-
-		var kelas models.Kelas
-		if err := database.DB.First(&kelas, assign.KelasID).Error; err == nil {
-			if kelas.WaliKelasID != nil {
-				recipientIDs = append(recipientIDs, *kelas.WaliKelasID)
-			}
-		}
-
-		var adminIDs []uint
-		rows, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penghapusan Penugasan Guru"
-		body := fmt.Sprintf(
-			"Penugasan Guru %s untuk mata pelajaran %s di kelas %s pada Tahun Ajaran %s (Semester %s) telah dihapus.",
-			assign.Guru.Nama,
-			assign.MataPelajaran.Nama,
-			assign.Kelas.Nama,
-			assign.TahunAjaran,
-			assign.Semester,
-		)
-
-		payload := map[string]interface{}{
-			"type":         "delete_guru_mapel",
-			"guru_id":      fmt.Sprintf("%d", assign.GuruID),
-			"mapel_id":     fmt.Sprintf("%d", assign.MapelID),
-			"kelas_id":     fmt.Sprintf("%d", assign.KelasID),
-			"tahun_ajaran": assign.TahunAjaran,
-			"semester":     assign.Semester,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(),
-			"delete_guru_mapel", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (delete): %v", err)
-		}
-	}(assignment)
-
 	utils.SuccessResponse(c, http.StatusOK, "Penugasan berhasil dihapus", nil)
 }
 
@@ -358,3 +266,17 @@ func ListAssignMapelKelas(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Daftar penugasan guru ke mapel dan kelas", assignments)
 }
+
+// penerimaGuruMapel: guru pengampu, wali kelas dari kelas terkait, dan admin.
+func penerimaGuruMapel(tx *gorm.DB, assign models.GuruMapelKelas) map[string][]uint {
+	guruIDs := []uint{assign.GuruID}
+	var kelas models.Kelas
+	if err := tx.First(&kelas, assign.KelasID).Error; err == nil && kelas.WaliKelasID != nil {
+		guruIDs = append(guruIDs, *kelas.WaliKelasID)
+	}
+	adminIDs, _ := semuaAdminIDs(tx)
+	return map[string][]uint{
+		"guru":  guruIDs,
+		"admin": adminIDs,
+	}
+}
@@ -2,13 +2,10 @@ package controllers
 
 import (
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
-	"context"
-	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -30,6 +27,15 @@ func AssignWaliKelas(c *gin.Context) {
 
 	tx := database.DB.Begin()
 
+	var prevWaliIDs []uint
+	if err := tx.Model(&models.Kelas{}).
+		Where("id = ? AND wali_kelas_id IS NOT NULL", req.KelasID).
+		Pluck("wali_kelas_id", &prevWaliIDs).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data kelas")
+		return
+	}
+
 	if err := tx.Model(&models.Kelas{}).
 		Where("id = ?", req.KelasID).
 		Update("wali_kelas_id", nil).Error; err != nil {
@@ -64,63 +70,26 @@ func AssignWaliKelas(c *gin.Context) {
 		return
 	}
 
+	adminIDs, _ := semuaAdminIDs(tx)
+	if err := enqueuePerRole(tx, notifikasi.AssignWaliKelas{
+		GuruID:    *kelas.WaliKelasID,
+		GuruNama:  kelas.WaliKelas.Nama,
+		KelasID:   kelas.ID,
+		KelasNama: kelas.Nama,
+	}, map[string][]uint{
+		"guru":  {*kelas.WaliKelasID},
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi")
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi")
 		return
 	}
-
-	go func(kelas models.Kelas) {
-		var recipientIDs []uint
-		if kelas.WaliKelasID != nil {
-			recipientIDs = append(recipientIDs, *kelas.WaliKelasID)
-		}
-
-		var adminIDs []uint
-		rows, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if _, ok := uniq[id]; !ok && id != 0 {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penetapan Wali Kelas"
-		body := fmt.Sprintf(
-			"Guru %s telah resmi ditetapkan sebagai wali kelas %s.",
-			kelas.WaliKelas.Nama,
-			kelas.Nama,
-		)
-
-		payload := map[string]interface{}{
-			"type":       "assign_wali_kelas",
-			"guru_id":    fmt.Sprintf("%d", *kelas.WaliKelasID),
-			"kelas_id":   fmt.Sprintf("%d", kelas.ID),
-			"kelas_nama": kelas.Nama,
-		}
-
-		if err := firebaseclient.NotifyUsers(
-			context.Background(),
-			"assign_wali_kelas",
-			title,
-			body,
-			payload,
-			finalRecipients,
-		); err != nil {
-			log.Printf("NotifyUsers error (assign wali kelas): %v", err)
-		}
-	}(kelas)
+	sinkronTopikGuru(append(prevWaliIDs, req.GuruID)...)
 
 	utils.SuccessResponse(c, http.StatusOK, "Wali kelas berhasil ditetapkan", kelas)
 }
@@ -181,54 +150,28 @@ func UnassignWaliKelas(c *gin.Context) {
 		return
 	}
 
+	adminIDs, _ := semuaAdminIDs(tx)
+	if err := enqueuePerRole(tx, notifikasi.UnassignWaliKelas{
+		GuruID:    prevWaliID,
+		GuruNama:  prevWaliNama,
+		KelasID:   kelasID,
+		KelasNama: kelasNama,
+	}, map[string][]uint{
+		"guru":  {prevWaliID},
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
 
-	go func(waliID uint, waliNama, kelasNama string, kelasID uint) {
-
-		var recipientIDs []uint
-		recipientIDs = append(recipientIDs, waliID)
-
-		var adminIDs []uint
-		rows, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Pencabutan Wali Kelas"
-		body := fmt.Sprintf("Penetapan Wali Kelas untuk kelas %s dibatalkan. Guru %s tidak lagi menjabat wali kelas %s.", kelasNama, waliNama, kelasNama)
-		payload := map[string]interface{}{
-			"type":       "unassign_wali_kelas",
-			"guru_id":    fmt.Sprintf("%d", waliID),
-			"kelas_id":   fmt.Sprintf("%d", kelasID),
-			"kelas_nama": kelasNama,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "unassign_wali_kelas", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (unassign wali kelas): %v", err)
-		}
-	}(prevWaliID, prevWaliNama, kelasNama, kelasID)
+	sinkronTopikGuru(prevWaliID)
 
 	kelas.WaliKelas = nil
 	kelas.WaliKelasID = nil
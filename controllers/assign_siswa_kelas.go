@@ -1,14 +1,11 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
-	"log"
 	"net/http"
 
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
 
@@ -24,12 +21,6 @@ func AssignSiswaToKelas(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("role")
-	if roleStr, ok := role.(string); !ok || roleStr != "admin" {
-		utils.ErrorResponse(c, http.StatusForbidden, "Hanya admin yang dapat melakukan aksi ini")
-		return
-	}
-
 	tx := database.DB.Begin()
 	if tx.Error != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memulai transaksi: "+tx.Error.Error())
@@ -103,6 +94,7 @@ func AssignSiswaToKelas(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 			return
 		}
+		sinkronTopikSiswa(siswa.ID)
 
 		_ = database.DB.Preload("Kelas").Preload("MataPelajaran").First(&siswa, req.SiswaID)
 		utils.SuccessResponse(c, http.StatusOK, "Siswa sudah terdaftar di kelas ini", siswa)
@@ -121,61 +113,32 @@ func AssignSiswaToKelas(c *gin.Context) {
 		return
 	}
 
+	var waliIDs []uint
+	if kelas.WaliKelasID != nil && *kelas.WaliKelasID != 0 {
+		waliIDs = append(waliIDs, *kelas.WaliKelasID)
+	}
+	adminIDs, _ := semuaAdminIDs(tx)
+	if err := enqueuePerRole(tx, notifikasi.AssignSiswaKelas{
+		SiswaID:   siswa.ID,
+		SiswaNama: siswa.Nama,
+		KelasID:   kelas.ID,
+		KelasNama: kelas.Nama,
+		Actor:     "admin",
+	}, map[string][]uint{
+		"siswa": {siswa.ID},
+		"guru":  waliIDs,
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
-
-	go func(s models.Siswa, k models.Kelas) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("panic in notif goroutine: %v", r)
-			}
-		}()
-
-		var recipientIDs []uint
-		recipientIDs = append(recipientIDs, s.ID)
-
-		if k.WaliKelasID != nil && *k.WaliKelasID != 0 {
-			recipientIDs = append(recipientIDs, *k.WaliKelasID)
-		}
-
-		var adminIDs []uint
-		rowsAdmin, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rowsAdmin.Close()
-			for rowsAdmin.Next() {
-				var id uint
-				_ = rowsAdmin.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if _, ok := uniq[id]; !ok && id != 0 {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penambahan Siswa ke Kelas"
-		body := fmt.Sprintf("Siswa %s telah ditambahkan ke kelas %s.", s.Nama, k.Nama)
-		payload := map[string]interface{}{
-			"type":       "assign_siswa_kelas",
-			"siswa_id":   s.ID,
-			"siswa_nama": s.Nama,
-			"kelas_id":   k.ID,
-			"kelas_nama": k.Nama,
-			"actor":      "admin",
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "assign_siswa_kelas", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (assign siswa kelas): %v", err)
-		}
-	}(siswa, kelas)
+	sinkronTopikSiswa(siswa.ID)
 
 	if err := database.DB.Preload("Kelas").Preload("MataPelajaran").First(&siswa, req.SiswaID).Error; err != nil {
 		utils.SuccessResponse(c, http.StatusCreated, "Siswa ditambahkan ke kelas (tapi gagal memuat relasi)", siswa)
@@ -192,12 +155,6 @@ func UnassignSiswaFromKelas(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("role")
-	if roleStr, ok := role.(string); !ok || roleStr != "admin" {
-		utils.ErrorResponse(c, http.StatusForbidden, "Hanya admin yang dapat melakukan aksi ini")
-		return
-	}
-
 	tx := database.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -245,55 +202,32 @@ func UnassignSiswaFromKelas(c *gin.Context) {
 		return
 	}
 
+	var waliIDs []uint
+	if kelas.WaliKelasID != nil && *kelas.WaliKelasID != 0 {
+		waliIDs = append(waliIDs, *kelas.WaliKelasID)
+	}
+	adminIDs, _ := semuaAdminIDs(tx)
+	if err := enqueuePerRole(tx, notifikasi.UnassignSiswaKelas{
+		SiswaID:   siswa.ID,
+		SiswaNama: siswa.Nama,
+		KelasID:   kelas.ID,
+		KelasNama: kelas.Nama,
+	}, map[string][]uint{
+		"siswa": {siswa.ID},
+		"guru":  waliIDs,
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
-
-	go func(siswa models.Siswa, kelas models.Kelas) {
-		var recipientIDs []uint
-		recipientIDs = append(recipientIDs, siswa.ID)
-
-		if kelas.WaliKelasID != nil && *kelas.WaliKelasID != 0 {
-			recipientIDs = append(recipientIDs, *kelas.WaliKelasID)
-		}
-
-		var adminIDs []uint
-		rowsAdmin, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rowsAdmin.Close()
-			for rowsAdmin.Next() {
-				var id uint
-				_ = rowsAdmin.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if _, ok := uniq[id]; !ok && id != 0 {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penghapusan Siswa dari Kelas"
-		body := fmt.Sprintf("Siswa %s telah dihapus dari kelas %s.", siswa.Nama, kelas.Nama)
-		payload := map[string]interface{}{
-			"type":       "unassign_siswa_kelas",
-			"siswa_id":   siswa.ID,
-			"siswa_nama": siswa.Nama,
-			"kelas_id":   kelas.ID,
-			"kelas_nama": kelas.Nama,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "unassign_siswa_kelas", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (unassign siswa kelas): %v", err)
-		}
-	}(siswa, kelas)
+	sinkronTopikSiswa(siswa.ID)
 
 	if err := database.DB.Preload("Kelas").Preload("MataPelajaran").First(&siswa, req.SiswaID).Error; err != nil {
 		utils.SuccessResponse(c, http.StatusOK, "Siswa dihapus dari kelas (tapi gagal memuat relasi)", siswa)
@@ -1,14 +1,13 @@
 package controllers
 
 import (
-	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
 
@@ -22,12 +21,6 @@ func AssignSiswaToMapel(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("role")
-	if roleStr, ok := role.(string); !ok || roleStr != "admin" {
-		utils.ErrorResponse(c, http.StatusForbidden, "Hanya admin yang dapat melakukan aksi ini")
-		return
-	}
-
 	tx := database.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -69,68 +62,32 @@ func AssignSiswaToMapel(c *gin.Context) {
 		return
 	}
 
+	var guruIDs []uint
+	if err := tx.Table("guru_mapels").Where("mapel_id = ?", mapel.ID).Pluck("guru_id", &guruIDs).Error; err != nil {
+		log.Printf("warning: gagal ambil guru pengampu untuk mapel %d: %v", mapel.ID, err)
+	}
+	adminIDs, _ := semuaAdminIDs(tx)
+	if err := enqueuePerRole(tx, notifikasi.AssignSiswaMapel{
+		SiswaID:   siswa.ID,
+		SiswaNama: siswa.Nama,
+		MapelID:   mapel.ID,
+		MapelNama: mapel.Nama,
+	}, map[string][]uint{
+		"siswa": {siswa.ID},
+		"guru":  guruIDs,
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
 
-	go func(siswa models.Siswa, mapel models.MataPelajaran) {
-		var recipientIDs []uint
-
-		recipientIDs = append(recipientIDs, siswa.ID)
-
-		var guruIDs []uint
-		rows, err := database.DB.Raw("SELECT guru_id FROM guru_mapels WHERE mapel_id = ?", mapel.ID).Rows()
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				guruIDs = append(guruIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, guruIDs...)
-
-		var adminIDs []uint
-		rowsAdmin, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rowsAdmin.Close()
-			for rowsAdmin.Next() {
-				var id uint
-				_ = rowsAdmin.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penambahan Siswa ke Mapel"
-		body := fmt.Sprintf("Siswa %s berhasil ditambahkan ke mata pelajaran %s", siswa.Nama, mapel.Nama)
-		payload := map[string]interface{}{
-			"type":       "assign_siswa_mapel",
-			"siswa_id":   fmt.Sprintf("%d", siswa.ID),
-			"siswa_nama": siswa.Nama,
-			"mapel_id":   fmt.Sprintf("%d", mapel.ID),
-			"mapel_nama": mapel.Nama,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "assign_siswa_mapel", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (assign siswa mapel): %v", err)
-		}
-	}(siswa, mapel)
-
 	if err := database.DB.Preload("Kelas").Preload("MataPelajaran").First(&siswa, req.SiswaID).Error; err != nil {
 		utils.SuccessResponse(c, http.StatusCreated, "Siswa ditambahkan ke mapel (tapi gagal memuat relasi)", siswa)
 		return
@@ -146,12 +103,6 @@ func UnassignSiswaFromMapel(c *gin.Context) {
 		return
 	}
 
-	role, _ := c.Get("role")
-	if roleStr, ok := role.(string); !ok || roleStr != "admin" {
-		utils.ErrorResponse(c, http.StatusForbidden, "Hanya admin yang dapat melakukan aksi ini")
-		return
-	}
-
 	tx := database.DB.Begin()
 	if tx.Error != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memulai transaksi: "+tx.Error.Error())
@@ -190,83 +141,44 @@ func UnassignSiswaFromMapel(c *gin.Context) {
 		return
 	}
 
-	if err := tx.Commit().Error; err != nil {
+	var mapel models.MataPelajaran
+	if err := tx.First(&mapel, req.MapelID).Error; err != nil {
+		log.Printf("warning: gagal ambil data mapel untuk notifikasi: %v", err)
+	}
+	mapelNama := mapel.Nama
+	if mapelNama == "" {
+		mapelNama = fmt.Sprintf("ID %d", req.MapelID)
+	}
+
+	var guruIDs []uint
+	if err := tx.Table("guru_mapel_kelas").Where("mapel_id = ?", req.MapelID).Distinct().Pluck("guru_id", &guruIDs).Error; err != nil {
+		log.Printf("warning: gagal ambil guru pengampu untuk mapel %d: %v", req.MapelID, err)
+	}
+	adminIDs, err := semuaAdminIDs(tx)
+	if err != nil {
+		log.Printf("warning: gagal ambil admin ids: %v", err)
+	}
+	if err := enqueuePerRole(tx, notifikasi.UnassignSiswaMapel{
+		SiswaID:   siswa.ID,
+		SiswaNama: siswa.Nama,
+		MapelID:   req.MapelID,
+		MapelNama: mapelNama,
+	}, map[string][]uint{
+		"siswa": {siswa.ID},
+		"guru":  guruIDs,
+		"admin": adminIDs,
+	}); err != nil {
 		tx.Rollback()
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
 		return
 	}
 
-	var mapel models.MataPelajaran
-	if err := database.DB.First(&mapel, req.MapelID).Error; err != nil {
-
-		log.Printf("warning: gagal ambil data mapel untuk notifikasi: %v", err)
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
+		return
 	}
 
-	go func(siswa models.Siswa, mapel models.MataPelajaran, mapelID uint) {
-		var recipientIDs []uint
-
-		recipientIDs = append(recipientIDs, siswa.ID)
-
-		var guruIDs []uint
-		rows, err := database.DB.Raw("SELECT DISTINCT guru_id FROM guru_mapel_kelas WHERE mapel_id = ?", mapelID).Rows()
-		if err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				guruIDs = append(guruIDs, id)
-			}
-		} else {
-			log.Printf("warning: gagal ambil guru pengampu untuk mapel %d: %v", mapelID, err)
-		}
-		recipientIDs = append(recipientIDs, guruIDs...)
-
-		var adminIDs []uint
-		rowsAdmin, err := database.DB.Raw("SELECT id FROM admins").Rows()
-		if err == nil {
-			defer rowsAdmin.Close()
-			for rowsAdmin.Next() {
-				var id uint
-				_ = rowsAdmin.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
-			log.Printf("warning: gagal ambil admin ids: %v", err)
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Penghapusan dari Mata Pelajaran"
-		mapelNama := mapel.Nama
-		if mapelNama == "" {
-			mapelNama = fmt.Sprintf("ID %d", mapelID)
-		}
-		body := fmt.Sprintf("Siswa %s telah dihapus dari mata pelajaran %s.", siswa.Nama, mapelNama)
-
-		payload := map[string]interface{}{
-			"type":       "unassign_siswa_mapel",
-			"siswa_id":   fmt.Sprintf("%d", siswa.ID),
-			"siswa_nama": siswa.Nama,
-			"mapel_id":   fmt.Sprintf("%d", mapelID),
-			"mapel_nama": mapelNama,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "unassign_siswa_mapel", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (unassign siswa mapel): %v", err)
-		}
-	}(siswa, mapel, req.MapelID)
-
 	if err := database.DB.Preload("Kelas").Preload("MataPelajaran").First(&siswa, req.SiswaID).Error; err != nil {
 		utils.SuccessResponse(c, http.StatusOK, "Siswa dihapus dari mapel (tapi gagal memuat relasi)", siswa)
 		return
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"strings"
 
 	"abs-be/database"
 	"abs-be/models"
@@ -18,38 +19,24 @@ func LoginAdmin(c *gin.Context) {
 		return
 	}
 
-	var admin models.Admin
-	if err := database.DB.Where("email = ?", req.Email).First(&admin).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan")
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeAdmin)
+	if !ok {
 		return
 	}
-
-	if !utils.CheckPasswordHash(req.Password, admin.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
+	var admin models.Admin
+	if err := database.DB.First(&admin, p.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil data akun")
 		return
 	}
 
-	token, err := utils.GenerateToken(admin.ID, "admin")
+	resp, err := buatSesi(c, admin.ID, "admin", gin.H{
+		"admin": requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
+	})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-
-	session := models.Session{
-		UserID: admin.ID,
-		Token:  token,
-		Role:   "admin",
-	}
-
-	if err := database.DB.Create(&session).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
 		return
 	}
-
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
-		"admin": requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
-	})
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 func LoginGuru(c *gin.Context) {
@@ -59,50 +46,26 @@ func LoginGuru(c *gin.Context) {
 		return
 	}
 
-	var guru models.Guru
-	if err := database.DB.Where("email = ?", req.Email).First(&guru).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan")
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeGuru)
+	if !ok {
 		return
 	}
-
-	if !utils.CheckPasswordHash(req.Password, guru.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
+	var guru models.Guru
+	if err := database.DB.First(&guru, p.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil data akun")
 		return
 	}
 
-	token, err := utils.GenerateToken(guru.ID, "guru")
+	// Wali kelas juga boleh login sebagai guru; role wali_kelas tetap ada
+	// di sesi dan bisa diaktifkan lewat /session/active-role.
+	resp, err := buatSesi(c, guru.ID, "guru", gin.H{
+		"guru": requests.LoginResponse{ID: guru.ID, Email: guru.Email, Nama: guru.Nama},
+	})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-
-	var waliKelasCount int64
-	database.DB.Model(&models.GuruRole{}).
-		Where("guru_id = ? AND role = 'wali_kelas'", guru.ID).
-		Count(&waliKelasCount)
-	isWaliKelas := waliKelasCount > 0
-
-	if isWaliKelas {
-		utils.ErrorResponse(c, http.StatusForbidden,
-			"Anda adalah wali kelas, silakan login melalui endpoint wali kelas")
-		return
-	}
-
-	session := models.Session{
-		UserID: guru.ID,
-		Token:  token,
-		Role:   "guru",
-	}
-
-	if err := database.DB.Create(&session).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
 		return
 	}
-
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
-		"guru":  requests.LoginResponse{ID: guru.ID, Email: guru.Email, Nama: guru.Nama},
-	})
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 func LoginWaliKelas(c *gin.Context) {
@@ -112,12 +75,16 @@ func LoginWaliKelas(c *gin.Context) {
 		return
 	}
 
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeGuru)
+	if !ok {
+		return
+	}
 	var wali models.Guru
 	if err := database.DB.
 		Joins("JOIN guru_roles ON guru_roles.guru_id = gurus.id").
-		Where("gurus.email = ? AND guru_roles.role = ?", req.Email, "wali_kelas").
+		Where("gurus.id = ? AND guru_roles.role = ?", p.ID, "wali_kelas").
 		First(&wali).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan atau bukan wali kelas")
+		utils.ErrorResponse(c, http.StatusForbidden, "akun ini bukan wali kelas")
 		return
 	}
 
@@ -130,30 +97,7 @@ func LoginWaliKelas(c *gin.Context) {
 		return
 	}
 
-	if !utils.CheckPasswordHash(req.Password, wali.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
-		return
-	}
-
-	token, err := utils.GenerateToken(wali.ID, "wali_kelas")
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-
-	session := models.Session{
-		UserID: wali.ID,
-		Token:  token,
-		Role:   "wali_kelas",
-	}
-
-	if err := database.DB.Create(&session).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
-		return
-	}
-
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
+	resp, err := buatSesi(c, wali.ID, "wali_kelas", gin.H{
 		"wali_kelas": requests.LoginResponse{
 			ID:    wali.ID,
 			Email: wali.Email,
@@ -165,6 +109,11 @@ func LoginWaliKelas(c *gin.Context) {
 			"tingkat": kelas.Tingkat,
 		},
 	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 // func Logout(c *gin.Context) {
@@ -204,78 +153,58 @@ func LoginAll(c *gin.Context) {
 }
 
 func loginAdminAll(c *gin.Context, req requests.AllLoginRequest) {
-	var admin models.Admin
-	if err := database.DB.Where("email = ?", req.Email).First(&admin).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan")
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeAdmin)
+	if !ok {
 		return
 	}
-	if !utils.CheckPasswordHash(req.Password, admin.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
+	var admin models.Admin
+	if err := database.DB.First(&admin, p.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil data akun")
 		return
 	}
-	token, err := utils.GenerateToken(admin.ID, "admin")
+	resp, err := buatSesi(c, admin.ID, "admin", gin.H{
+		"user": requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
+		"role": "admin",
+	})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-	session := models.Session{UserID: admin.ID, Token: token, Role: "admin"}
-	if err := database.DB.Create(&session).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
 		return
 	}
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
-		"user":  requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
-		"role":  "admin",
-	})
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 func loginGuruAll(c *gin.Context, req requests.AllLoginRequest) {
-	var guru models.Guru
-	if err := database.DB.Where("email = ?", req.Email).First(&guru).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan")
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeGuru)
+	if !ok {
 		return
 	}
-	if !utils.CheckPasswordHash(req.Password, guru.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
-		return
-	}
-	var waliKelasCount int64
-	database.DB.Model(&models.GuruRole{}).
-		Where("guru_id = ? AND role = 'wali_kelas'", guru.ID).
-		Count(&waliKelasCount)
-	if waliKelasCount > 0 {
-		utils.ErrorResponse(c, http.StatusForbidden, "Anda adalah wali kelas, silakan login sebagai wali kelas")
+	var guru models.Guru
+	if err := database.DB.First(&guru, p.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil data akun")
 		return
 	}
-	token, err := utils.GenerateToken(guru.ID, "guru")
+	resp, err := buatSesi(c, guru.ID, "guru", gin.H{
+		"user": requests.LoginResponse{ID: guru.ID, Email: guru.Email, Nama: guru.Nama},
+		"role": "guru",
+	})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-	session := models.Session{UserID: guru.ID, Token: token, Role: "guru"}
-	if err := database.DB.Create(&session).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
 		return
 	}
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
-		"user":  requests.LoginResponse{ID: guru.ID, Email: guru.Email, Nama: guru.Nama},
-		"role":  "guru",
-	})
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 func loginWaliKelasAll(c *gin.Context, req requests.AllLoginRequest) {
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeGuru)
+	if !ok {
+		return
+	}
 	var wali models.Guru
 	if err := database.DB.
 		Joins("JOIN guru_roles ON guru_roles.guru_id = gurus.id").
-		Where("gurus.email = ? AND guru_roles.role = ?", req.Email, "wali_kelas").
+		Where("gurus.id = ? AND guru_roles.role = ?", p.ID, "wali_kelas").
 		First(&wali).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan atau bukan wali kelas")
-		return
-	}
-	if !utils.CheckPasswordHash(req.Password, wali.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
+		utils.ErrorResponse(c, http.StatusForbidden, "akun ini bukan wali kelas")
 		return
 	}
 	var kelas models.Kelas
@@ -285,221 +214,104 @@ func loginWaliKelasAll(c *gin.Context, req requests.AllLoginRequest) {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "wali kelas belum ditugaskan ke kelas manapun")
 		return
 	}
-	token, err := utils.GenerateToken(wali.ID, "wali_kelas")
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-	session := models.Session{UserID: wali.ID, Token: token, Role: "wali_kelas"}
-	if err := database.DB.Create(&session).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
-		return
-	}
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
-		"user":  requests.LoginResponse{ID: wali.ID, Email: wali.Email, Nama: wali.Nama},
-		"role":  "wali_kelas",
+	resp, err := buatSesi(c, wali.ID, "wali_kelas", gin.H{
+		"user": requests.LoginResponse{ID: wali.ID, Email: wali.Email, Nama: wali.Nama},
+		"role": "wali_kelas",
 		"kelas": gin.H{
 			"id":      kelas.ID,
 			"nama":    kelas.Nama,
 			"tingkat": kelas.Tingkat,
 		},
 	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 func loginSiswaAll(c *gin.Context, req requests.AllLoginRequest) {
-	var siswa models.Siswa
-	if err := database.DB.Where("email = ?", req.Email).First(&siswa).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan")
+	p, _, ok := autentikasi(c, req.Email, req.Password, models.UserTypeSiswa)
+	if !ok {
 		return
 	}
-	if !utils.CheckPasswordHash(req.Password, siswa.Password) {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
+	var siswa models.Siswa
+	if err := database.DB.First(&siswa, p.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil data akun")
 		return
 	}
-	token, err := utils.GenerateToken(siswa.ID, "siswa")
+	resp, err := buatSesi(c, siswa.ID, "siswa", gin.H{
+		"user": requests.LoginResponse{ID: siswa.ID, Email: siswa.Email, Nama: siswa.Nama},
+		"role": "siswa",
+	})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
-		return
-	}
-	session := models.Session{UserID: siswa.ID, Token: token, Role: "siswa"}
-	if err := database.DB.Create(&session).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
 		return
 	}
-	utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-		"token": token,
-		"user":  requests.LoginResponse{ID: siswa.ID, Email: siswa.Email, Nama: siswa.Nama},
-		"role":  "siswa",
-	})
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
 }
 
 func LoginAutoRole(c *gin.Context) {
-	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
-	}
+	var req requests.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
 		return
 	}
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
+	}
+	if strings.TrimSpace(identifier) == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "identifier wajib diisi (email, NISN atau NIP)")
+		return
+	}
 
-	var admin models.Admin
-	if err := database.DB.Where("email = ?", req.Email).First(&admin).Error; err == nil {
-		if !utils.CheckPasswordHash(req.Password, admin.Password) {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
-			return
-		}
-		token, _ := utils.GenerateToken(admin.ID, "admin")
-		database.DB.Create(&models.Session{UserID: admin.ID, Token: token, Role: "admin"})
-		utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-			"token": token,
-			"role":  "admin",
-		})
+	p, jenis, ok := autentikasi(c, identifier, req.Password, req.UserType)
+	if !ok {
 		return
 	}
 
-	var guru models.Guru
-	if err := database.DB.Where("email = ?", req.Email).First(&guru).Error; err == nil {
-		if !utils.CheckPasswordHash(req.Password, guru.Password) {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
-			return
-		}
+	role := p.UserType
+	if p.UserType == models.UserTypeGuru {
 		var waliKelasCount int64
 		database.DB.Model(&models.GuruRole{}).
-			Where("guru_id = ? AND role = 'wali_kelas'", guru.ID).
+			Where("guru_id = ? AND role = 'wali_kelas'", p.ID).
 			Count(&waliKelasCount)
-		role := "guru"
 		if waliKelasCount > 0 {
 			role = "wali_kelas"
 		}
-		token, _ := utils.GenerateToken(guru.ID, role)
-		database.DB.Create(&models.Session{UserID: guru.ID, Token: token, Role: role})
-		utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-			"token": token,
-			"role":  role,
-		})
-		return
-	}
-
-	var siswa models.Siswa
-	if err := database.DB.Where("email = ?", req.Email).First(&siswa).Error; err == nil {
-		if !utils.CheckPasswordHash(req.Password, siswa.Password) {
-			utils.ErrorResponse(c, http.StatusUnauthorized, "password salah")
-			return
-		}
-		token, _ := utils.GenerateToken(siswa.ID, "siswa")
-		database.DB.Create(&models.Session{UserID: siswa.ID, Token: token, Role: "siswa"})
-		utils.SuccessResponse(c, http.StatusOK, "login berhasil", gin.H{
-			"token": token,
-			"role":  "siswa",
-		})
-		return
-	}
-	utils.ErrorResponse(c, http.StatusUnauthorized, "email tidak ditemukan")
-}
-
-func Logout(c *gin.Context) {
-	tokenString := c.GetHeader("Authorization")
-	if tokenString == "" {
-		utils.ErrorResponse(c, http.StatusBadRequest, "token tidak ditemukan")
-		return
-	}
-
-	userID, exists := c.Get("user_id")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "user_id tidak ditemukan")
-		return
-	}
-
-	role, exists := c.Get("role")
-	if !exists {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "role tidak ditemukan")
-		return
-	}
-
-	if err := database.DB.Where("token = ?", tokenString).Delete(&models.Session{}).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menghapus sesi lama")
-		return
-	}
-
-	newToken, err := utils.GenerateToken(userID.(uint), role.(string))
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token baru")
-		return
-	}
-
-	session := models.Session{
-		UserID: userID.(uint),
-		Token:  newToken,
-		Role:   role.(string),
-	}
-
-	if err := database.DB.Create(&session).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi baru")
-		return
 	}
-
-	utils.SuccessResponse(c, http.StatusOK, "logout berhasil dan token baru dibuat", gin.H{
-		"message":   "token baru udh dibuat",
-		"new_token": newToken,
+	resp, err := buatSesi(c, p.ID, role, gin.H{
+		"role":            role,
+		"identifier_type": jenis,
 	})
-}
-
-func RegisterAdmin(c *gin.Context) {
-	var req requests.AdminRegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
-		return
-	}
-
-	if req.Password != req.ConfirmPassword {
-		utils.ErrorResponse(c, http.StatusBadRequest, "password dan konfirmasi tidak cocok")
-		return
-	}
-
-	var existing models.Admin
-	if err := database.DB.Where("email = ?", req.Email).First(&existing).Error; err == nil {
-		utils.ErrorResponse(c, http.StatusConflict, "email sudah terdaftar")
-		return
-	}
-
-	hashed, err := utils.HashPassword(req.Password)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memproses password")
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
 		return
 	}
+	utils.SuccessResponse(c, http.StatusOK, "login berhasil", resp)
+}
 
-	admin := models.Admin{
-		Nama:     req.Nama,
-		Email:    req.Email,
-		Password: hashed,
-	}
-
-	if err := database.DB.Create(&admin).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan admin: "+err.Error())
+// Logout mengakhiri sesi yang sedang dipakai: access token dan semua
+// refresh token sesi ini tidak berlaku lagi.
+func Logout(c *gin.Context) {
+	val, exists := c.Get("session")
+	session, ok := val.(models.Session)
+	if !exists || !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "sesi tidak ditemukan")
 		return
 	}
 
-	token, err := utils.GenerateToken(admin.ID, "admin")
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
+	if err := cabutSesi(database.DB, session.ID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menghapus sesi")
 		return
 	}
 
-	session := models.Session{
-		UserID: admin.ID,
-		Token:  token,
-		Role:   "admin",
+	var req requests.LogoutRequest
+	_ = c.ShouldBindJSON(&req)
+	if me, ok := currentPrincipal(c); ok {
+		lepasDeviceLogout(me, req.DeviceToken)
 	}
 
-	if err := database.DB.Create(&session).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan sesi login")
-		return
-	}
-
-	utils.SuccessResponse(c, http.StatusCreated, "registrasi admin berhasil", gin.H{
-		"token": token,
-		"admin": requests.LoginResponse{ID: admin.ID, Email: admin.Email, Nama: admin.Nama},
-	})
+	utils.SuccessResponse(c, http.StatusOK, "logout berhasil", nil)
 }
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"abs-be/akses"
+	"abs-be/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func currentUserID(c *gin.Context) (uint, bool) {
+	userIDVal, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	switch v := userIDVal.(type) {
+	case uint:
+		return v, true
+	case int:
+		return uint(v), true
+	case int64:
+		return uint(v), true
+	case float64:
+		return uint(v), true
+	default:
+		return 0, false
+	}
+}
+
+func currentRole(c *gin.Context) string {
+	roleVal, _ := c.Get("role")
+	role, _ := roleVal.(string)
+	return role
+}
+
+// currentRoles mengembalikan semua role sesi saat ini; role aktif selalu
+// termasuk di dalamnya.
+func currentRoles(c *gin.Context) []string {
+	if v, ok := c.Get("roles"); ok {
+		if roles, ok := v.([]string); ok && len(roles) > 0 {
+			return roles
+		}
+	}
+	if role := currentRole(c); role != "" {
+		return []string{role}
+	}
+	return nil
+}
+
+// punyaRole melaporkan apakah sesi saat ini memiliki salah satu roles,
+// tidak hanya sebagai role aktif.
+func punyaRole(c *gin.Context, roles ...string) bool {
+	for _, r := range currentRoles(c) {
+		for _, want := range roles {
+			if r == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// punyaIzin melaporkan apakah salah satu role sesi saat ini memiliki salah
+// satu izin perms.
+func punyaIzin(c *gin.Context, perms ...string) (bool, error) {
+	return akses.Punya(currentRoles(c), perms...)
+}
+
+// currentPrincipal mengembalikan akun sesi saat ini: jenis user (dari role)
+// dan id-nya.
+func currentPrincipal(c *gin.Context) (models.Principal, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return models.Principal{}, false
+	}
+	p := models.NewPrincipal(currentRole(c), userID)
+	return p, p.Valid()
+}
@@ -1,33 +1,122 @@
 package controllers
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
+
 	"abs-be/database"
+	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/requests"
 	"abs-be/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RegisterDeviceToken mendaftarkan token atau memperbarui waktu terakhir
+// terlihat bila sudah terdaftar; aplikasi memanggilnya setiap kali dibuka.
 func RegisterDeviceToken(c *gin.Context) {
-    userID := c.MustGet("user_id").(uint) 
-    var req struct {
-        Token    string `json:"token" binding:"required"`
-        Platform string `json:"platform"`
-    }
-    if err := c.ShouldBindJSON(&req); err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "token wajib")
-        return
-    }
-
-    dt := models.DeviceToken{
-        UserID: userID,
-        Token:  req.Token,
-        Platform: req.Platform,
-    }
-    if err := database.DB.Where("token = ?", req.Token).Assign(dt).FirstOrCreate(&dt).Error; err != nil {
-        utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan device token")
-        return
-    }
-    utils.SuccessResponse(c, http.StatusOK, "Device token terdaftar", nil)
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	var req requests.DeviceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "token wajib")
+		return
+	}
+
+	now := time.Now()
+	dt := models.DeviceToken{
+		UserID:     me.ID,
+		UserType:   me.UserType,
+		Token:      req.Token,
+		Platform:   req.Platform,
+		AppVersion: req.AppVersion,
+		LastSeenAt: &now,
+	}
+	if err := database.DB.Where("token = ?", req.Token).Assign(dt).FirstOrCreate(&dt).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan device token")
+		return
+	}
+	firebaseclient.SinkronTopikAsync(me)
+	utils.SuccessResponse(c, http.StatusOK, "Device token terdaftar", dt)
+}
+
+// GetMyDeviceTokens menampilkan perangkat milik user yang sedang login.
+func GetMyDeviceTokens(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	var rows []models.DeviceToken
+	if err := database.DB.Where("user_type = ? AND user_id = ?", me.UserType, me.ID).
+		Order("last_seen_at DESC").Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil perangkat")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Daftar perangkat", rows)
+}
+
+// DeleteMyDeviceToken menghapus satu perangkat milik user yang sedang login.
+func DeleteMyDeviceToken(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	var dt models.DeviceToken
+	if err := database.DB.Where("id = ? AND user_type = ? AND user_id = ?", id, me.UserType, me.ID).
+		First(&dt).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Perangkat tidak ditemukan")
+		return
+	}
+	if err := firebaseclient.HapusDeviceToken(c.Request.Context(), dt.Token); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus perangkat")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Perangkat dihapus", nil)
+}
+
+// lepasDeviceLogout menghapus token perangkat milik user saat logout agar
+// perangkat tsb tidak lagi menerima push. Kegagalan tidak menggagalkan logout.
+func lepasDeviceLogout(me models.Principal, token string) {
+	if token == "" {
+		return
+	}
+	var tokens []string
+	if err := database.DB.Model(&models.DeviceToken{}).
+		Where("token = ? AND user_type = ? AND user_id = ?", token, me.UserType, me.ID).
+		Pluck("token", &tokens).Error; err != nil {
+		log.Printf("device: gagal cari token saat logout: %v", err)
+		return
+	}
+	if err := firebaseclient.HapusDeviceToken(context.Background(), tokens...); err != nil {
+		log.Printf("device: gagal hapus token saat logout: %v", err)
+	}
+}
+
+// lepasSemuaDevice menghapus semua perangkat akun, mis. saat akun
+// dinonaktifkan, agar tidak lagi menerima push.
+func lepasSemuaDevice(p models.Principal) {
+	var tokens []string
+	if err := database.DB.Model(&models.DeviceToken{}).
+		Where("user_type = ? AND user_id = ?", p.UserType, p.ID).
+		Pluck("token", &tokens).Error; err != nil {
+		log.Printf("device: gagal cari token %s/%d: %v", p.UserType, p.ID, err)
+		return
+	}
+	if err := firebaseclient.HapusDeviceToken(context.Background(), tokens...); err != nil {
+		log.Printf("device: gagal hapus token %s/%d: %v", p.UserType, p.ID, err)
+	}
 }
@@ -3,16 +3,18 @@ package controllers
 import (
 	"abs-be/database"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
-	"abs-be/firebaseclient"
+	"abs-be/jadwal"
 	"net/http"
 	"strconv"
-	"context"
-	"fmt"
 	"log"
+	"errors"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func GetAllGurus(c *gin.Context) {
@@ -71,9 +73,35 @@ func CreateGuru(c *gin.Context) {
 		Alamat:       req.Alamat,
 		JenisKelamin: req.JenisKelamin,
 		Password:     hashedPassword,
+		// Password awal adalah NIP sehingga wajib diganti saat login pertama.
+		MustChangePassword: true,
 	}
 
-	if err := database.DB.Create(&newGuru).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := pastikanEmailBebas(tx, newGuru.Email, models.Principal{}); err != nil {
+			return err
+		}
+		if err := tx.Create(&newGuru).Error; err != nil {
+			return err
+		}
+		adminIDs, err := semuaAdminIDs(tx)
+		if err != nil {
+			log.Printf("CreateGuru: gagal ambil admin ids: %v", err)
+		}
+		return enqueuePerRole(tx, notifikasi.CreateGuru{
+			GuruID: newGuru.ID,
+			Nama:   newGuru.Nama,
+			Email:  newGuru.Email,
+		}, map[string][]uint{
+			"admin": adminIDs,
+			"guru":  {newGuru.ID},
+		})
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, "Email sudah terdaftar")
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat guru: "+err.Error())
 		return
 	}
@@ -81,56 +109,6 @@ func CreateGuru(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusCreated, "Guru berhasil dibuat", gin.H{
 		"guru": newGuru,
 	})
-
-	go func(guru models.Guru) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("panic di notification goroutine CreateGuru: %v", r)
-			}
-		}()
-
-		var recipientIDs []uint
-
-		var adminIDs []uint
-		if rows, err := database.DB.Raw("SELECT id FROM admins").Rows(); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
-			log.Printf("CreateGuru: gagal ambil admin ids: %v", err)
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		recipientIDs = append(recipientIDs, guru.ID)
-
-		uniq := map[uint]struct{}{}
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Akun Guru Baru Dibuat"
-		body := fmt.Sprintf("Akun guru %s (%s) berhasil dibuat.", guru.Nama, guru.Email)
-		payload := map[string]interface{}{
-			"type":    "create_guru",
-			"guru_id": fmt.Sprintf("%d", guru.ID),
-			"nama":    guru.Nama,
-			"email":   guru.Email,
-		}
-
-		if err := firebaseclient.SendNotify(context.Background(), "create_guru", title, body, payload, finalRecipients); err != nil {
-			log.Printf("CreateGuru: NotifyUsers failed: %v", err)
-		}
-	}(newGuru)
 }
 
 func UpdateGuru(c *gin.Context) {
@@ -158,6 +136,7 @@ func UpdateGuru(c *gin.Context) {
 		return
 	}
 
+	gantiEmail := req.Email != guru.Email
 	guru.Nama = req.Nama
 	guru.NIP = req.NIP
 	guru.NIK = req.NIK
@@ -166,7 +145,19 @@ func UpdateGuru(c *gin.Context) {
 	guru.Alamat = req.Alamat
 	guru.JenisKelamin = req.JenisKelamin
 
-	if err := database.DB.Save(&guru).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if gantiEmail {
+			if err := pastikanEmailBebas(tx, guru.Email, models.Principal{UserType: models.UserTypeGuru, ID: guru.ID}); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&guru).Error
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, "email sudah terdaftar")
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memperbarui data guru")
 		return
 	}
@@ -191,15 +182,8 @@ func DeleteGuru(c *gin.Context) {
 }
 
 func GetPengajaranGuru(c *gin.Context) {
-	roleVal, ok := c.Get("role")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "role tidak ditemukan di context")
-		return
-	}
-	role, _ := roleVal.(string)
-
 	var guruID uint
-	if role == "guru" {
+	if punyaRole(c, "guru") {
 		uidVal, ok := c.Get("user_id")
 		if !ok {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "user_id tidak ditemukan di context")
@@ -218,7 +202,7 @@ func GetPengajaranGuru(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
 			return
 		}
-	} else if role == "admin" {
+	} else if punyaRole(c, "admin") {
 		gq := c.Query("guru_id")
 		if gq == "" {
 			utils.ErrorResponse(c, http.StatusBadRequest, "admin harus menyertakan query param guru_id")
@@ -255,4 +239,105 @@ func GetPengajaranGuru(c *gin.Context) {
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Daftar mapel & kelas yang diajar", rows)
-}
\ No newline at end of file
+}
+
+// sesiHariIni adalah satu sesi mengajar pada respons GetSesiHariIni.
+type sesiHariIni struct {
+	MapelID       uint                   `json:"mapel_id"`
+	MapelNama     string                 `json:"mapel_nama"`
+	KelasID       uint                   `json:"kelas_id"`
+	KelasNama     string                 `json:"kelas_nama"`
+	JamMulai      string                 `json:"jam_mulai"`
+	JamSelesai    string                 `json:"jam_selesai"`
+	JumlahSiswa   int64                  `json:"jumlah_siswa"`
+	JumlahTerisi  int64                  `json:"jumlah_terisi"`
+	StatusAbsensi string                 `json:"status_absensi"` // belum_diisi | sebagian | lengkap
+	Dibatalkan    bool                   `json:"dibatalkan"`
+	Keterangan    string                 `json:"keterangan,omitempty"`
+	DeepLink      map[string]interface{} `json:"deep_link"`
+}
+
+func GetSesiHariIni(c *gin.Context) {
+	guruID, ok := currentUserID(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "user_id tidak ditemukan di context")
+		return
+	}
+
+	tanggal := time.Now()
+	if tgl := c.Query("tanggal"); tgl != "" {
+		t, err := time.ParseInLocation("2006-01-02", tgl, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal harus YYYY-MM-DD")
+			return
+		}
+		tanggal = t
+	}
+	tanggal = time.Date(tanggal.Year(), tanggal.Month(), tanggal.Day(), 0, 0, 0, 0, time.Local)
+
+	db := database.DB
+	hari := jadwal.NamaHari(tanggal)
+
+	sesiGuru, err := jadwal.SesiGuru(db, guruID, jadwal.TahunAjaran(tanggal), jadwal.Semester(tanggal))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil jadwal mengajar: "+err.Error())
+		return
+	}
+
+	keteranganLibur, libur, err := jadwal.CekLibur(db, tanggal)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa hari libur: "+err.Error())
+		return
+	}
+
+	result := make([]sesiHariIni, 0)
+	for _, s := range sesiGuru {
+		if s.Hari != hari {
+			continue
+		}
+
+		roster, err := jadwal.RosterMapel(db, s.KelasID, s.MapelID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil daftar siswa: "+err.Error())
+			return
+		}
+		mapelID := s.MapelID
+		terisi, err := jadwal.JumlahTerisi(db, s.KelasID, &mapelID, tanggal, roster)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung absensi: "+err.Error())
+			return
+		}
+
+		item := sesiHariIni{
+			MapelID:       s.MapelID,
+			MapelNama:     s.MapelNama,
+			KelasID:       s.KelasID,
+			KelasNama:     s.KelasNama,
+			JamMulai:      s.JamMulai,
+			JamSelesai:    s.JamSelesai,
+			JumlahSiswa:   int64(len(roster)),
+			JumlahTerisi:  terisi,
+			StatusAbsensi: jadwal.StatusPengisian(terisi, int64(len(roster))),
+			DeepLink:      jadwal.DeepLinkAbsensi(s.KelasID, s.MapelID, tanggal),
+		}
+
+		if libur {
+			item.Dibatalkan = true
+			item.Keterangan = "Libur: " + keteranganLibur
+		} else {
+			alasan, batal, err := jadwal.CekPembatalan(db, s.MapelID, s.KelasID, tanggal)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa pembatalan sesi: "+err.Error())
+				return
+			}
+			if batal {
+				item.Dibatalkan = true
+				item.Keterangan = alasan
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sesi mengajar "+hari+" "+tanggal.Format("2006-01-02"), result)
+}
@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"abs-be/database"
+	"abs-be/jadwal"
+	"abs-be/models"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lokasiKalender adalah zona waktu feed kalender, diatur lewat KALENDER_TZ
+// (default Asia/Jakarta).
+func lokasiKalender() *time.Location {
+	if nama := os.Getenv("KALENDER_TZ"); nama != "" {
+		if loc, err := time.LoadLocation(nama); err == nil {
+			return loc
+		}
+	}
+	loc, _ := time.LoadLocation("Asia/Jakarta")
+	return loc
+}
+
+func kalenderBaseURL(c *gin.Context) string {
+	if base := os.Getenv("APP_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func feedRoleFor(role string) (string, bool) {
+	switch role {
+	case "guru", "wali_kelas":
+		return "guru", true
+	case "siswa":
+		return "siswa", true
+	}
+	return "", false
+}
+
+func CreateKalenderToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	feedRole, ok := feedRoleFor(currentRole(c))
+	if !ok {
+		utils.ErrorResponse(c, http.StatusForbidden, "Feed kalender hanya tersedia untuk guru dan siswa")
+		return
+	}
+
+	raw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat token kalender")
+		return
+	}
+
+	now := time.Now()
+	tx := database.DB.Begin()
+	if err := tx.Model(&models.CalendarFeedToken{}).
+		Where("user_id = ? AND role = ? AND revoked_at IS NULL", userID, feedRole).
+		Update("revoked_at", now).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mencabut token kalender lama: "+err.Error())
+		return
+	}
+	feed := models.CalendarFeedToken{
+		UserID:    userID,
+		Role:      feedRole,
+		TokenHash: utils.HashToken(raw),
+	}
+	if err := tx.Create(&feed).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan token kalender: "+err.Error())
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
+		return
+	}
+
+	url := kalenderBaseURL(c) + "/api/kalender/feed/" + raw + ".ics"
+	webcal := url
+	if i := strings.Index(url, "://"); i >= 0 {
+		webcal = "webcal" + url[i:]
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Feed kalender berhasil dibuat", gin.H{
+		"id":         feed.ID,
+		"url":        url,
+		"webcal_url": webcal,
+	})
+}
+
+func RevokeKalenderToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	feedRole, ok := feedRoleFor(currentRole(c))
+	if !ok {
+		utils.ErrorResponse(c, http.StatusForbidden, "Feed kalender hanya tersedia untuk guru dan siswa")
+		return
+	}
+
+	res := database.DB.Model(&models.CalendarFeedToken{}).
+		Where("user_id = ? AND role = ? AND revoked_at IS NULL", userID, feedRole).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mencabut feed kalender: "+res.Error.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Feed kalender berhasil dicabut", gin.H{"revoked": res.RowsAffected})
+}
+
+func GetKalenderFeed(c *gin.Context) {
+	raw := strings.TrimSuffix(c.Param("token"), ".ics")
+	if raw == "" {
+		utils.ErrorResponse(c, http.StatusNotFound, "Feed kalender tidak ditemukan")
+		return
+	}
+
+	var feed models.CalendarFeedToken
+	if err := database.DB.Where("token_hash = ? AND revoked_at IS NULL", utils.HashToken(raw)).First(&feed).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Feed kalender tidak ditemukan")
+		return
+	}
+
+	loc := lokasiKalender()
+	now := time.Now().In(loc)
+	ta := jadwal.TahunAjaran(now)
+	sem := jadwal.Semester(now)
+
+	var (
+		sesi     []jadwal.Sesi
+		namaFeed string
+		err      error
+	)
+	switch feed.Role {
+	case "guru":
+		var guru models.Guru
+		if err := database.DB.First(&guru, feed.UserID).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Guru tidak ditemukan")
+			return
+		}
+		namaFeed = "Jadwal Mengajar " + guru.Nama
+		sesi, err = jadwal.SesiGuru(database.DB, guru.ID, ta, sem)
+	case "siswa":
+		var siswa models.Siswa
+		if err := database.DB.First(&siswa, feed.UserID).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Siswa tidak ditemukan")
+			return
+		}
+		namaFeed = "Jadwal Pelajaran " + siswa.Nama
+		sesi, err = jadwal.SesiSiswa(database.DB, siswa.ID, ta, sem)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil jadwal: "+err.Error())
+		return
+	}
+
+	mulai, selesai, err := jadwal.RentangSemester(ta, sem, loc)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	libur, err := jadwal.HariLiburAntara(database.DB, mulai, selesai)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil hari libur: "+err.Error())
+		return
+	}
+	batal, err := jadwal.PembatalanAntara(database.DB, mulai, selesai)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pembatalan jadwal: "+err.Error())
+		return
+	}
+
+	events := buildKalenderEvents(feed.Role, sesi, mulai, selesai, libur, batal)
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", "inline; filename=jadwal.ics")
+	c.Header("Cache-Control", "no-cache")
+	c.String(http.StatusOK, utils.BuildICalendar(namaFeed, loc, events))
+}
+
+// buildKalenderEvents menyusun event feed dalam zona waktu mulai; tanggal
+// libur dan pembatalan dibandingkan sebagai tanggal kalender di zona yang sama.
+func buildKalenderEvents(feedRole string, sesi []jadwal.Sesi, mulai, selesai time.Time, libur []models.HariLibur, batal []models.PembatalanJadwal) []utils.ICalEvent {
+	loc := mulai.Location()
+	tgl := func(t time.Time) string { return t.In(loc).Format("2006-01-02") }
+	isLibur := func(d time.Time) bool {
+		key := tgl(d)
+		for _, l := range libur {
+			if key >= tgl(l.TanggalMulai) && key <= tgl(l.TanggalSelesai) {
+				return true
+			}
+		}
+		return false
+	}
+	isBatal := func(s jadwal.Sesi, d time.Time) bool {
+		key := tgl(d)
+		for _, b := range batal {
+			if b.MapelID == s.MapelID && b.KelasID == s.KelasID && tgl(b.Tanggal) == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	until := time.Date(selesai.Year(), selesai.Month(), selesai.Day(), 23, 59, 59, 0, loc)
+	events := make([]utils.ICalEvent, 0, len(sesi)+len(libur))
+
+	for _, s := range sesi {
+		wd, ok := jadwal.HariKe(s.Hari)
+		if !ok {
+			continue
+		}
+		first := mulai
+		for first.Weekday() != wd {
+			first = first.AddDate(0, 0, 1)
+		}
+		start, err := jadwal.WaktuSesi(first, s.JamMulai)
+		if err != nil {
+			continue
+		}
+		end, err := jadwal.WaktuSesi(first, s.JamSelesai)
+		if err != nil {
+			continue
+		}
+
+		var exdates []time.Time
+		for d := first; !d.After(selesai); d = d.AddDate(0, 0, 7) {
+			if isLibur(d) || isBatal(s, d) {
+				exdate, _ := jadwal.WaktuSesi(d, s.JamMulai)
+				exdates = append(exdates, exdate)
+			}
+		}
+
+		summary := fmt.Sprintf("%s - %s", s.MapelNama, s.KelasNama)
+		if feedRole == "siswa" && s.GuruNama != "" {
+			summary = fmt.Sprintf("%s (%s)", s.MapelNama, s.GuruNama)
+		}
+
+		events = append(events, utils.ICalEvent{
+			UID: fmt.Sprintf("sesi-%d-%d-%d-%s-%s@abs-be",
+				s.GuruID, s.MapelID, s.KelasID, strings.ReplaceAll(s.TahunAjaran, "/", "-"), s.Semester),
+			Summary:     summary,
+			Description: fmt.Sprintf("Mapel %s (%s), kelas %s, guru %s. Tahun ajaran %s semester %s.", s.MapelNama, s.MapelKode, s.KelasNama, s.GuruNama, s.TahunAjaran, s.Semester),
+			Location:    "Kelas " + s.KelasNama,
+			Start:       start,
+			End:         end,
+			RRule:       "FREQ=WEEKLY;UNTIL=" + until.UTC().Format("20060102T150405Z"),
+			ExDates:     exdates,
+		})
+	}
+
+	for _, l := range libur {
+		events = append(events, utils.ICalEvent{
+			UID:     fmt.Sprintf("libur-%d@abs-be", l.ID),
+			Summary: "Libur: " + l.Keterangan,
+			Start:   l.TanggalMulai,
+			End:     l.TanggalSelesai.AddDate(0, 0, 1),
+			AllDay:  true,
+		})
+	}
+
+	return events
+}
+
+func CreateHariLibur(c *gin.Context) {
+	var req requests.HariLiburRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	mulai, err := time.ParseInLocation("2006-01-02", req.TanggalMulai, time.Local)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal_mulai harus YYYY-MM-DD")
+		return
+	}
+	selesai := mulai
+	if req.TanggalSelesai != "" {
+		selesai, err = time.ParseInLocation("2006-01-02", req.TanggalSelesai, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal_selesai harus YYYY-MM-DD")
+			return
+		}
+	}
+	if selesai.Before(mulai) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "tanggal_selesai tidak boleh sebelum tanggal_mulai")
+		return
+	}
+
+	libur := models.HariLibur{
+		TanggalMulai:   mulai,
+		TanggalSelesai: selesai,
+		Keterangan:     req.Keterangan,
+	}
+	if err := database.DB.Create(&libur).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan hari libur: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Hari libur berhasil ditambahkan", libur)
+}
+
+func GetHariLibur(c *gin.Context) {
+	q := database.DB.Model(&models.HariLibur{})
+	if dari := c.Query("dari"); dari != "" {
+		q = q.Where("tanggal_selesai >= ?", dari)
+	}
+	if sampai := c.Query("sampai"); sampai != "" {
+		q = q.Where("tanggal_mulai <= ?", sampai)
+	}
+
+	var libur []models.HariLibur
+	if err := q.Order("tanggal_mulai ASC").Find(&libur).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil hari libur: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Daftar hari libur", libur)
+}
+
+func DeleteHariLibur(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	res := database.DB.Delete(&models.HariLibur{}, uint(id))
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus hari libur: "+res.Error.Error())
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Hari libur tidak ditemukan")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Hari libur berhasil dihapus", nil)
+}
+
+func CreatePembatalanJadwal(c *gin.Context) {
+	var req requests.PembatalanJadwalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	tanggal, err := time.ParseInLocation("2006-01-02", req.Tanggal, time.Local)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Format tanggal harus YYYY-MM-DD")
+		return
+	}
+
+	var mapel models.MataPelajaran
+	if err := database.DB.First(&mapel, req.MapelID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mapel tidak ditemukan")
+		return
+	}
+	var kelas models.Kelas
+	if err := database.DB.First(&kelas, req.KelasID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Kelas tidak ditemukan")
+		return
+	}
+	if jadwal.NamaHari(tanggal) != mapel.Hari {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Mapel %s dijadwalkan hari %s, bukan %s", mapel.Nama, mapel.Hari, jadwal.NamaHari(tanggal)))
+		return
+	}
+
+	batal := models.PembatalanJadwal{
+		MapelID: req.MapelID,
+		KelasID: req.KelasID,
+		Tanggal: tanggal,
+		Alasan:  req.Alasan,
+	}
+	if err := database.DB.Create(&batal).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusConflict, "Gagal menyimpan pembatalan (mungkin sudah ada): "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Pembatalan jadwal berhasil disimpan", batal)
+}
+
+func GetPembatalanJadwal(c *gin.Context) {
+	q := database.DB.Model(&models.PembatalanJadwal{})
+	if kelasID := c.Query("kelas_id"); kelasID != "" {
+		q = q.Where("kelas_id = ?", kelasID)
+	}
+	if mapelID := c.Query("mapel_id"); mapelID != "" {
+		q = q.Where("mapel_id = ?", mapelID)
+	}
+
+	var batal []models.PembatalanJadwal
+	if err := q.Order("tanggal ASC").Find(&batal).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pembatalan jadwal: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Daftar pembatalan jadwal", batal)
+}
+
+func DeletePembatalanJadwal(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	res := database.DB.Delete(&models.PembatalanJadwal{}, uint(id))
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus pembatalan jadwal: "+res.Error.Error())
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Pembatalan jadwal tidak ditemukan")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Pembatalan jadwal berhasil dihapus", nil)
+}
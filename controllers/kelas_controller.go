@@ -2,12 +2,10 @@ package controllers
 
 import (
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
-	"context"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -47,6 +45,23 @@ func CreateKelas(c *gin.Context) {
 		return
 	}
 
+	adminIDs, err := semuaAdminIDs(tx)
+	if err != nil {
+		log.Printf("warning: gagal ambil admin ids: %v", err)
+	}
+	if err := enqueuePerRole(tx, notifikasi.CreateKelas{
+		KelasID:     newKelas.ID,
+		KelasNama:   newKelas.Nama,
+		Tingkat:     newKelas.Tingkat,
+		TahunAjaran: newKelas.TahunAjaran,
+	}, map[string][]uint{
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit: "+err.Error())
@@ -57,63 +72,6 @@ func CreateKelas(c *gin.Context) {
 		log.Printf("warning: gagal preload kelas setelah create: %v", err)
 	}
 
-	go func(kelas models.Kelas) {
-		var recipientIDs []uint
-
-		var adminIDs []uint
-		if rows, err := database.DB.Raw("SELECT id FROM admins").Rows(); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
-			log.Printf("warning: gagal ambil admin ids: %v", err)
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		// (notif semua guru)
-		// var guruIDs []uint
-		// if rows, err := database.DB.Raw("SELECT id FROM gurus").Rows(); err == nil {
-		// 	defer rows.Close()
-		// 	for rows.Next() {
-		// 		var id uint
-		// 		_ = rows.Scan(&id)
-		// 		guruIDs = append(guruIDs, id)
-		// 	}
-		// } else {
-		// 	log.Printf("warning: gagal ambil guru ids: %v", err)
-		// }
-		// recipientIDs = append(recipientIDs, guruIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Kelas Baru Dibuat"
-		body := fmt.Sprintf("Kelas %s (%s, TA %s) telah dibuat.", kelas.Nama, kelas.Tingkat, kelas.TahunAjaran)
-		payload := map[string]interface{}{
-			"type":        "create_kelas",
-			"kelas_id":    fmt.Sprintf("%d", kelas.ID),
-			"kelas_nama":  kelas.Nama,
-			"tingkat":     kelas.Tingkat,
-			"tahun_ajaran": kelas.TahunAjaran,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "create_kelas", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (create_kelas): %v", err)
-		}
-	}(newKelas)
-
 	utils.SuccessResponse(c, http.StatusCreated, "Kelas berhasil dibuat", newKelas)
 }
 
@@ -164,6 +122,7 @@ func UpdateKelas(c *gin.Context) {
 		return
 	}
 
+	tingkatBerubah := kelas.Tingkat != req.Tingkat
 	kelas.Nama = req.Nama
 	kelas.Tingkat = req.Tingkat
 	kelas.TahunAjaran = req.TahunAjaran
@@ -172,6 +131,13 @@ func UpdateKelas(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui data kelas")
 		return
 	}
+	if tingkatBerubah {
+		if ids, err := siswaIDsKelas(database.DB, kelas.ID); err == nil {
+			sinkronTopikSiswa(ids...)
+		} else {
+			log.Printf("warning: gagal ambil siswa kelas %d untuk sinkron topic: %v", kelas.ID, err)
+		}
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Data kelas berhasil diperbarui", kelas)
 }
@@ -207,8 +173,8 @@ func DeleteKelas(c *gin.Context) {
 		return
 	}
 
-	var siswaIDs []uint
-	if err := tx.Table("kelas_siswas").Where("kelas_id = ?", kelas.ID).Pluck("siswa_id", &siswaIDs).Error; err != nil {
+	siswaIDs, err := siswaIDsKelas(tx, kelas.ID)
+	if err != nil {
 		log.Printf("warning: gagal ambil siswa di kelas %d: %v", kelas.ID, err)
 	}
 
@@ -218,58 +184,34 @@ func DeleteKelas(c *gin.Context) {
 		return
 	}
 
+	var waliIDs []uint
+	if kelas.WaliKelasID != nil && *kelas.WaliKelasID != 0 {
+		waliIDs = append(waliIDs, *kelas.WaliKelasID)
+	}
+	adminIDs, err := semuaAdminIDs(tx)
+	if err != nil {
+		log.Printf("warning: gagal ambil admin ids: %v", err)
+	}
+	if err := enqueuePerRole(tx, notifikasi.DeleteKelas{
+		KelasID:   kelas.ID,
+		KelasNama: kelas.Nama,
+	}, map[string][]uint{
+		"guru":  waliIDs,
+		"admin": adminIDs,
+		"siswa": siswaIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
 		return
 	}
-
-	go func(kelas models.Kelas, siswaIDs []uint) {
-		var recipientIDs []uint
-
-		if kelas.WaliKelasID != nil && *kelas.WaliKelasID != 0 {
-			recipientIDs = append(recipientIDs, *kelas.WaliKelasID)
-		}
-
-		var adminIDs []uint
-		if rows, err := database.DB.Raw("SELECT id FROM admins").Rows(); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
-			log.Printf("warning: gagal ambil admin ids: %v", err)
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-
-		recipientIDs = append(recipientIDs, siswaIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Kelas Dihapus"
-		body := fmt.Sprintf("Kelas %s telah dihapus dari sistem.", kelas.Nama)
-		payload := map[string]interface{}{
-			"type":       "delete_kelas",
-			"kelas_id":   fmt.Sprintf("%d", kelas.ID),
-			"kelas_nama": kelas.Nama,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "delete_kelas", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (delete_kelas): %v", err)
-		}
-	}(kelas, siswaIDs)
+	sinkronTopikSiswa(siswaIDs...)
+	sinkronTopikGuru(waliIDs...)
 
 	utils.SuccessResponse(c, http.StatusOK, "Data kelas berhasil dihapus", nil)
 }
@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Pesan yang sama untuk akun tidak ada dan password salah agar login tidak
+// bisa dipakai untuk menebak akun yang terdaftar.
+const pesanLoginGagal = "identifier atau password salah"
+
+const pesanAkunNonaktif = "akun dinonaktifkan, hubungi admin"
+
+const (
+	alasanAkunTidakAda     = "akun_tidak_ditemukan"
+	alasanPasswordSalah    = "password_salah"
+	alasanTerkunci         = "akun_terkunci"
+	alasanIPDibatasi       = "ip_dibatasi"
+	alasanIdentifierAmbigu = "identifier_ambigu"
+	alasanAkunNonaktif     = "akun_nonaktif"
+)
+
+// Hash bcrypt dari string acak, dipakai saat akun tidak ditemukan agar
+// waktu respons sama dengan pengecekan password sungguhan.
+const hashPalsu = "$2a$10$breBHvkFheNydYbmQddE6.6bw3dcESJHJQ2AC7DCtZMSqTKklD4su"
+
+func envAngka(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// batasLogin dibaca dari env: LOGIN_MAKS_GAGAL (default 5) gagal berturut-turut
+// mengunci akun selama LOGIN_KUNCI_MENIT (default 15, berlipat dua setiap
+// gagal berikutnya, maks 24 jam); LOGIN_MAKS_GAGAL_IP (default 20) gagal dari
+// satu IP dalam LOGIN_JENDELA_IP_MENIT (default 15) menolak IP tsb.
+type batasLogin struct {
+	maksGagal   int
+	kunci       time.Duration
+	maksGagalIP int
+	jendelaIP   time.Duration
+}
+
+func bacaBatasLogin() batasLogin {
+	return batasLogin{
+		maksGagal:   envAngka("LOGIN_MAKS_GAGAL", 5),
+		kunci:       time.Duration(envAngka("LOGIN_KUNCI_MENIT", 15)) * time.Minute,
+		maksGagalIP: envAngka("LOGIN_MAKS_GAGAL_IP", 20),
+		jendelaIP:   time.Duration(envAngka("LOGIN_JENDELA_IP_MENIT", 15)) * time.Minute,
+	}
+}
+
+// jeda menghitung berapa lama akun harus menunggu setelah gagal ke-n.
+// Sebelum batas, jeda bertambah 5, 10, 20 ... detik mulai gagal ke-3.
+func (b batasLogin) jeda(gagal int) time.Duration {
+	if gagal >= b.maksGagal {
+		d := time.Duration(float64(b.kunci) * math.Pow(2, float64(gagal-b.maksGagal)))
+		if d <= 0 || d > 24*time.Hour {
+			d = 24 * time.Hour
+		}
+		return d
+	}
+	if gagal >= 3 {
+		return time.Duration(5<<uint(gagal-3)) * time.Second
+	}
+	return 0
+}
+
+func catatPercobaanLogin(c *gin.Context, identifier string, p *models.Principal, berhasil bool, alasan string) {
+	ua := c.Request.UserAgent()
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	if len(identifier) > 100 {
+		identifier = identifier[:100]
+	}
+	row := models.LoginAttempt{
+		Identifier: identifier,
+		IP:         c.ClientIP(),
+		UserAgent:  ua,
+		Berhasil:   berhasil,
+		Alasan:     alasan,
+	}
+	if p != nil {
+		row.UserType = p.UserType
+		row.UserID = &p.ID
+	}
+	if err := database.DB.Create(&row).Error; err != nil {
+		log.Printf("login: gagal mencatat percobaan login: %v", err)
+	}
+}
+
+func pesanTunggu(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d detik", int(math.Ceil(d.Seconds())))
+	}
+	return fmt.Sprintf("%d menit", int(math.Ceil(d.Minutes())))
+}
+
+// Jenis identifier login.
+const (
+	identifierEmail = "email"
+	identifierNISN  = "nisn"
+	identifierNIP   = "nip"
+)
+
+var errIdentifierAmbigu = errors.New("identifier cocok dengan lebih dari satu akun")
+
+type kolomIdentifier struct {
+	userType string
+	kolom    string
+	jenis    string
+}
+
+// kolomLogin menentukan kolom yang dicocokkan dengan identifier: email di
+// semua tabel akun, atau NISN siswa dan NIP guru untuk identifier tanpa @.
+// userType tidak kosong membatasi pencarian ke satu jenis user.
+func kolomLogin(identifier, userType string) []kolomIdentifier {
+	var semua []kolomIdentifier
+	if strings.Contains(identifier, "@") {
+		for _, ut := range []string{models.UserTypeAdmin, models.UserTypeGuru, models.UserTypeSiswa, models.UserTypeOrangTua} {
+			semua = append(semua, kolomIdentifier{ut, "email", identifierEmail})
+		}
+	} else {
+		semua = []kolomIdentifier{
+			{models.UserTypeSiswa, "nisn", identifierNISN},
+			{models.UserTypeGuru, "nip", identifierNIP},
+		}
+	}
+	if userType == "" {
+		return semua
+	}
+	var hasil []kolomIdentifier
+	for _, k := range semua {
+		if k.userType == userType {
+			hasil = append(hasil, k)
+		}
+	}
+	return hasil
+}
+
+// cariAkun mencocokkan identifier dengan akun. Identifier yang cocok dengan
+// lebih dari satu akun ditolak, bukan dipilih berdasarkan urutan tabel.
+func cariAkun(identifier, userType string) (models.Principal, string, error) {
+	var (
+		cocok []models.Principal
+		jenis string
+	)
+	for _, k := range kolomLogin(identifier, userType) {
+		var ids []uint
+		if err := database.DB.Table(models.TabelAkun(k.userType)).
+			Where(k.kolom+" = ?", identifier).Limit(2).Pluck("id", &ids).Error; err != nil {
+			return models.Principal{}, "", err
+		}
+		for _, id := range ids {
+			cocok = append(cocok, models.Principal{UserType: k.userType, ID: id})
+			jenis = k.jenis
+		}
+	}
+	switch len(cocok) {
+	case 0:
+		return models.Principal{}, "", gorm.ErrRecordNotFound
+	case 1:
+		return cocok[0], jenis, nil
+	}
+	return models.Principal{}, "", errIdentifierAmbigu
+}
+
+// kunciIdentifier menormalkan identifier menjadi kunci penguncian login.
+func kunciIdentifier(identifier string) string {
+	kunci := strings.ToLower(strings.TrimSpace(identifier))
+	if len(kunci) > 100 {
+		kunci = kunci[:100]
+	}
+	return kunci
+}
+
+// autentikasi memeriksa identifier (email, NISN atau NIP) dan password
+// dengan pembatasan per IP dan per identifier, mencatat percobaannya, dan
+// menulis respons error bila gagal. Jenis identifier yang cocok ikut
+// dikembalikan.
+//
+// Identifier yang tidak terdaftar atau cocok dengan lebih dari satu akun
+// diperlakukan sama dengan password salah: hitungan gagal dan penguncian
+// tetap berjalan, bcrypt tetap dijalankan, dan responsnya sama, sehingga
+// login tidak bisa dipakai untuk menebak akun yang terdaftar.
+func autentikasi(c *gin.Context, identifier, password, userType string) (models.Principal, string, bool) {
+	batas := bacaBatasLogin()
+	now := time.Now()
+	ip := c.ClientIP()
+	identifier = strings.TrimSpace(identifier)
+	kunci := kunciIdentifier(identifier)
+
+	var gagalIP int64
+	database.DB.Model(&models.LoginAttempt{}).
+		Where("ip = ? AND berhasil = ? AND alasan IN ? AND created_at > ?", ip, false,
+			[]string{alasanAkunTidakAda, alasanPasswordSalah, alasanIdentifierAmbigu}, now.Add(-batas.jendelaIP)).
+		Count(&gagalIP)
+	if int(gagalIP) >= batas.maksGagalIP {
+		catatPercobaanLogin(c, identifier, nil, false, alasanIPDibatasi)
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "terlalu banyak percobaan login, coba lagi nanti")
+		return models.Principal{}, "", false
+	}
+
+	var lockout models.LoginLockout
+	if err := database.DB.Where("identifier = ?", kunci).
+		Limit(1).Find(&lockout).Error; err == nil && lockout.TerkunciSampai != nil && lockout.TerkunciSampai.After(now) {
+		catatPercobaanLogin(c, identifier, nil, false, alasanTerkunci)
+		utils.ErrorResponse(c, http.StatusTooManyRequests,
+			"terlalu banyak percobaan gagal, coba lagi dalam "+pesanTunggu(lockout.TerkunciSampai.Sub(now)))
+		return models.Principal{}, "", false
+	}
+
+	p, jenis, err := cariAkun(identifier, userType)
+	if err != nil {
+		utils.CheckPasswordHash(password, hashPalsu)
+		alasan := alasanAkunTidakAda
+		if errors.Is(err, errIdentifierAmbigu) {
+			alasan = alasanIdentifierAmbigu
+		}
+		catatGagalLogin(kunci, batas, now)
+		catatPercobaanLogin(c, identifier, nil, false, alasan)
+		utils.ErrorResponse(c, http.StatusUnauthorized, pesanLoginGagal)
+		return models.Principal{}, "", false
+	}
+
+	akun, err := ambilAkunPassword(database.DB, p)
+	if err != nil {
+		utils.CheckPasswordHash(password, hashPalsu)
+	}
+	if err != nil || !utils.CheckPasswordHash(password, akun.Password) {
+		catatGagalLogin(kunci, batas, now)
+		catatPercobaanLogin(c, identifier, &p, false, alasanPasswordSalah)
+		utils.ErrorResponse(c, http.StatusUnauthorized, pesanLoginGagal)
+		return p, "", false
+	}
+
+	database.DB.Where("identifier = ?", kunci).Delete(&models.LoginLockout{})
+	// Status nonaktif baru diungkapkan setelah password terbukti benar.
+	if akun.DisabledAt != nil {
+		catatPercobaanLogin(c, identifier, &p, false, alasanAkunNonaktif)
+		utils.ErrorResponse(c, http.StatusForbidden, pesanAkunNonaktif)
+		return p, "", false
+	}
+	catatPercobaanLogin(c, identifier, &p, true, "")
+	return p, jenis, true
+}
+
+// catatGagalLogin menambah hitungan gagal identifier lalu menetapkan jeda
+// atau penguncian berikutnya.
+func catatGagalLogin(kunci string, batas batasLogin, now time.Time) {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{"gagal": gorm.Expr("gagal + 1")}),
+		}).Create(&models.LoginLockout{Identifier: kunci, Gagal: 1}).Error; err != nil {
+			return err
+		}
+		var lockout models.LoginLockout
+		if err := tx.Where("identifier = ?", kunci).First(&lockout).Error; err != nil {
+			return err
+		}
+		d := batas.jeda(lockout.Gagal)
+		if d == 0 {
+			return nil
+		}
+		sampai := now.Add(d)
+		return tx.Model(&models.LoginLockout{}).Where("identifier = ?", kunci).
+			Update("terkunci_sampai", sampai).Error
+	})
+	if err != nil {
+		log.Printf("login: gagal memperbarui penguncian %q: %v", kunci, err)
+	}
+}
+
+// identifierAkun mengembalikan semua kunci identifier yang bisa dipakai
+// login ke akun p: email, ditambah NISN untuk siswa dan NIP untuk guru.
+func identifierAkun(p models.Principal) ([]string, error) {
+	kolom := []string{"email"}
+	for _, k := range kolomLogin("", p.UserType) {
+		kolom = append(kolom, k.kolom)
+	}
+	var row struct {
+		Email *string
+		NISN  *string `gorm:"column:nisn"`
+		NIP   *string `gorm:"column:nip"`
+	}
+	res := database.DB.Table(models.TabelAkun(p.UserType)).Select(kolom).Where("id = ?", p.ID).Scan(&row)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	var hasil []string
+	for _, v := range []*string{row.Email, row.NISN, row.NIP} {
+		if v != nil && *v != "" {
+			hasil = append(hasil, kunciIdentifier(*v))
+		}
+	}
+	return hasil, nil
+}
+
+// GetLoginHistory menampilkan riwayat login akun yang sedang login.
+func GetLoginHistory(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	page, perPage := halaman(c)
+	q := database.DB.Model(&models.LoginAttempt{}).Where("user_type = ? AND user_id = ?", me.UserType, me.ID)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil riwayat login")
+		return
+	}
+	var rows []models.LoginAttempt
+	if err := q.Order("id DESC").Limit(perPage).Offset((page - 1) * perPage).Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengambil riwayat login")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "riwayat login", gin.H{
+		"items":    rows,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// AdminUnlockAkun membuka penguncian login semua identifier sebuah akun
+// (email, NISN, NIP).
+func AdminUnlockAkun(c *gin.Context) {
+	p, ok := principalParam(c)
+	if !ok || !cekBolehKelolaAkun(c, p) {
+		return
+	}
+	kunci, err := identifierAkun(p)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuka kunci akun")
+		return
+	}
+	if len(kunci) > 0 {
+		if err := database.DB.Where("identifier IN ?", kunci).Delete(&models.LoginLockout{}).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuka kunci akun")
+			return
+		}
+	}
+	utils.SuccessResponse(c, http.StatusOK, "kunci login akun dibuka", nil)
+}
@@ -2,12 +2,10 @@ package controllers
 
 import (
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
-	"context"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -66,6 +64,27 @@ func CreateMataPelajaran(c *gin.Context) {
 		return
 	}
 
+	adminIDs, err := semuaAdminIDs(tx)
+	if err != nil {
+		log.Printf("warning: gagal ambil admins: %v", err)
+	}
+	if err := enqueuePerRole(tx, notifikasi.CreateMapel{
+		MapelID:    newMapel.ID,
+		MapelNama:  newMapel.Nama,
+		MapelKode:  newMapel.Kode,
+		Tingkat:    newMapel.Tingkat,
+		Semester:   newMapel.Semester,
+		Hari:       newMapel.Hari,
+		JamMulai:   newMapel.JamMulai,
+		JamSelesai: newMapel.JamSelesai,
+	}, map[string][]uint{
+		"admin": adminIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit: "+err.Error())
@@ -76,66 +95,6 @@ func CreateMataPelajaran(c *gin.Context) {
 		log.Printf("warning preload mapel setelah create: %v", err)
 	}
 
-	go func(mapel models.MataPelajaran) {
-		var recipientIDs []uint
-
-		var adminIDs []uint
-		if rows, err := database.DB.Raw("SELECT id FROM admins").Rows(); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
-			log.Printf("warning: gagal ambil admins: %v", err)
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-		// notif semua guru
-		// var guruIDs []uint
-		// if rows, err := database.DB.Raw("SELECT id FROM gurus").Rows(); err == nil {
-		// 	defer rows.Close()
-		// 	for rows.Next() {
-		// 		var id uint
-		// 		_ = rows.Scan(&id)
-		// 		guruIDs = append(guruIDs, id)
-		// 	}
-		// } else {
-		// 	log.Printf("warning: gagal ambil gurus: %v", err)
-		// }
-		// recipientIDs = append(recipientIDs, guruIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Mata Pelajaran Baru"
-		body := fmt.Sprintf("Mata pelajaran %s (%s) berhasil dibuat.", mapel.Nama, mapel.Kode)
-		payload := map[string]interface{}{
-			"type":        "create_mapel",
-			"mapel_id":    fmt.Sprintf("%d", mapel.ID),
-			"mapel_nama":  mapel.Nama,
-			"mapel_kode":  mapel.Kode,
-			"tingkat":     mapel.Tingkat,
-			"semester":    mapel.Semester,
-			"hari":        mapel.Hari,
-			"jam_mulai":   mapel.JamMulai,
-			"jam_selesai": mapel.JamSelesai,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "create_mapel", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (create_mapel): %v", err)
-		}
-	}(newMapel)
-
 	utils.SuccessResponse(c, http.StatusCreated, "Mata pelajaran berhasil dibuat", newMapel)
 }
 
@@ -269,55 +228,29 @@ func DeleteMataPelajaran(c *gin.Context) {
 		return
 	}
 
+	adminIDs, err := semuaAdminIDs(tx)
+	if err != nil {
+		log.Printf("warning: gagal ambil admins: %v", err)
+	}
+	if err := enqueuePerRole(tx, notifikasi.DeleteMapel{
+		MapelID:   mapel.ID,
+		MapelNama: mapel.Nama,
+		MapelKode: mapel.Kode,
+	}, map[string][]uint{
+		"admin": adminIDs,
+		"guru":  guruIDs,
+		"siswa": siswaIDs,
+	}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan notifikasi: "+err.Error())
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit: "+err.Error())
 		return
 	}
 
-	go func(mapel models.MataPelajaran, guruIDs, siswaIDs []uint) {
-		var recipientIDs []uint
-
-		var adminIDs []uint
-		if rows, err := database.DB.Raw("SELECT id FROM admins").Rows(); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
-			log.Printf("warning: gagal ambil admins: %v", err)
-		}
-		recipientIDs = append(recipientIDs, adminIDs...)
-		recipientIDs = append(recipientIDs, guruIDs...)
-		recipientIDs = append(recipientIDs, siswaIDs...)
-
-		uniq := make(map[uint]struct{})
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Mata Pelajaran Dihapus"
-		body := fmt.Sprintf("Mata pelajaran %s (%s) telah dihapus.", mapel.Nama, mapel.Kode)
-		payload := map[string]interface{}{
-			"type":       "delete_mapel",
-			"mapel_id":   fmt.Sprintf("%d", mapel.ID),
-			"mapel_nama": mapel.Nama,
-			"mapel_kode": mapel.Kode,
-		}
-
-		if err := firebaseclient.NotifyUsers(context.Background(), "delete_mapel", title, body, payload, finalRecipients); err != nil {
-			log.Printf("NotifyUsers error (delete_mapel): %v", err)
-		}
-	}(mapel, guruIDs, siswaIDs)
-
 	utils.SuccessResponse(c, http.StatusOK, "Data mata pelajaran berhasil dihapus", nil)
 }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/outbox"
+	"abs-be/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func GetNotifOutbox(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if err != nil || perPage < 1 || perPage > 200 {
+		perPage = 20
+	}
+
+	db := database.DB.Model(&models.NotifOutbox{})
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if typeStr := c.Query("type"); typeStr != "" {
+		db = db.Where("type = ?", typeStr)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung outbox: "+err.Error())
+		return
+	}
+
+	var rows []models.NotifOutbox
+	if err := db.Order("id DESC").Limit(perPage).Offset((page - 1) * perPage).Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil outbox: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Daftar outbox notifikasi", gin.H{
+		"data":     rows,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+func GetNotifOutboxByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var row models.NotifOutbox
+	if err := database.DB.First(&row, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Entri outbox tidak ditemukan")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil outbox: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Detail outbox notifikasi", row)
+}
+
+func RedriveNotifOutbox(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	if err := outbox.Redrive(database.DB, uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Entri outbox tidak ditemukan atau sedang diproses/sudah terkirim")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menjadwalkan ulang: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Entri outbox dijadwalkan ulang", nil)
+}
+
+// RedriveNotifOutboxDead menjadwalkan ulang semua entri dead, opsional
+// dibatasi ?type=.
+func RedriveNotifOutboxDead(c *gin.Context) {
+	db := database.DB.Model(&models.NotifOutbox{}).Where("status = ?", models.OutboxDead)
+	if typeStr := c.Query("type"); typeStr != "" {
+		db = db.Where("type = ?", typeStr)
+	}
+	res := db.Updates(map[string]interface{}{
+		"status":          models.OutboxPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menjadwalkan ulang: "+res.Error.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Entri dead dijadwalkan ulang", gin.H{"jumlah": res.RowsAffected})
+}
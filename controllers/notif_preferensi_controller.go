@@ -0,0 +1,371 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"abs-be/database"
+	"abs-be/firebaseclient"
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func tipeWajibSet() (map[string]bool, error) {
+	var rows []models.NotifTipeWajib
+	if err := database.DB.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		set[r.Type] = true
+	}
+	return set, nil
+}
+
+func GetNotifPreferensi(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	wajib, err := tipeWajibSet()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil tipe wajib: "+err.Error())
+		return
+	}
+
+	var prefs []models.NotifPreferensi
+	if err := database.DB.Where("user_type = ? AND user_id = ?", me.UserType, me.ID).Find(&prefs).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil preferensi: "+err.Error())
+		return
+	}
+	perType := map[string]map[string]bool{}
+	for _, p := range prefs {
+		if perType[p.Type] == nil {
+			perType[p.Type] = map[string]bool{}
+		}
+		perType[p.Type][p.Channel] = p.Aktif
+	}
+
+	types := notifikasi.Types()
+	items := make([]requests.NotifPreferensiTypeResponse, 0, len(types))
+	for _, t := range types {
+		channels := make(map[string]bool, len(firebaseclient.ChannelPreferensi))
+		for _, ch := range firebaseclient.ChannelPreferensi {
+			aktif := true
+			if !wajib[t] {
+				if v, ok := perType[t][ch]; ok {
+					aktif = v
+				} else if v, ok := perType[t][firebaseclient.ChannelSemua]; ok {
+					aktif = v
+				}
+			}
+			channels[ch] = aktif
+		}
+		items = append(items, requests.NotifPreferensiTypeResponse{Type: t, Wajib: wajib[t], Channels: channels})
+	}
+
+	var jam models.NotifJamTenang
+	var jamResp interface{}
+	if err := database.DB.First(&jam, "user_type = ? AND user_id = ?", me.UserType, me.ID).Error; err == nil {
+		jamResp = jam
+	} else if err != gorm.ErrRecordNotFound {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil jam tenang: "+err.Error())
+		return
+	}
+
+	bahasa := notifikasi.BahasaDefault()
+	var pengaturan models.NotifPengaturan
+	if err := database.DB.First(&pengaturan, "user_type = ? AND user_id = ?", me.UserType, me.ID).Error; err == nil {
+		bahasa = pengaturan.Bahasa
+	} else if err != gorm.ErrRecordNotFound {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil bahasa: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Preferensi notifikasi", gin.H{
+		"bahasa":     bahasa,
+		"types":      items,
+		"jam_tenang": jamResp,
+	})
+}
+
+func UpdateNotifPreferensi(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	var req requests.NotifPreferensiRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	wajib, err := tipeWajibSet()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil tipe wajib: "+err.Error())
+		return
+	}
+
+	rows := make([]models.NotifPreferensi, 0, len(req.Items))
+	for _, it := range req.Items {
+		if !notifikasi.Dikenal(it.Type) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Tipe notifikasi %s tidak dikenal", it.Type))
+			return
+		}
+		if !firebaseclient.ChannelPreferensiValid(it.Channel) {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Channel %s tidak dikenal", it.Channel))
+			return
+		}
+		if wajib[it.Type] {
+			utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("Tipe notifikasi %s diwajibkan admin dan tidak dapat diubah", it.Type))
+			return
+		}
+		rows = append(rows, models.NotifPreferensi{
+			UserType: me.UserType,
+			UserID:   me.ID,
+			Type:     it.Type,
+			Channel:  it.Channel,
+			Aktif:    *it.Aktif,
+		})
+	}
+
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_type"}, {Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"aktif", "updated_at"}),
+	}).Create(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan preferensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Preferensi notifikasi berhasil disimpan", gin.H{"updated_count": len(rows)})
+}
+
+func UpdateNotifJamTenang(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	var req requests.NotifJamTenangRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+	for _, v := range []string{req.Mulai, req.Selesai} {
+		if _, err := time.Parse("15:04", v); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format jam salah (gunakan HH:MM)")
+			return
+		}
+	}
+	if req.Mulai == req.Selesai {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Jam mulai dan selesai tidak boleh sama")
+		return
+	}
+
+	jam := models.NotifJamTenang{
+		UserType: me.UserType,
+		UserID:   me.ID,
+		Mulai:    req.Mulai,
+		Selesai:  req.Selesai,
+		Aktif:    *req.Aktif,
+	}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_type"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mulai", "selesai", "aktif", "updated_at"}),
+	}).Create(&jam).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan jam tenang: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Jam tenang berhasil disimpan", jam)
+}
+
+func UpdateNotifBahasa(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	var req requests.NotifBahasaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+	if !notifikasi.BahasaValid(req.Bahasa) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Bahasa tidak didukung (gunakan id atau en)")
+		return
+	}
+
+	row := models.NotifPengaturan{UserType: me.UserType, UserID: me.ID, Bahasa: req.Bahasa}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_type"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"bahasa", "updated_at"}),
+	}).Create(&row).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan bahasa: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Bahasa notifikasi berhasil disimpan", row)
+}
+
+func GetNotifTipeWajib(c *gin.Context) {
+	var rows []models.NotifTipeWajib
+	if err := database.DB.Order("type").Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil tipe wajib: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Daftar tipe notifikasi wajib", rows)
+}
+
+func CreateNotifTipeWajib(c *gin.Context) {
+	var req requests.NotifTipeWajibRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+	if !notifikasi.Dikenal(req.Type) {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Tipe notifikasi %s tidak dikenal", req.Type))
+		return
+	}
+
+	row := models.NotifTipeWajib{Type: req.Type}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan tipe wajib: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "Tipe notifikasi berhasil diwajibkan", row)
+}
+
+func DeleteNotifTipeWajib(c *gin.Context) {
+	typeStr := c.Param("type")
+	res := database.DB.Where("type = ?", typeStr).Delete(&models.NotifTipeWajib{})
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus tipe wajib: "+res.Error.Error())
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Tipe notifikasi wajib tidak ditemukan")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Tipe notifikasi tidak lagi diwajibkan", gin.H{"type": typeStr})
+}
+
+func GetNotifDigest(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	pengaturan := models.NotifPengaturan{DigestFrekuensi: "harian", DigestJam: "07:00"}
+	if err := database.DB.Where("user_type = ? AND user_id = ?", me.UserType, me.ID).Limit(1).Find(&pengaturan).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengaturan digest: "+err.Error())
+		return
+	}
+
+	types := []string{}
+	if err := database.DB.Model(&models.NotifDigestType{}).
+		Where("user_type = ? AND user_id = ?", me.UserType, me.ID).
+		Order("type").Pluck("type", &types).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil tipe digest: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Pengaturan digest notifikasi", gin.H{
+		"frekuensi": pengaturan.DigestFrekuensi,
+		"jam":       pengaturan.DigestJam,
+		"types":     types,
+	})
+}
+
+func UpdateNotifDigest(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	var req requests.NotifDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+	if _, err := time.Parse("15:04", req.Jam); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Format jam salah (gunakan HH:MM)")
+		return
+	}
+
+	wajib, err := tipeWajibSet()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil tipe wajib: "+err.Error())
+		return
+	}
+
+	seen := map[string]bool{}
+	rows := make([]models.NotifDigestType, 0, len(req.Types))
+	for _, t := range req.Types {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if !notifikasi.Dikenal(t) || t == (notifikasi.DigestNotifikasi{}).Type() {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Tipe notifikasi %s tidak dapat dimasukkan ke digest", t))
+			return
+		}
+		if wajib[t] {
+			utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("Tipe notifikasi %s diwajibkan admin dan harus dikirim langsung", t))
+			return
+		}
+		rows = append(rows, models.NotifDigestType{UserType: me.UserType, UserID: me.ID, Type: t})
+	}
+
+	pengaturan := models.NotifPengaturan{
+		UserType:        me.UserType,
+		UserID:          me.ID,
+		Bahasa:          notifikasi.BahasaDefault(),
+		DigestFrekuensi: req.Frekuensi,
+		DigestJam:       req.Jam,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_type"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"digest_frekuensi", "digest_jam", "updated_at"}),
+		}).Create(&pengaturan).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_type = ? AND user_id = ?", me.UserType, me.ID).Delete(&models.NotifDigestType{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan pengaturan digest: "+err.Error())
+		return
+	}
+
+	types := make([]string, 0, len(rows))
+	for _, r := range rows {
+		types = append(types, r.Type)
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pengaturan digest berhasil disimpan", gin.H{
+		"frekuensi": req.Frekuensi,
+		"jam":       req.Jam,
+		"types":     types,
+	})
+}
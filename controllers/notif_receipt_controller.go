@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetNotifReceipts menampilkan tanda terima pengiriman push.
+// Query: notification_id, outbox_id, user_type, user_id, token, success
+// (true/false), error_code, page, per_page.
+func GetNotifReceipts(c *gin.Context) {
+	daftarReceipt(c, database.DB.Model(&models.NotifDeliveryReceipt{}))
+}
+
+// GetNotifOutboxReceipts menampilkan tanda terima satu entri outbox.
+func GetNotifOutboxReceipts(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	daftarReceipt(c, database.DB.Model(&models.NotifDeliveryReceipt{}).Where("outbox_id = ?", id))
+}
+
+func daftarReceipt(c *gin.Context, db *gorm.DB) {
+	page, perPage := halaman(c)
+
+	for _, kolom := range []string{"notification_id", "outbox_id", "user_id"} {
+		if v := c.Query(kolom); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, kolom+" tidak valid")
+				return
+			}
+			db = db.Where(kolom+" = ?", n)
+		}
+	}
+	for _, kolom := range []string{"user_type", "token", "error_code"} {
+		if v := c.Query(kolom); v != "" {
+			db = db.Where(kolom+" = ?", v)
+		}
+	}
+	switch c.Query("success") {
+	case "true":
+		db = db.Where("success = ?", true)
+	case "false":
+		db = db.Where("success = ?", false)
+	}
+
+	var ringkasan struct {
+		Total  int64
+		Sukses int64
+	}
+	if err := db.Session(&gorm.Session{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(success), 0) AS sukses").
+		Scan(&ringkasan).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung tanda terima: "+err.Error())
+		return
+	}
+
+	var rows []models.NotifDeliveryReceipt
+	if err := db.Order("id DESC").Limit(perPage).Offset((page - 1) * perPage).Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil tanda terima: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Tanda terima pengiriman notifikasi", gin.H{
+		"items":    rows,
+		"total":    ringkasan.Total,
+		"sukses":   ringkasan.Sukses,
+		"gagal":    ringkasan.Total - ringkasan.Sukses,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"abs-be/database"
 	"abs-be/models"
+	"abs-be/realtime"
 	"abs-be/requests"
 	"abs-be/utils"
 
@@ -14,25 +15,11 @@ import (
 )
 
 func GetNotifications(c *gin.Context) {
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	case float64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
 	pageStr := c.DefaultQuery("page", "1")
 	perPageStr := c.DefaultQuery("per_page", "20")
@@ -48,7 +35,7 @@ func GetNotifications(c *gin.Context) {
 	}
 	offset := (page - 1) * perPage
 
-	db := database.DB.Model(&models.Notification{}).Where("recipient = ?", userID)
+	db := database.DB.Model(&models.Notification{}).Where("recipient_type = ? AND recipient = ?", me.UserType, me.ID)
 	if unreadOnly == "true" || unreadOnly == "1" {
 		db = db.Where("`read` = ?", false)
 	}
@@ -105,30 +92,18 @@ func MarkNotificationRead(c *gin.Context) {
 	}
 	id := uint(id64)
 
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
 	var notif models.Notification
 	if err := database.DB.First(&notif, id).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Notifikasi tidak ditemukan")
 		return
 	}
-	if notif.Recipient != userID {
+	if notif.RecipientType != me.UserType || notif.Recipient != me.ID {
 		utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki akses ke notifikasi ini")
 		return
 	}
@@ -143,6 +118,7 @@ func MarkNotificationRead(c *gin.Context) {
 		return
 	}
 
+	terbitkanPerubahan(me, realtime.EventDibaca, gin.H{"ids": []uint{notif.ID}})
 	utils.SuccessResponse(c, http.StatusOK, "Notifikasi berhasil ditandai terbaca", gin.H{"id": notif.ID})
 }
 
@@ -159,60 +135,38 @@ func MarkNotificationsRead(c *gin.Context) {
 		return
 	}
 
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
 	if err := database.DB.Model(&models.Notification{}).
-		Where("id IN ? AND recipient = ?", body.IDs, userID).
+		Where("id IN ? AND recipient_type = ? AND recipient = ?", body.IDs, me.UserType, me.ID).
 		Update("read", true).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menandai notifikasi terbaca: "+err.Error())
 		return
 	}
 
+	terbitkanPerubahan(me, realtime.EventDibaca, gin.H{"ids": body.IDs})
 	utils.SuccessResponse(c, http.StatusOK, "Notifikasi berhasil ditandai terbaca", gin.H{"updated_count": len(body.IDs)})
 }
 
 func MarkAllRead(c *gin.Context) {
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
 	if err := database.DB.Model(&models.Notification{}).
-		Where("recipient = ? AND `read` = ?", userID, false).
+		Where("recipient_type = ? AND recipient = ? AND `read` = ?", me.UserType, me.ID, false).
 		Update("read", true).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menandai semua notifikasi terbaca: "+err.Error())
 		return
 	}
 
+	terbitkanPerubahan(me, realtime.EventDibaca, gin.H{"all": true})
 	utils.SuccessResponse(c, http.StatusOK, "Semua notifikasi berhasil ditandai terbaca", nil)
 }
 
@@ -225,23 +179,11 @@ func DeleteNotification(c *gin.Context) {
 	}
 	id := uint(id64)
 
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
 	var notif models.Notification
 	if err := database.DB.First(&notif, id).Error; err != nil {
@@ -249,7 +191,7 @@ func DeleteNotification(c *gin.Context) {
 		return
 	}
 
-	if notif.Recipient != userID {
+	if notif.RecipientType != me.UserType || notif.Recipient != me.ID {
 		utils.ErrorResponse(c, http.StatusForbidden, "Tidak memiliki izin untuk menghapus notifikasi ini")
 		return
 	}
@@ -259,6 +201,7 @@ func DeleteNotification(c *gin.Context) {
 		return
 	}
 
+	terbitkanPerubahan(me, realtime.EventDihapus, gin.H{"ids": []uint{notif.ID}})
 	utils.SuccessResponse(c, http.StatusOK, "Notifikasi berhasil dihapus", nil)
 }
 
@@ -275,25 +218,13 @@ func DeleteNotificationsBulk(c *gin.Context) {
 		return
 	}
 
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
-	res := database.DB.Where("id IN ? AND recipient = ?", body.IDs, userID).Delete(&models.Notification{})
+	res := database.DB.Where("id IN ? AND recipient_type = ? AND recipient = ?", body.IDs, me.UserType, me.ID).Delete(&models.Notification{})
 	if res.Error != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus notifikasi: "+res.Error.Error())
 		return
@@ -304,33 +235,30 @@ func DeleteNotificationsBulk(c *gin.Context) {
 		return
 	}
 
+	terbitkanPerubahan(me, realtime.EventDihapus, gin.H{"ids": body.IDs})
 	utils.SuccessResponse(c, http.StatusOK, "Notifikasi berhasil dihapus", gin.H{"deleted": res.RowsAffected})
 }
 
 func DeleteAllNotifications(c *gin.Context) {
-	userIDVal, ok := c.Get("user_id")
+	me, ok := currentPrincipal(c)
 	if !ok {
 		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
 		return
 	}
-	var userID uint
-	switch v := userIDVal.(type) {
-	case uint:
-		userID = v
-	case int:
-		userID = uint(v)
-	case int64:
-		userID = uint(v)
-	default:
-		utils.ErrorResponse(c, http.StatusInternalServerError, "format user_id tidak dikenali")
-		return
-	}
 
-	res := database.DB.Where("recipient = ?", userID).Delete(&models.Notification{})
+	res := database.DB.Where("recipient_type = ? AND recipient = ?", me.UserType, me.ID).Delete(&models.Notification{})
 	if res.Error != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus notifikasi: "+res.Error.Error())
 		return
 	}
 
+	terbitkanPerubahan(me, realtime.EventDihapus, gin.H{"all": true})
 	utils.SuccessResponse(c, http.StatusOK, "Semua notifikasi berhasil dihapus", gin.H{"deleted": res.RowsAffected})
 }
+
+// terbitkanPerubahan mengabarkan perubahan ke koneksi stream milik user,
+// disusul jumlah belum dibaca terbaru.
+func terbitkanPerubahan(me models.Principal, jenis string, data interface{}) {
+	realtime.Publish(me, jenis, data)
+	realtime.PublishUnread(me)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/realtime"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamNotifications membuka stream Server-Sent Events berisi notifikasi
+// baru, perubahan status baca dan jumlah belum dibaca milik user. Klien
+// yang menyambung ulang mengirim header Last-Event-ID (atau query
+// last_event_id) untuk menerima event yang terlewat; jika event tersebut
+// sudah tidak tersimpan dikirim event "resync". Klien browser membuka stream
+// dengan query ticket dari CreateStreamTicket.
+func StreamNotifications(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	lastStr := c.GetHeader("Last-Event-ID")
+	if lastStr == "" {
+		lastStr = c.Query("last_event_id")
+	}
+	var lastID uint64
+	punyaLast := false
+	if lastStr != "" {
+		v, err := strconv.ParseUint(lastStr, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Last-Event-ID tidak valid")
+			return
+		}
+		lastID, punyaLast = v, true
+	}
+
+	broker := realtime.Default()
+	sub, replay, lengkap := broker.Subscribe(me, lastID, punyaLast)
+	defer sub.Close()
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if _, err := w.WriteString("retry: 5000\n\n"); err != nil {
+		return
+	}
+	if punyaLast && !lengkap {
+		if err := realtime.TulisSSE(w, realtime.Event{ID: broker.Seq(), Jenis: realtime.EventResync, Data: gin.H{}}); err != nil {
+			return
+		}
+	}
+	if !punyaLast || !lengkap {
+		if err := tulisUnread(w, broker, me); err != nil {
+			return
+		}
+	}
+	for _, ev := range replay {
+		if err := realtime.TulisSSE(w, ev); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(realtime.Heartbeat())
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err := realtime.TulisSSE(w, ev); err != nil {
+				return
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := w.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+func tulisUnread(w gin.ResponseWriter, broker *realtime.Broker, me models.Principal) error {
+	n, err := realtime.UnreadCount(me)
+	if err != nil {
+		return err
+	}
+	return realtime.TulisSSE(w, realtime.Event{
+		ID:    broker.Seq(),
+		Jenis: realtime.EventUnreadCount,
+		Data:  map[string]int64{"unread": n},
+	})
+}
+
+// ttlStreamTicket cukup untuk membuka koneksi segera setelah tiket dibuat.
+const ttlStreamTicket = 30 * time.Second
+
+// CreateStreamTicket membuat tiket sekali pakai untuk membuka stream
+// notifikasi lewat EventSource tanpa menaruh access token di URL. Klien
+// meminta tiket baru setiap kali menyambung ulang.
+func CreateStreamTicket(c *gin.Context) {
+	val, _ := c.Get("session")
+	session, ok := val.(models.Session)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "sesi tidak ditemukan")
+		return
+	}
+	tiket, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat tiket stream")
+		return
+	}
+	if err := database.DB.Create(&models.StreamTicket{
+		SessionID:  session.ID,
+		TicketHash: utils.HashToken(tiket),
+		ExpiresAt:  time.Now().Add(ttlStreamTicket),
+	}).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat tiket stream")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, "tiket stream dibuat", gin.H{
+		"ticket":     tiket,
+		"expires_in": int(ttlStreamTicket.Seconds()),
+	})
+}
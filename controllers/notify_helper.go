@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"log"
+
+	"abs-be/database"
+	"abs-be/firebaseclient"
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/outbox"
+
+	"gorm.io/gorm"
+)
+
+// enqueuePerRole memasukkan notifikasi yang sama ke outbox untuk beberapa
+// kelompok penerima, memakai tx yang sama dengan perubahan datanya. ID dari
+// tabel berbeda bisa bertabrakan, jadi setiap role disimpan terpisah agar
+// channel email/SMS mengambil kontak yang benar.
+func enqueuePerRole(tx *gorm.DB, p notifikasi.Payload, perRole map[string][]uint, lampiran ...firebaseclient.Lampiran) error {
+	return outbox.EnqueuePerRole(tx, p, perRole, lampiran...)
+}
+
+// semuaAdminIDs mengambil id seluruh admin sebagai penerima notifikasi.
+func semuaAdminIDs(tx *gorm.DB) ([]uint, error) {
+	var ids []uint
+	err := tx.Table("admins").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// sinkronTopikSiswa memperbarui langganan topic FCM siswa beserta orang
+// tuanya setelah keanggotaan kelas berubah. Dipanggil setelah commit.
+func sinkronTopikSiswa(siswaIDs ...uint) {
+	if len(siswaIDs) == 0 {
+		return
+	}
+	var ortuIDs []uint
+	if err := database.DB.Table("orang_tua_siswas").
+		Where("siswa_id IN ?", siswaIDs).
+		Distinct().Pluck("orang_tua_id", &ortuIDs).Error; err != nil {
+		log.Printf("warning: gagal ambil orang tua untuk sinkron topic: %v", err)
+	}
+	ps := models.PrincipalsDariRole("siswa", siswaIDs)
+	ps = append(ps, models.PrincipalsDariRole("orang_tua", ortuIDs)...)
+	firebaseclient.SinkronTopikAsync(ps...)
+}
+
+// siswaIDsKelas mengambil siswa yang kelas utama atau relasinya kelas tsb.
+func siswaIDsKelas(tx *gorm.DB, kelasID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw("SELECT id FROM siswas WHERE kelas_id = ? UNION SELECT siswa_id FROM kelas_siswas WHERE kelas_id = ?", kelasID, kelasID).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// sinkronTopikGuru memperbarui topic role-wali_kelas guru setelah
+// penugasan wali kelas berubah.
+func sinkronTopikGuru(guruIDs ...uint) {
+	firebaseclient.SinkronTopikAsync(models.PrincipalsDariRole("guru", guruIDs)...)
+}
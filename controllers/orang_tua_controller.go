@@ -0,0 +1,418 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"abs-be/database"
+	"abs-be/firebaseclient"
+	"abs-be/jadwal"
+	"abs-be/models"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func CreateOrangTua(c *gin.Context) {
+	var req requests.CreateOrangTuaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengenkripsi password")
+		return
+	}
+
+	ortu := models.OrangTua{
+		Nama:     req.Nama,
+		Email:    req.Email,
+		Telepon:  req.Telepon,
+		Password: hashed,
+		// Password dibuat oleh admin sehingga wajib diganti saat login pertama.
+		MustChangePassword: true,
+	}
+
+	tx := database.DB.Begin()
+	if dipakai, err := emailDipakai(tx, req.Email, models.Principal{}); err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa email")
+		return
+	} else if dipakai {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusConflict, "Email sudah terdaftar")
+		return
+	}
+	if err := tx.Create(&ortu).Error; err != nil {
+		tx.Rollback()
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat akun orang tua: "+err.Error())
+		return
+	}
+	for _, sid := range req.SiswaIDs {
+		var siswa models.Siswa
+		if err := tx.First(&siswa, sid).Error; err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusNotFound, "Siswa tidak ditemukan: "+strconv.FormatUint(uint64(sid), 10))
+			return
+		}
+		if err := tx.Exec("INSERT IGNORE INTO orang_tua_siswas (orang_tua_id, siswa_id) VALUES (?, ?)", ortu.ID, sid).Error; err != nil {
+			tx.Rollback()
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghubungkan siswa: "+err.Error())
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal commit transaksi: "+err.Error())
+		return
+	}
+
+	_ = database.DB.Preload("Anak").First(&ortu, ortu.ID)
+	utils.SuccessResponse(c, http.StatusCreated, "Akun orang tua berhasil dibuat", ortu)
+}
+
+func GetAllOrangTua(c *gin.Context) {
+	var list []models.OrangTua
+	if err := database.DB.Preload("Anak").Find(&list).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data orang tua")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Daftar orang tua", list)
+}
+
+func GetOrangTuaByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var ortu models.OrangTua
+	if err := database.DB.Preload("Anak").First(&ortu, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Orang tua tidak ditemukan")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Data orang tua", ortu)
+}
+
+func UpdateOrangTua(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req requests.UpdateOrangTuaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	var ortu models.OrangTua
+	if err := database.DB.First(&ortu, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Orang tua tidak ditemukan")
+		return
+	}
+
+	gantiEmail := req.Email != ortu.Email
+	ortu.Nama = req.Nama
+	ortu.Email = req.Email
+	ortu.Telepon = req.Telepon
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if gantiEmail {
+			if err := pastikanEmailBebas(tx, ortu.Email, models.Principal{UserType: models.UserTypeOrangTua, ID: ortu.ID}); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&ortu).Error
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, "Email sudah terdaftar")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui data orang tua: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Data orang tua berhasil diperbarui", ortu)
+}
+
+func DeleteOrangTua(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	res := database.DB.Delete(&models.OrangTua{}, id)
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus orang tua")
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Orang tua tidak ditemukan")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Orang tua berhasil dihapus", nil)
+}
+
+func LinkOrangTuaSiswa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var req requests.LinkOrangTuaSiswaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	var ortu models.OrangTua
+	if err := database.DB.First(&ortu, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Orang tua tidak ditemukan")
+		return
+	}
+	var siswa models.Siswa
+	if err := database.DB.First(&siswa, req.SiswaID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Siswa tidak ditemukan")
+		return
+	}
+
+	if err := database.DB.Exec("INSERT IGNORE INTO orang_tua_siswas (orang_tua_id, siswa_id) VALUES (?, ?)", ortu.ID, siswa.ID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghubungkan siswa: "+err.Error())
+		return
+	}
+	firebaseclient.SinkronTopikAsync(models.Principal{UserType: models.UserTypeOrangTua, ID: ortu.ID})
+
+	_ = database.DB.Preload("Anak").First(&ortu, ortu.ID)
+	utils.SuccessResponse(c, http.StatusOK, "Siswa berhasil dihubungkan ke orang tua", ortu)
+}
+
+func UnlinkOrangTuaSiswa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+	siswaID, err := strconv.Atoi(c.Param("siswa_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "siswa_id tidak valid")
+		return
+	}
+
+	res := database.DB.Exec("DELETE FROM orang_tua_siswas WHERE orang_tua_id = ? AND siswa_id = ?", id, siswaID)
+	if res.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal melepas hubungan siswa: "+res.Error.Error())
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Siswa tidak terhubung dengan orang tua ini")
+		return
+	}
+	firebaseclient.SinkronTopikAsync(models.Principal{UserType: models.UserTypeOrangTua, ID: uint(id)})
+	utils.SuccessResponse(c, http.StatusOK, "Hubungan siswa dengan orang tua berhasil dihapus", nil)
+}
+
+func anakOrangTua(c *gin.Context) (models.Siswa, bool) {
+	ortuID, ok := currentUserID(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return models.Siswa{}, false
+	}
+	siswaID, err := strconv.ParseUint(c.Param("siswa_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "siswa_id tidak valid")
+		return models.Siswa{}, false
+	}
+
+	var cnt int64
+	if err := database.DB.Table("orang_tua_siswas").
+		Where("orang_tua_id = ? AND siswa_id = ?", ortuID, siswaID).
+		Count(&cnt).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa hubungan orang tua: "+err.Error())
+		return models.Siswa{}, false
+	}
+	if cnt == 0 {
+		utils.ErrorResponse(c, http.StatusForbidden, "Siswa ini bukan anak Anda")
+		return models.Siswa{}, false
+	}
+
+	var siswa models.Siswa
+	if err := database.DB.First(&siswa, siswaID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Siswa tidak ditemukan")
+		return models.Siswa{}, false
+	}
+	return siswa, true
+}
+
+func GetAnakOrangTua(c *gin.Context) {
+	ortuID, ok := currentUserID(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+
+	var ortu models.OrangTua
+	if err := database.DB.Preload("Anak").First(&ortu, ortuID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Orang tua tidak ditemukan")
+		return
+	}
+
+	type anakResponse struct {
+		requests.SiswaPublic
+		Kelas *requests.KelasPublic `json:"kelas,omitempty"`
+	}
+	result := make([]anakResponse, 0, len(ortu.Anak))
+	for _, s := range ortu.Anak {
+		item := anakResponse{SiswaPublic: requests.SiswaPublic{
+			ID:           s.ID,
+			Nama:         s.Nama,
+			NISN:         s.NISN,
+			JenisKelamin: s.JenisKelamin,
+		}}
+		if s.KelasID != nil {
+			var k models.Kelas
+			if err := database.DB.First(&k, *s.KelasID).Error; err == nil {
+				item.Kelas = &requests.KelasPublic{ID: k.ID, Nama: k.Nama, Tingkat: k.Tingkat, TahunAjaran: k.TahunAjaran}
+			}
+		}
+		result = append(result, item)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Daftar anak", result)
+}
+
+func GetProfilAnak(c *gin.Context) {
+	siswa, ok := anakOrangTua(c)
+	if !ok {
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Profil anak", toSiswaResponse(siswa))
+}
+
+// rentangTanggal membaca query dari/sampai, default ke semester berjalan.
+func rentangTanggal(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now()
+	dari, sampai, err := jadwal.RentangSemester(jadwal.TahunAjaran(now), jadwal.Semester(now), time.Local)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return dari, sampai, false
+	}
+	if v := c.Query("dari"); v != "" {
+		if dari, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format dari harus YYYY-MM-DD")
+			return dari, sampai, false
+		}
+	}
+	if v := c.Query("sampai"); v != "" {
+		if sampai, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format sampai harus YYYY-MM-DD")
+			return dari, sampai, false
+		}
+	}
+	return dari, sampai, true
+}
+
+func GetAbsensiAnak(c *gin.Context) {
+	siswa, ok := anakOrangTua(c)
+	if !ok {
+		return
+	}
+	dari, sampai, ok := rentangTanggal(c)
+	if !ok {
+		return
+	}
+
+	q := database.DB.
+		Preload("Kelas").
+		Preload("MataPelajaran").
+		Preload("Guru").
+		Where("siswa_id = ? AND tanggal BETWEEN ? AND ?", siswa.ID, dari.Format("2006-01-02"), sampai.Format("2006-01-02"))
+
+	if tipe := c.Query("tipe"); tipe != "" {
+		if tipe != "kelas" && tipe != "mapel" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "tipe harus 'kelas' atau 'mapel'")
+			return
+		}
+		q = q.Where("tipe_absensi = ?", tipe)
+	}
+	if status := c.Query("status"); status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	var absensi []models.AbsensiSiswa
+	if err := q.Order("tanggal DESC, id DESC").Find(&absensi).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data absensi: "+err.Error())
+		return
+	}
+	for i := range absensi {
+		absensi[i].Siswa = siswa
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Riwayat absensi anak", toAbsensiResponses(absensi))
+}
+
+func GetRekapAbsensiAnak(c *gin.Context) {
+	siswa, ok := anakOrangTua(c)
+	if !ok {
+		return
+	}
+	dari, sampai, ok := rentangTanggal(c)
+	if !ok {
+		return
+	}
+
+	rekap, err := rekapAbsensiSiswa(database.DB, siswa.ID, dari, sampai)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung rekap absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Rekap absensi anak", rekap)
+}
+
+func rekapAbsensiSiswa(db *gorm.DB, siswaID uint, dari, sampai time.Time) (requests.RekapAbsensiResponse, error) {
+	var rows []struct {
+		TipeAbsensi string
+		Status      string
+		Jumlah      int64
+	}
+	rekap := requests.RekapAbsensiResponse{
+		SiswaID:   siswaID,
+		Dari:      dari.Format("2006-01-02"),
+		Sampai:    sampai.Format("2006-01-02"),
+		PerStatus: map[string]int64{},
+		PerTipe:   map[string]map[string]int64{},
+	}
+	if err := db.Table("absensi_siswas").
+		Select("tipe_absensi, status, COUNT(*) AS jumlah").
+		Where("siswa_id = ? AND tanggal BETWEEN ? AND ?", siswaID, rekap.Dari, rekap.Sampai).
+		Group("tipe_absensi, status").
+		Scan(&rows).Error; err != nil {
+		return rekap, err
+	}
+
+	var hadir int64
+	for _, r := range rows {
+		rekap.Total += r.Jumlah
+		rekap.PerStatus[r.Status] += r.Jumlah
+		if rekap.PerTipe[r.TipeAbsensi] == nil {
+			rekap.PerTipe[r.TipeAbsensi] = map[string]int64{}
+		}
+		rekap.PerTipe[r.TipeAbsensi][r.Status] += r.Jumlah
+		if r.Status == "masuk" || r.Status == "terlambat" {
+			hadir += r.Jumlah
+		}
+	}
+	if rekap.Total > 0 {
+		rekap.Kehadiran = float64(hadir) * 100 / float64(rekap.Total)
+	}
+	return rekap, nil
+}
@@ -0,0 +1,419 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"abs-be/database"
+	"abs-be/firebaseclient"
+	"abs-be/models"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	panjangKodeReset  = 6
+	maksPercobaanKode = 5
+	jedaMintaKode     = time.Minute
+	jendelaKodeReset  = 24 * time.Hour
+)
+
+var errKodeResetTidakValid = errors.New("kode reset tidak valid atau sudah kadaluarsa")
+
+// ttlKodeReset diatur lewat PASSWORD_RESET_TTL_MENIT (default 15 menit).
+func ttlKodeReset() time.Duration {
+	return time.Duration(envAngka("PASSWORD_RESET_TTL_MENIT", 15)) * time.Minute
+}
+
+// batasHarianKodeReset membatasi per akun dalam 24 jam terakhir, lintas kode:
+// PASSWORD_RESET_MAKS_KODE_HARIAN (default 5) kode yang diminta dan
+// PASSWORD_RESET_MAKS_GAGAL_HARIAN (default 10) percobaan kode yang salah.
+// Tanpa batas ini jeda per kode masih memberi ribuan tebakan per hari.
+func batasHarianKodeReset() (maksKode, maksGagal int) {
+	return envAngka("PASSWORD_RESET_MAKS_KODE_HARIAN", 5), envAngka("PASSWORD_RESET_MAKS_GAGAL_HARIAN", 10)
+}
+
+// pemakaianKodeReset menghitung kode yang diminta dan total percobaan salah
+// milik akun dalam jendelaKodeReset terakhir.
+func pemakaianKodeReset(tx *gorm.DB, p models.Principal, now time.Time) (kode, gagal int64, err error) {
+	var hasil struct {
+		Kode  int64
+		Gagal int64
+	}
+	err = tx.Model(&models.PasswordReset{}).
+		Select("COUNT(*) AS kode, COALESCE(SUM(percobaan), 0) AS gagal").
+		Where("user_type = ? AND user_id = ? AND created_at > ?", p.UserType, p.ID, now.Add(-jendelaKodeReset)).
+		Scan(&hasil).Error
+	return hasil.Kode, hasil.Gagal, err
+}
+
+// channelKodeReset adalah channel pengiriman kode reset, dipisah koma lewat
+// PASSWORD_RESET_CHANNEL (default email), mis. "email,whatsapp".
+func channelKodeReset() []string {
+	v := os.Getenv("PASSWORD_RESET_CHANNEL")
+	if v == "" {
+		v = "email"
+	}
+	var hasil []string
+	for _, ch := range strings.Split(v, ",") {
+		if ch = strings.TrimSpace(ch); ch != "" {
+			hasil = append(hasil, ch)
+		}
+	}
+	return hasil
+}
+
+type akunPassword struct {
+	Password           string
+	MustChangePassword bool
+	DisabledAt         *time.Time
+}
+
+func ambilAkunPassword(tx *gorm.DB, p models.Principal) (akunPassword, error) {
+	var akun akunPassword
+	tabel := models.TabelAkun(p.UserType)
+	if tabel == "" {
+		return akun, fmt.Errorf("jenis user %q tidak dikenal", p.UserType)
+	}
+	res := tx.Table(tabel).Select("password, must_change_password, disabled_at").Where("id = ?", p.ID).Scan(&akun)
+	if res.Error != nil {
+		return akun, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return akun, gorm.ErrRecordNotFound
+	}
+	return akun, nil
+}
+
+func simpanPassword(tx *gorm.DB, p models.Principal, plain string, mustChange bool) error {
+	hashed, err := utils.HashPassword(plain)
+	if err != nil {
+		return err
+	}
+	return tx.Table(models.TabelAkun(p.UserType)).Where("id = ?", p.ID).Updates(map[string]interface{}{
+		"password":             hashed,
+		"must_change_password": mustChange,
+		"updated_at":           time.Now(),
+	}).Error
+}
+
+// cabutSesiUser mencabut semua sesi milik user kecuali sesi dengan id
+// kecuali (0 berarti semua).
+func cabutSesiUser(tx *gorm.DB, p models.Principal, kecuali uint) error {
+	var ids []uint
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND role IN ? AND id <> ?", p.ID, models.RoleSesi(p.UserType), kecuali).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := cabutSesi(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cariAkunEmail mencari satu-satunya akun dengan email tsb, di jenis
+// userType saja bila diisi. Email yang cocok dengan lebih dari satu akun
+// dianggap tidak ditemukan, sehingga respons tetap sama dengan email yang
+// tidak terdaftar dan kode reset tidak terkirim ke akun yang salah.
+func cariAkunEmail(tx *gorm.DB, email, userType string) (models.Principal, bool) {
+	jenis := []string{models.UserTypeAdmin, models.UserTypeGuru, models.UserTypeSiswa, models.UserTypeOrangTua}
+	if userType != "" {
+		jenis = []string{userType}
+	}
+	var hasil []models.Principal
+	for _, ut := range jenis {
+		var ids []uint
+		if err := tx.Table(models.TabelAkun(ut)).Where("email = ?", email).Limit(2).Pluck("id", &ids).Error; err != nil {
+			return models.Principal{}, false
+		}
+		for _, id := range ids {
+			hasil = append(hasil, models.Principal{UserType: ut, ID: id})
+		}
+	}
+	if len(hasil) != 1 {
+		return models.Principal{}, false
+	}
+	return hasil[0], true
+}
+
+// emailDipakai melaporkan apakah email sudah dipakai akun lain di tabel akun
+// mana pun. Email yang sama di dua tabel membuat login dan reset password
+// lewat email menjadi ambigu. Panggil di dalam transaksi yang menyimpan
+// akunnya: baris yang dicocokkan dikunci agar dua pendaftaran bersamaan
+// tidak sama-sama lolos.
+func emailDipakai(tx *gorm.DB, email string, kecuali models.Principal) (bool, error) {
+	if email == "" {
+		return false, nil
+	}
+	for _, ut := range []string{models.UserTypeAdmin, models.UserTypeGuru, models.UserTypeSiswa, models.UserTypeOrangTua} {
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Table(models.TabelAkun(ut)).Where("email = ?", email)
+		if ut == kecuali.UserType {
+			q = q.Where("id <> ?", kecuali.ID)
+		}
+		var n int64
+		if err := q.Count(&n).Error; err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var errEmailTerdaftar = errors.New("email sudah terdaftar")
+
+// pastikanEmailBebas mengembalikan errEmailTerdaftar bila email dipakai akun
+// lain; lihat emailDipakai.
+func pastikanEmailBebas(tx *gorm.DB, email string, kecuali models.Principal) error {
+	dipakai, err := emailDipakai(tx, email, kecuali)
+	if err != nil {
+		return err
+	}
+	if dipakai {
+		return errEmailTerdaftar
+	}
+	return nil
+}
+
+// ChangePassword mengganti password user yang sedang login. Sesi lain milik
+// user dicabut; sesi ini tetap berlaku dan tidak lagi wajib ganti password.
+func ChangePassword(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	val, _ := c.Get("session")
+	session, _ := val.(models.Session)
+
+	var req requests.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	if req.PasswordBaru != req.KonfirmasiPassword {
+		utils.ErrorResponse(c, http.StatusBadRequest, "password dan konfirmasi tidak cocok")
+		return
+	}
+	if req.PasswordBaru == req.PasswordLama {
+		utils.ErrorResponse(c, http.StatusBadRequest, "password baru harus berbeda dari password lama")
+		return
+	}
+
+	akun, err := ambilAkunPassword(database.DB, me)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "akun tidak ditemukan")
+		return
+	}
+	if !utils.CheckPasswordHash(req.PasswordLama, akun.Password) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "password lama salah")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := simpanPassword(tx, me, req.PasswordBaru, false); err != nil {
+			return err
+		}
+		if err := cabutSesiUser(tx, me, session.ID); err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).Where("id = ?", session.ID).
+			Update("must_change_password", false).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengganti password")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "password berhasil diganti", nil)
+}
+
+// AdminResetPassword membuat password sementara untuk user. Semua sesinya
+// dicabut dan user wajib mengganti password saat login berikutnya.
+func AdminResetPassword(c *gin.Context) {
+	p, ok := principalParam(c)
+	if !ok || !cekBolehKelolaAkun(c, p) {
+		return
+	}
+	sementara, err := utils.GenerateRandomToken(6)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat password sementara")
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := simpanPassword(tx, p, sementara, true); err != nil {
+			return err
+		}
+		return cabutSesiUser(tx, p, 0)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mereset password")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "password berhasil direset", gin.H{
+		"user_type":          p.UserType,
+		"user_id":            p.ID,
+		"password_sementara": sementara,
+	})
+}
+
+// ForgotPassword mengirim kode reset ke kontak user. Respons selalu sama
+// agar tidak bisa dipakai untuk menebak email yang terdaftar.
+func ForgotPassword(c *gin.Context) {
+	var req requests.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	const pesan = "jika email terdaftar, kode reset password telah dikirim"
+
+	p, ok := cariAkunEmail(database.DB, req.Email, req.UserType)
+	if !ok {
+		utils.SuccessResponse(c, http.StatusOK, pesan, nil)
+		return
+	}
+
+	now := time.Now()
+	var baru int64
+	database.DB.Model(&models.PasswordReset{}).
+		Where("user_type = ? AND user_id = ? AND used_at IS NULL AND created_at > ?", p.UserType, p.ID, now.Add(-jedaMintaKode)).
+		Count(&baru)
+	if baru > 0 {
+		utils.SuccessResponse(c, http.StatusOK, pesan, nil)
+		return
+	}
+	maksKode, maksGagal := batasHarianKodeReset()
+	jumlahKode, jumlahGagal, err := pemakaianKodeReset(database.DB, p, now)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat kode reset")
+		return
+	}
+	if jumlahKode >= int64(maksKode) || jumlahGagal >= int64(maksGagal) {
+		// Respons tetap sama agar batas tidak membocorkan akun yang terdaftar.
+		utils.SuccessResponse(c, http.StatusOK, pesan, nil)
+		return
+	}
+
+	kode, err := utils.GenerateNumericCode(panjangKodeReset)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat kode reset")
+		return
+	}
+	ttl := ttlKodeReset()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Hanya kode terbaru yang berlaku.
+		if err := tx.Model(&models.PasswordReset{}).
+			Where("user_type = ? AND user_id = ? AND used_at IS NULL", p.UserType, p.ID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordReset{
+			UserType:  p.UserType,
+			UserID:    p.ID,
+			CodeHash:  utils.HashToken(kode),
+			ExpiresAt: now.Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal menyimpan kode reset")
+		return
+	}
+
+	msg := firebaseclient.Pesan{
+		Type:  "reset_password",
+		Title: "Kode reset password",
+		Body: fmt.Sprintf("Kode reset password Anda: %s. Berlaku %d menit. Abaikan pesan ini jika Anda tidak memintanya.",
+			kode, int(ttl.Minutes())),
+	}
+	go func() {
+		for _, ch := range channelKodeReset() {
+			if err := firebaseclient.KirimLangsung(context.Background(), ch, msg, p); err != nil {
+				log.Printf("password: gagal kirim kode reset lewat %s ke %s/%d: %v", ch, p.UserType, p.ID, err)
+			}
+		}
+	}()
+	utils.SuccessResponse(c, http.StatusOK, pesan, nil)
+}
+
+// ResetPassword memakai kode reset untuk menetapkan password baru. Kode
+// hanya berlaku sekali dan gugur setelah terlalu banyak percobaan salah,
+// baik per kode maupun total per akun dalam 24 jam.
+func ResetPassword(c *gin.Context) {
+	var req requests.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	if req.PasswordBaru != req.KonfirmasiPassword {
+		utils.ErrorResponse(c, http.StatusBadRequest, "password dan konfirmasi tidak cocok")
+		return
+	}
+
+	p, ok := cariAkunEmail(database.DB, req.Email, req.UserType)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, errKodeResetTidakValid.Error())
+		return
+	}
+
+	now := time.Now()
+	_, maksGagal := batasHarianKodeReset()
+	_, jumlahGagal, err := pemakaianKodeReset(database.DB, p, now)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mereset password")
+		return
+	}
+	if jumlahGagal >= int64(maksGagal) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errKodeResetTidakValid.Error())
+		return
+	}
+	var reset models.PasswordReset
+	if err := database.DB.Where("user_type = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", p.UserType, p.ID, now).
+		Order("id DESC").First(&reset).Error; err != nil || reset.Percobaan >= maksPercobaanKode {
+		utils.ErrorResponse(c, http.StatusBadRequest, errKodeResetTidakValid.Error())
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(req.Kode)), []byte(reset.CodeHash)) != 1 {
+		database.DB.Model(&reset).Update("percobaan", gorm.Expr("percobaan + 1"))
+		utils.ErrorResponse(c, http.StatusBadRequest, errKodeResetTidakValid.Error())
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// used_at IS NULL memastikan kode tidak dipakai dua kali oleh
+		// request yang bersamaan.
+		res := tx.Model(&models.PasswordReset{}).Where("id = ? AND used_at IS NULL", reset.ID).Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errKodeResetTidakValid
+		}
+		if err := simpanPassword(tx, p, req.PasswordBaru, false); err != nil {
+			return err
+		}
+		return cabutSesiUser(tx, p, 0)
+	})
+	if errors.Is(err, errKodeResetTidakValid) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mereset password")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "password berhasil direset, silakan login kembali", nil)
+}
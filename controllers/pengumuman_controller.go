@@ -0,0 +1,501 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"abs-be/akses"
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/pengumuman"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// namaPrincipal mengambil nama akun untuk ditampilkan sebagai penulis.
+func namaPrincipal(db *gorm.DB, p models.Principal) string {
+	tabel := map[string]string{
+		models.UserTypeAdmin:    "admins",
+		models.UserTypeGuru:     "gurus",
+		models.UserTypeSiswa:    "siswas",
+		models.UserTypeOrangTua: "orang_tuas",
+	}[p.UserType]
+	if tabel == "" {
+		return ""
+	}
+	var nama []string
+	db.Table(tabel).Where("id = ?", p.ID).Limit(1).Pluck("nama", &nama)
+	if len(nama) == 0 {
+		return ""
+	}
+	return nama[0]
+}
+
+// cekTargetWaliKelas memastikan wali kelas hanya menargetkan kelas yang
+// diwalikannya.
+func cekTargetWaliKelas(guruID uint, targets []models.PengumumanTarget) error {
+	var kelasIDs []uint
+	if err := database.DB.Model(&models.Kelas{}).Where("wali_kelas_id = ?", guruID).Pluck("id", &kelasIDs).Error; err != nil {
+		return err
+	}
+	milik := map[string]bool{}
+	for _, id := range kelasIDs {
+		milik[strconv.FormatUint(uint64(id), 10)] = true
+	}
+	for _, t := range targets {
+		if t.Jenis != pengumuman.JenisKelas || !milik[t.Nilai] {
+			return fmt.Errorf("wali kelas hanya dapat mengirim pengumuman ke kelas yang diwalikannya")
+		}
+	}
+	return nil
+}
+
+func CreatePengumuman(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	var req requests.CreatePengumumanRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Data tidak valid: "+err.Error())
+		return
+	}
+
+	isi := pengumuman.BersihkanHTML(req.Isi)
+	if pengumuman.TeksPolos(isi, 0) == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Isi pengumuman tidak boleh kosong")
+		return
+	}
+
+	var targetReq []requests.PengumumanTargetRequest
+	if err := json.Unmarshal([]byte(req.Target), &targetReq); err != nil || len(targetReq) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Target harus berupa JSON array yang tidak kosong")
+		return
+	}
+	targets := make([]models.PengumumanTarget, 0, len(targetReq))
+	for _, t := range targetReq {
+		target := models.PengumumanTarget{
+			Jenis: strings.TrimSpace(t.Jenis),
+			Nilai: strings.TrimSpace(t.Nilai),
+			Peran: strings.TrimSpace(t.Peran),
+		}
+		if err := pengumuman.ValidasiTarget(target); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Target tidak valid: "+err.Error())
+			return
+		}
+		targets = append(targets, target)
+	}
+	// Tanpa izin pengumuman.create.all, penulis hanya boleh menuju kelas
+	// yang diwalinya, apa pun role aktifnya.
+	semua, err := punyaIzin(c, akses.PengumumanAll)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa izin akses")
+		return
+	}
+	role := "admin"
+	if !semua {
+		role = "wali_kelas"
+		if err := cekTargetWaliKelas(me.ID, targets); err != nil {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+	}
+
+	now := time.Now()
+	var terbitPada *time.Time
+	if req.TerbitPada != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04", req.TerbitPada, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Format terbit_pada salah (gunakan YYYY-MM-DD HH:MM)")
+			return
+		}
+		if !t.After(now) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "terbit_pada harus di masa depan; kosongkan untuk terbit sekarang")
+			return
+		}
+		terbitPada = &t
+	}
+
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil && form != nil {
+		files = form.File["lampiran"]
+	}
+	if len(files) > pengumuman.MaksLampiran() {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Maksimal %d lampiran per pengumuman", pengumuman.MaksLampiran()))
+		return
+	}
+	lampiran := make([]models.PengumumanLampiran, 0, len(files))
+	for _, fh := range files {
+		l, err := pengumuman.SimpanLampiran(fh)
+		if err != nil {
+			pengumuman.HapusLampiran(lampiran)
+			utils.ErrorResponse(c, http.StatusBadRequest, "Gagal menyimpan lampiran: "+err.Error())
+			return
+		}
+		lampiran = append(lampiran, l)
+	}
+
+	p := models.Pengumuman{
+		Judul:      strings.TrimSpace(req.Judul),
+		Isi:        isi,
+		AuthorType: me.UserType,
+		AuthorID:   me.ID,
+		AuthorRole: role,
+		AuthorNama: namaPrincipal(database.DB, me),
+		Status:     "terjadwal",
+		TerbitPada: terbitPada,
+		Target:     targets,
+		Lampiran:   lampiran,
+	}
+	if terbitPada == nil {
+		p.Status = "terbit"
+		p.DiterbitkanPada = &now
+	}
+
+	jumlah := 0
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&p).Error; err != nil {
+			return err
+		}
+		if p.Status != "terbit" {
+			return nil
+		}
+		var err error
+		jumlah, err = pengumuman.Sebarkan(tx, &p, now)
+		return err
+	})
+	if err != nil {
+		pengumuman.HapusLampiran(lampiran)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan pengumuman: "+err.Error())
+		return
+	}
+
+	msg := "Pengumuman berhasil diterbitkan"
+	if p.Status == "terjadwal" {
+		msg = "Pengumuman berhasil dijadwalkan"
+	}
+	utils.SuccessResponse(c, http.StatusCreated, msg, gin.H{
+		"pengumuman":     p,
+		"total_penerima": jumlah,
+	})
+}
+
+// GetPengumuman menampilkan pengumuman yang diterima user saat ini.
+// Query: dibaca=true|false, page, per_page.
+func GetPengumuman(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	page, perPage := halaman(c)
+
+	q := database.DB.Table("pengumuman_penerimas AS pp").
+		Joins("JOIN pengumumans p ON p.id = pp.pengumuman_id").
+		Where("pp.user_type = ? AND pp.user_id = ? AND p.status = ?", me.UserType, me.ID, "terbit")
+	switch c.Query("dibaca") {
+	case "true":
+		q = q.Where("pp.dibaca_pada IS NOT NULL")
+	case "false":
+		q = q.Where("pp.dibaca_pada IS NULL")
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengumuman: "+err.Error())
+		return
+	}
+
+	var rows []struct {
+		models.Pengumuman
+		DibacaPada *time.Time
+	}
+	if err := q.Select("p.*, pp.dibaca_pada").
+		Order("p.diterbitkan_pada DESC, p.id DESC").
+		Limit(perPage).Offset((page - 1) * perPage).
+		Scan(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengumuman: "+err.Error())
+		return
+	}
+
+	var belumDibaca int64
+	if err := database.DB.Table("pengumuman_penerimas AS pp").
+		Joins("JOIN pengumumans p ON p.id = pp.pengumuman_id").
+		Where("pp.user_type = ? AND pp.user_id = ? AND p.status = ? AND pp.dibaca_pada IS NULL", me.UserType, me.ID, "terbit").
+		Count(&belumDibaca).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung pengumuman belum dibaca: "+err.Error())
+		return
+	}
+
+	items := make([]requests.PengumumanItemResponse, 0, len(rows))
+	for _, r := range rows {
+		items = append(items, requests.PengumumanItemResponse{
+			ID:              r.ID,
+			Judul:           r.Judul,
+			Ringkasan:       pengumuman.TeksPolos(r.Isi, 160),
+			AuthorNama:      r.AuthorNama,
+			AuthorRole:      r.AuthorRole,
+			DiterbitkanPada: r.DiterbitkanPada,
+			Dibaca:          r.DibacaPada != nil,
+			DibacaPada:      r.DibacaPada,
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Daftar pengumuman", gin.H{
+		"items":        items,
+		"total":        total,
+		"belum_dibaca": belumDibaca,
+		"page":         page,
+		"per_page":     perPage,
+	})
+}
+
+// aksesPengumuman memuat pengumuman yang boleh dilihat user: admin,
+// penulisnya, atau penerima setelah terbit. Baris penerima ikut
+// dikembalikan bila user termasuk penerima.
+func aksesPengumuman(c *gin.Context) (models.Pengumuman, *models.PengumumanPenerima, bool) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return models.Pengumuman{}, nil, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return models.Pengumuman{}, nil, false
+	}
+
+	var p models.Pengumuman
+	if err := database.DB.Preload("Lampiran").First(&p, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			utils.ErrorResponse(c, http.StatusNotFound, "Pengumuman tidak ditemukan")
+			return p, nil, false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengumuman: "+err.Error())
+		return p, nil, false
+	}
+
+	var penerima []models.PengumumanPenerima
+	if err := database.DB.Where("pengumuman_id = ? AND user_type = ? AND user_id = ?", p.ID, me.UserType, me.ID).
+		Limit(1).Find(&penerima).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data penerima: "+err.Error())
+		return p, nil, false
+	}
+	if len(penerima) > 0 && p.Status == "terbit" {
+		return p, &penerima[0], true
+	}
+	if me.UserType == models.UserTypeAdmin || (p.AuthorType == me.UserType && p.AuthorID == me.ID) {
+		return p, nil, true
+	}
+	utils.ErrorResponse(c, http.StatusNotFound, "Pengumuman tidak ditemukan")
+	return p, nil, false
+}
+
+// bolehKelolaPengumuman: admin atau penulis pengumuman.
+func bolehKelolaPengumuman(c *gin.Context, p models.Pengumuman) bool {
+	me, _ := currentPrincipal(c)
+	return me.UserType == models.UserTypeAdmin || (p.AuthorType == me.UserType && p.AuthorID == me.ID)
+}
+
+func GetPengumumanByID(c *gin.Context) {
+	p, penerima, ok := aksesPengumuman(c)
+	if !ok {
+		return
+	}
+	data := gin.H{"pengumuman": p}
+	if bolehKelolaPengumuman(c, p) {
+		var targets []models.PengumumanTarget
+		if err := database.DB.Where("pengumuman_id = ?", p.ID).Find(&targets).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil target: "+err.Error())
+			return
+		}
+		p.Target = targets
+		data["pengumuman"] = p
+	}
+	if penerima != nil {
+		data["dibaca_pada"] = penerima.DibacaPada
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Detail pengumuman", data)
+}
+
+func MarkPengumumanDibaca(c *gin.Context) {
+	p, penerima, ok := aksesPengumuman(c)
+	if !ok {
+		return
+	}
+	if penerima == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Anda bukan penerima pengumuman ini")
+		return
+	}
+	if penerima.DibacaPada == nil {
+		now := time.Now()
+		if err := database.DB.Model(&models.PengumumanPenerima{}).
+			Where("pengumuman_id = ? AND user_type = ? AND user_id = ? AND dibaca_pada IS NULL", p.ID, penerima.UserType, penerima.UserID).
+			Update("dibaca_pada", now).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menandai pengumuman: "+err.Error())
+			return
+		}
+		penerima.DibacaPada = &now
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Pengumuman ditandai sudah dibaca", gin.H{
+		"id":          p.ID,
+		"dibaca_pada": penerima.DibacaPada,
+	})
+}
+
+func DownloadLampiranPengumuman(c *gin.Context) {
+	p, _, ok := aksesPengumuman(c)
+	if !ok {
+		return
+	}
+	lampiranID, err := strconv.Atoi(c.Param("lampiranID"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID lampiran tidak valid")
+		return
+	}
+	for _, l := range p.Lampiran {
+		if l.ID == uint(lampiranID) {
+			c.Header("Content-Type", l.ContentType)
+			c.FileAttachment(pengumuman.PathLampiran(l), l.Nama)
+			return
+		}
+	}
+	utils.ErrorResponse(c, http.StatusNotFound, "Lampiran tidak ditemukan")
+}
+
+// GetPengumumanDibuat menampilkan pengumuman yang dibuat user beserta
+// jumlah penerima dan pembacanya. Admin melihat semua pengumuman.
+// Query: status=terjadwal|terbit, page, per_page.
+func GetPengumumanDibuat(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	page, perPage := halaman(c)
+
+	q := database.DB.Model(&models.Pengumuman{})
+	if me.UserType != models.UserTypeAdmin {
+		q = q.Where("author_type = ? AND author_id = ?", me.UserType, me.ID)
+	}
+	if status := c.Query("status"); status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengumuman: "+err.Error())
+		return
+	}
+
+	var rows []requests.PengumumanDibuatResponse
+	if err := q.Select(`pengumumans.id, pengumumans.judul, pengumumans.author_nama, pengumumans.author_role,
+			pengumumans.status, pengumumans.terbit_pada, pengumumans.diterbitkan_pada, pengumumans.created_at,
+			(SELECT COUNT(*) FROM pengumuman_penerimas pp WHERE pp.pengumuman_id = pengumumans.id) AS total_penerima,
+			(SELECT COUNT(*) FROM pengumuman_penerimas pp WHERE pp.pengumuman_id = pengumumans.id AND pp.dibaca_pada IS NOT NULL) AS total_dibaca`).
+		Order("pengumumans.id DESC").
+		Limit(perPage).Offset((page - 1) * perPage).
+		Scan(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengumuman: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Daftar pengumuman yang dibuat", gin.H{
+		"items":    rows,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// GetPembacaPengumuman menampilkan siapa yang sudah dan belum membaca.
+// Query: status=sudah|belum.
+func GetPembacaPengumuman(c *gin.Context) {
+	p, _, ok := aksesPengumuman(c)
+	if !ok {
+		return
+	}
+	if !bolehKelolaPengumuman(c, p) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Hanya penulis atau admin yang dapat melihat pembaca")
+		return
+	}
+
+	q := database.DB.Table("pengumuman_penerimas AS pp").
+		Select("pp.user_type, pp.user_id, pp.dibaca_pada, COALESCE(a.nama, g.nama, s.nama, o.nama, '') AS nama").
+		Joins("LEFT JOIN admins a ON pp.user_type = 'admin' AND a.id = pp.user_id").
+		Joins("LEFT JOIN gurus g ON pp.user_type = 'guru' AND g.id = pp.user_id").
+		Joins("LEFT JOIN siswas s ON pp.user_type = 'siswa' AND s.id = pp.user_id").
+		Joins("LEFT JOIN orang_tuas o ON pp.user_type = 'orang_tua' AND o.id = pp.user_id").
+		Where("pp.pengumuman_id = ?", p.ID)
+	switch c.Query("status") {
+	case "sudah":
+		q = q.Where("pp.dibaca_pada IS NOT NULL")
+	case "belum":
+		q = q.Where("pp.dibaca_pada IS NULL")
+	}
+
+	var rows []requests.PembacaPengumumanResponse
+	if err := q.Order("pp.user_type, nama").Scan(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pembaca: "+err.Error())
+		return
+	}
+
+	var total, dibaca int64
+	database.DB.Model(&models.PengumumanPenerima{}).Where("pengumuman_id = ?", p.ID).Count(&total)
+	database.DB.Model(&models.PengumumanPenerima{}).Where("pengumuman_id = ? AND dibaca_pada IS NOT NULL", p.ID).Count(&dibaca)
+
+	utils.SuccessResponse(c, http.StatusOK, "Pembaca pengumuman", gin.H{
+		"total_penerima": total,
+		"total_dibaca":   dibaca,
+		"total_belum":    total - dibaca,
+		"items":          rows,
+	})
+}
+
+func DeletePengumuman(c *gin.Context) {
+	p, _, ok := aksesPengumuman(c)
+	if !ok {
+		return
+	}
+	if !bolehKelolaPengumuman(c, p) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Hanya penulis atau admin yang dapat menghapus pengumuman")
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&models.PengumumanPenerima{}, &models.PengumumanTarget{}, &models.PengumumanLampiran{}} {
+			if err := tx.Where("pengumuman_id = ?", p.ID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Pengumuman{}, p.ID).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus pengumuman: "+err.Error())
+		return
+	}
+	pengumuman.HapusLampiran(p.Lampiran)
+
+	utils.SuccessResponse(c, http.StatusOK, "Pengumuman berhasil dihapus", gin.H{"id": p.ID})
+}
+
+func halaman(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if err != nil || perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+	return page, perPage
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// metaSesi adalah metadata perangkat yang dicatat di sesi.
+type metaSesi struct {
+	IP        string
+	UserAgent string
+	Device    string
+}
+
+// bacaMetaSesi membaca metadata dari request. Aplikasi bisa mengirim nama
+// perangkat lewat header X-Device-Name; bila kosong, diturunkan dari
+// user agent.
+func bacaMetaSesi(c *gin.Context) metaSesi {
+	ua := potong(c.GetHeader("User-Agent"), 255)
+	device := potong(strings.TrimSpace(c.GetHeader("X-Device-Name")), 100)
+	if device == "" {
+		device = perangkatDariUA(ua)
+	}
+	return metaSesi{IP: c.ClientIP(), UserAgent: ua, Device: device}
+}
+
+func potong(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// perangkatDariUA menebak jenis perangkat dari user agent secara kasar.
+func perangkatDariUA(ua string) string {
+	l := strings.ToLower(ua)
+	switch {
+	case l == "":
+		return ""
+	case strings.Contains(l, "okhttp"), strings.Contains(l, "dart"):
+		return "Aplikasi mobile"
+	case strings.Contains(l, "android"):
+		return "Android"
+	case strings.Contains(l, "iphone"), strings.Contains(l, "ipad"):
+		return "iOS"
+	case strings.Contains(l, "windows"):
+		return "Windows"
+	case strings.Contains(l, "mac os"):
+		return "macOS"
+	case strings.Contains(l, "linux"):
+		return "Linux"
+	}
+	return "Lainnya"
+}
+
+// sesiDenganStatus menandai sesi yang sedang dipakai request ini.
+type sesiDenganStatus struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+func sesiSaatIni(c *gin.Context) (models.Session, bool) {
+	val, _ := c.Get("session")
+	session, ok := val.(models.Session)
+	return session, ok
+}
+
+// GetMySessions menampilkan semua sesi aktif milik user yang sedang login.
+func GetMySessions(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	current, _ := sesiSaatIni(c)
+
+	var rows []models.Session
+	if err := database.DB.
+		Where("user_id = ? AND role IN ?", me.ID, models.RoleSesi(me.UserType)).
+		Order("COALESCE(last_seen_at, created_at) DESC").
+		Find(&rows).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil daftar sesi")
+		return
+	}
+	hasil := make([]sesiDenganStatus, 0, len(rows))
+	for _, s := range rows {
+		hasil = append(hasil, sesiDenganStatus{Session: s, Current: s.ID == current.ID})
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Daftar sesi", hasil)
+}
+
+// DeleteMySession mengakhiri satu sesi milik user, termasuk sesi saat ini.
+func DeleteMySession(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "ID tidak valid")
+		return
+	}
+
+	var session models.Session
+	if err := database.DB.
+		Where("id = ? AND user_id = ? AND role IN ?", id, me.ID, models.RoleSesi(me.UserType)).
+		First(&session).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sesi tidak ditemukan")
+		return
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return cabutSesi(tx, session.ID)
+	}); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengakhiri sesi")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Sesi diakhiri", nil)
+}
+
+// DeleteOtherSessions mengakhiri semua sesi user selain sesi saat ini.
+func DeleteOtherSessions(c *gin.Context) {
+	me, ok := currentPrincipal(c)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "akses ditolak: user tidak ditemukan di context")
+		return
+	}
+	current, _ := sesiSaatIni(c)
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return cabutSesiUser(tx, me, current.ID)
+	}); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengakhiri sesi lain")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Sesi lain diakhiri", nil)
+}
+
+// AdminRevokeSessions mengakhiri semua sesi sebuah akun, mis. bila akunnya
+// diduga disalahgunakan.
+func AdminRevokeSessions(c *gin.Context) {
+	p, ok := principalParam(c)
+	if !ok {
+		return
+	}
+	var jumlah int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND role IN ?", p.ID, models.RoleSesi(p.UserType)).
+			Count(&jumlah).Error; err != nil {
+			return err
+		}
+		return cabutSesiUser(tx, p, 0)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mencabut sesi")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Semua sesi akun dicabut", gin.H{
+		"user_type": p.UserType,
+		"user_id":   p.ID,
+		"dicabut":   jumlah,
+	})
+}
+
+// kadaluarsaToken mengembalikan exp access token, atau nil bila tidak
+// terbaca.
+func kadaluarsaToken(access string) *time.Time {
+	exp, err := utils.ExpToken(access)
+	if err != nil {
+		return nil
+	}
+	return &exp
+}
@@ -3,17 +3,17 @@ package controllers
 import (
 	"abs-be/database"
 	"abs-be/models"
+	"abs-be/notifikasi"
 	"abs-be/requests"
 	"abs-be/utils"
-	"abs-be/firebaseclient"
 	"net/http"
 	"strconv"
 	"time"
-	"context"
-	"fmt"
 	"log"
+	"errors"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func CreateSiswa(c *gin.Context) {
@@ -57,77 +57,57 @@ func CreateSiswa(c *gin.Context) {
 		Telepon:      req.Telepon,
 		AsalSekolah:  req.AsalSekolah,
 		Password:     hashedPassword,
+		// Password awal adalah tanggal lahir sehingga wajib diganti saat
+		// login pertama.
+		MustChangePassword: true,
 	}
 
 	// if req.KelasID != 0 { newSiswa.KelasID = req.KelasID }
 
-	if err := database.DB.Create(&newSiswa).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat siswa: "+err.Error())
-		return
-	}
-
-	utils.SuccessResponse(c, http.StatusCreated, "Siswa berhasil dibuat", gin.H{
-		"siswa": newSiswa,
-	})
-
-	go func(siswa models.Siswa) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("panic di notification goroutine CreateSiswa: %v", r)
-			}
-		}()
-
-		var recipientIDs []uint
-
-		var adminIDs []uint
-		if rows, err := database.DB.Raw("SELECT id FROM admins").Rows(); err == nil {
-			defer rows.Close()
-			for rows.Next() {
-				var id uint
-				_ = rows.Scan(&id)
-				adminIDs = append(adminIDs, id)
-			}
-		} else {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := pastikanEmailBebas(tx, newSiswa.Email, models.Principal{}); err != nil {
+			return err
+		}
+		if err := tx.Create(&newSiswa).Error; err != nil {
+			return err
+		}
+		adminIDs, err := semuaAdminIDs(tx)
+		if err != nil {
 			log.Printf("CreateSiswa: gagal ambil admin ids: %v", err)
 		}
-		recipientIDs = append(recipientIDs, adminIDs...)
 
-		if siswa.KelasID != nil && *siswa.KelasID != 0 {
+		var waliIDs []uint
+		if newSiswa.KelasID != nil && *newSiswa.KelasID != 0 {
 			var kelas models.Kelas
-			if err := database.DB.Preload("WaliKelas").First(&kelas, *siswa.KelasID).Error; err == nil {
+			if err := tx.First(&kelas, *newSiswa.KelasID).Error; err == nil {
 				if kelas.WaliKelasID != nil && *kelas.WaliKelasID != 0 {
-					recipientIDs = append(recipientIDs, *kelas.WaliKelasID)
+					waliIDs = append(waliIDs, *kelas.WaliKelasID)
 				}
 			}
 		}
 
-		recipientIDs = append(recipientIDs, siswa.ID)
-
-		uniq := map[uint]struct{}{}
-		finalRecipients := make([]uint, 0, len(recipientIDs))
-		for _, id := range recipientIDs {
-			if id == 0 {
-				continue
-			}
-			if _, ok := uniq[id]; !ok {
-				uniq[id] = struct{}{}
-				finalRecipients = append(finalRecipients, id)
-			}
-		}
-
-		title := "Akun Siswa Baru Dibuat"
-		body := fmt.Sprintf("Akun siswa %s (%s) berhasil dibuat.", siswa.Nama, siswa.NISN)
-		payload := map[string]interface{}{
-			"type":     "create_siswa",
-			"siswa_id": fmt.Sprintf("%d", siswa.ID),
-			"nama":     siswa.Nama,
-			"nisn":     siswa.NISN,
-		}
+		return enqueuePerRole(tx, notifikasi.CreateSiswa{
+			SiswaID: newSiswa.ID,
+			Nama:    newSiswa.Nama,
+			NISN:    newSiswa.NISN,
+		}, map[string][]uint{
+			"admin": adminIDs,
+			"guru":  waliIDs,
+			"siswa": {newSiswa.ID},
+		})
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, "Email sudah terdaftar")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat siswa: "+err.Error())
+		return
+	}
 
-		if err := firebaseclient.SendNotify(context.Background(), "create_siswa", title, body, payload, finalRecipients); err != nil {
-			log.Printf("CreateSiswa: NotifyUsers failed: %v", err)
-		}
-	}(newSiswa)
+	utils.SuccessResponse(c, http.StatusCreated, "Siswa berhasil dibuat", gin.H{
+		"siswa": newSiswa,
+	})
 }
 
 func GetSiswaByKelas(c *gin.Context) {
@@ -218,6 +198,7 @@ func UpdateSiswa(c *gin.Context) {
 		return
 	}
 
+	gantiEmail := req.Email != siswa.Email
 	siswa.Nama = req.Nama
 	siswa.NISN = req.NISN
 	siswa.TempatLahir = req.TempatLahir
@@ -231,7 +212,19 @@ func UpdateSiswa(c *gin.Context) {
 	siswa.Telepon = req.Telepon
 	siswa.AsalSekolah = req.AsalSekolah
 
-	if err := database.DB.Save(&siswa).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if gantiEmail {
+			if err := pastikanEmailBebas(tx, siswa.Email, models.Principal{UserType: models.UserTypeSiswa, ID: siswa.ID}); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&siswa).Error
+	})
+	if errors.Is(err, errEmailTerdaftar) {
+		utils.ErrorResponse(c, http.StatusConflict, "Email sudah terdaftar")
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui data siswa")
 		return
 	}
@@ -264,7 +257,11 @@ func GetProfilSiswa(c *gin.Context) {
 		return
 	}
 
-	response := requests.SiswaResponse{
+	utils.SuccessResponse(c, http.StatusOK, "Profil siswa", toSiswaResponse(siswa))
+}
+
+func toSiswaResponse(siswa models.Siswa) requests.SiswaResponse {
+	return requests.SiswaResponse{
 		ID:           siswa.ID,
 		Nama:         siswa.Nama,
 		NISN:         siswa.NISN,
@@ -279,8 +276,6 @@ func GetProfilSiswa(c *gin.Context) {
 		Telepon:      siswa.Telepon,
 		AsalSekolah:  siswa.AsalSekolah,
 	}
-
-	utils.SuccessResponse(c, http.StatusOK, "Profil siswa", response)
 }
 
 func GetAbsensiSiswa(c *gin.Context) {
@@ -321,6 +316,10 @@ func GetAbsensiSiswa(c *gin.Context) {
 		return
 	}
 
+	utils.SuccessResponse(c, http.StatusOK, "Data absensi", toAbsensiResponses(absensi))
+}
+
+func toAbsensiResponses(absensi []models.AbsensiSiswa) []requests.AbsensiResponse {
 	var resp []requests.AbsensiResponse
 	for _, a := range absensi {
 
@@ -384,6 +383,5 @@ func GetAbsensiSiswa(c *gin.Context) {
 		}
 		resp = append(resp, entry)
 	}
-
-	utils.SuccessResponse(c, http.StatusOK, "Data absensi", resp)
+	return resp
 }
@@ -2,13 +2,11 @@ package controllers
 
 import (
 	"abs-be/database"
-	"abs-be/firebaseclient"
 	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/outbox"
 	"abs-be/requests"
 	"abs-be/utils"
-	"context"
-	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,6 +15,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// Catatan todo adalah milik pribadi per role: role aktif memilih daftar
+// mana (admin_id/guru_id/wali_kelas_id) yang dibaca atau diubah, dan setiap
+// query selalu dibatasi ke user_id pemanggil. Role di sini bukan otorisasi,
+// jadi sengaja tidak memakai izin RBAC.
 func CreateTodo(c *gin.Context) {
 	var req requests.CreateTodoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -80,38 +82,25 @@ func CreateTodo(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Create(&todo).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&todo).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, todo.Role, notifikasi.CreateTodo{
+			TodoID:    todo.ID,
+			Role:      todo.Role,
+			Tanggal:   todo.Tanggal.Format("2006-01-02"),
+			Deskripsi: todo.Deskripsi,
+			Jam:       todo.JamDibuat,
+			ActorID:   userID,
+		}, []uint{userID})
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat catatan: "+err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusCreated, "Catatan berhasil dibuat", todo)
-
-	go func(t models.Todo, actorID uint) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("panic di notification goroutine CreateTodo: %v", r)
-			}
-		}()
-
-		recipientIDs := []uint{actorID}
-
-		title := "To-Do Baru Dibuat"
-		body := fmt.Sprintf("To-Do: %s (tanggal %s)", t.Deskripsi, t.Tanggal.Format("2006-01-02"))
-		payload := map[string]interface{}{
-			"type":      "create_todo",
-			"todo_id":   fmt.Sprintf("%d", t.ID),
-			"role":      t.Role,
-			"tanggal":   t.Tanggal.Format("2006-01-02"),
-			"deskripsi": t.Deskripsi,
-			"jam":       t.JamDibuat,
-			"actor_id":  fmt.Sprintf("%d", actorID),
-		}
-
-		if err := firebaseclient.SendNotify(context.Background(), "create_todo", title, body, payload, recipientIDs); err != nil {
-			log.Printf("CreateTodo: SendNotify failed: %v", err)
-		}
-	}(todo, userID)
 }
 
 func GetTodosByTanggal(c *gin.Context) {
@@ -303,48 +292,35 @@ func DeleteTodo(c *gin.Context) {
 		return
 	}
 
-	var delErr error
+	var kolom string
 	switch role {
 	case "admin":
-		delErr = db.Where("id = ? AND admin_id = ?", id, userID).Delete(&models.Todo{}).Error
+		kolom = "admin_id"
 	case "guru":
-		delErr = db.Where("id = ? AND guru_id = ?", id, userID).Delete(&models.Todo{}).Error
+		kolom = "guru_id"
 	case "wali_kelas":
-		delErr = db.Where("id = ? AND wali_kelas_id = ?", id, userID).Delete(&models.Todo{}).Error
+		kolom = "wali_kelas_id"
 	default:
 		utils.ErrorResponse(c, http.StatusBadRequest, "Role tidak valid")
 		return
 	}
 
+	delErr := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND "+kolom+" = ?", id, userID).Delete(&models.Todo{}).Error; err != nil {
+			return err
+		}
+		return outbox.Enqueue(tx, todo.Role, notifikasi.DeleteTodo{
+			TodoID:    todo.ID,
+			Role:      todo.Role,
+			Tanggal:   todo.Tanggal.Format("2006-01-02"),
+			Deskripsi: todo.Deskripsi,
+			ActorID:   userID,
+		}, []uint{userID})
+	})
 	if delErr != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus catatan: "+delErr.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Catatan berhasil dihapus", nil)
-
-	go func(t models.Todo, actorID uint) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("panic di notification goroutine DeleteTodo: %v", r)
-			}
-		}()
-
-		recipientIDs := []uint{actorID}
-
-		title := "To-Do Dihapus"
-		body := fmt.Sprintf("To-Do: %s (tanggal %s) telah dihapus.", t.Deskripsi, t.Tanggal.Format("2006-01-02"))
-		payload := map[string]interface{}{
-			"type":      "delete_todo",
-			"todo_id":   fmt.Sprintf("%d", t.ID),
-			"role":      t.Role,
-			"deskripsi": t.Deskripsi,
-			"tanggal":   t.Tanggal.Format("2006-01-02"),
-			"actor_id":  fmt.Sprintf("%d", actorID),
-		}
-
-		if err := firebaseclient.SendNotify(context.Background(), "delete_todo", title, body, payload, recipientIDs); err != nil {
-			log.Printf("DeleteTodo: SendNotify failed: %v", err)
-		}
-	}(todo, userID)
 }
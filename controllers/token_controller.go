@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/requests"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errRefreshTidakValid = errors.New("refresh token tidak valid")
+	errRefreshKadaluarsa = errors.New("refresh token sudah kadaluarsa")
+	errRefreshDipakai    = errors.New("refresh token sudah pernah dipakai, semua sesi terkait dicabut")
+	errAkunNonaktif      = errors.New(pesanAkunNonaktif)
+)
+
+// buatSesi membuat sesi login baru beserta access token dan refresh token
+// pertamanya, lalu menambahkan keduanya ke data respons login.
+func buatSesi(c *gin.Context, userID uint, role string, data gin.H) (gin.H, error) {
+	meta := bacaMetaSesi(c)
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		p := models.NewPrincipal(role, userID)
+		akun, err := ambilAkunPassword(tx, p)
+		if err != nil {
+			return err
+		}
+		roles, err := rolesAkun(tx, p)
+		if err != nil {
+			return err
+		}
+		access, err := utils.GenerateToken(userID, role, roles...)
+		if err != nil {
+			return err
+		}
+		session := models.Session{
+			UserID:             userID,
+			Token:              access,
+			Role:               role,
+			Roles:              strings.Join(roles, ","),
+			MustChangePassword: akun.MustChangePassword,
+			IP:                 meta.IP,
+			UserAgent:          meta.UserAgent,
+			Device:             meta.Device,
+			LastSeenAt:         &now,
+			ExpiresAt:          kadaluarsaToken(access),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		refresh, err := terbitkanRefresh(tx, session)
+		if err != nil {
+			return err
+		}
+		for k, v := range responsToken(access, refresh) {
+			data[k] = v
+		}
+		data["must_change_password"] = akun.MustChangePassword
+		data["roles"] = roles
+		return nil
+	})
+	return data, err
+}
+
+// rolesAkun menghitung semua role yang dimiliki akun. Setiap guru bisa
+// mengajar mapel; guru yang ditugaskan sebagai wali kelas juga mendapat
+// role wali_kelas.
+func rolesAkun(tx *gorm.DB, p models.Principal) ([]string, error) {
+	if p.UserType != models.UserTypeGuru {
+		return []string{p.UserType}, nil
+	}
+	roles := []string{"guru"}
+	var wali int64
+	if err := tx.Model(&models.GuruRole{}).
+		Where("guru_id = ? AND role = 'wali_kelas'", p.ID).
+		Count(&wali).Error; err != nil {
+		return nil, err
+	}
+	if wali > 0 {
+		roles = append(roles, "wali_kelas")
+	}
+	return roles, nil
+}
+
+func terbitkanRefresh(tx *gorm.DB, session models.Session) (string, error) {
+	refresh, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	rt := models.RefreshToken{
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		Role:      session.Role,
+		TokenHash: utils.HashToken(refresh),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := tx.Create(&rt).Error; err != nil {
+		return "", err
+	}
+	return refresh, nil
+}
+
+func responsToken(access, refresh string) gin.H {
+	return gin.H{
+		"token":         access,
+		"refresh_token": refresh,
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+	}
+}
+
+// cabutSesi mengakhiri sesi: access token tidak lagi diterima dan semua
+// refresh token keluarganya dicabut.
+func cabutSesi(tx *gorm.DB, sessionID uint) error {
+	now := time.Now()
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Where("id = ?", sessionID).Delete(&models.Session{}).Error
+}
+
+// RefreshToken menukar refresh token dengan access token dan refresh token
+// baru. Token lama hanya berlaku sekali; bila dipakai lagi, seluruh
+// keluarganya dicabut karena token tsb kemungkinan sudah bocor.
+func RefreshToken(c *gin.Context) {
+	var req requests.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+
+	var resp gin.H
+	var dipakaiUlang *models.RefreshToken
+	var sesiNonaktif uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var rt models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(req.RefreshToken)).
+			First(&rt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTidakValid
+			}
+			return err
+		}
+		now := time.Now()
+		if rt.UsedAt != nil || rt.RevokedAt != nil {
+			dipakaiUlang = &rt
+			return errRefreshDipakai
+		}
+		if now.After(rt.ExpiresAt) {
+			return errRefreshKadaluarsa
+		}
+
+		var session models.Session
+		if err := tx.Where("id = ?", rt.SessionID).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTidakValid
+			}
+			return err
+		}
+		p := models.NewPrincipal(session.Role, session.UserID)
+		akun, err := ambilAkunPassword(tx, p)
+		if err != nil {
+			return err
+		}
+		if akun.DisabledAt != nil {
+			sesiNonaktif = session.ID
+			return errAkunNonaktif
+		}
+		if err := tx.Model(&rt).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		// Role dihitung ulang agar perubahan penugasan (mis. wali kelas)
+		// berlaku paling lambat pada refresh berikutnya.
+		roles, err := rolesAkun(tx, p)
+		if err != nil {
+			return err
+		}
+		session.Roles = strings.Join(roles, ",")
+		if !session.PunyaRole(session.Role) {
+			session.Role = roles[0]
+		}
+		access, err := utils.GenerateToken(session.UserID, session.Role, roles...)
+		if err != nil {
+			return err
+		}
+		meta := bacaMetaSesi(c)
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"token":        access,
+			"role":         session.Role,
+			"roles":        session.Roles,
+			"ip":           meta.IP,
+			"user_agent":   meta.UserAgent,
+			"last_seen_at": now,
+			"expires_at":   kadaluarsaToken(access),
+		}).Error; err != nil {
+			return err
+		}
+		refresh, err := terbitkanRefresh(tx, session)
+		if err != nil {
+			return err
+		}
+		resp = responsToken(access, refresh)
+		resp["role"] = session.Role
+		resp["roles"] = roles
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		utils.SuccessResponse(c, http.StatusOK, "token diperbarui", resp)
+	case errors.Is(err, errRefreshDipakai):
+		// Pencabutan dilakukan di luar transaksi rotasi yang sudah dibatalkan.
+		if cerr := cabutSesi(database.DB, dipakaiUlang.SessionID); cerr != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mencabut sesi")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, errAkunNonaktif):
+		if cerr := cabutSesi(database.DB, sesiNonaktif); cerr != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mencabut sesi")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, errRefreshTidakValid), errors.Is(err, errRefreshKadaluarsa):
+		utils.ErrorResponse(c, http.StatusUnauthorized, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memperbarui token")
+	}
+}
+
+// SwitchActiveRole mengganti role aktif sesi ke role lain yang dimiliki
+// akun. Access token lama tidak berlaku lagi; refresh token tetap sama.
+func SwitchActiveRole(c *gin.Context) {
+	var req requests.ActiveRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "data tidak valid: "+err.Error())
+		return
+	}
+	val, _ := c.Get("session")
+	session, ok := val.(models.Session)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "sesi tidak ditemukan")
+		return
+	}
+	if !session.PunyaRole(req.Role) {
+		utils.ErrorResponse(c, http.StatusForbidden, "role '"+req.Role+"' tidak dimiliki akun ini")
+		return
+	}
+
+	roles := session.DaftarRole()
+	access, err := utils.GenerateToken(session.UserID, req.Role, roles...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal membuat token")
+		return
+	}
+	if err := database.DB.Model(&session).Updates(map[string]interface{}{
+		"token":      access,
+		"role":       req.Role,
+		"expires_at": kadaluarsaToken(access),
+	}).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "gagal mengganti role aktif")
+		return
+	}
+	utils.SuccessResponse(c, http.StatusOK, "role aktif diganti", gin.H{
+		"token":      access,
+		"expires_in": int(utils.AccessTokenTTL().Seconds()),
+		"role":       req.Role,
+		"roles":      roles,
+	})
+}
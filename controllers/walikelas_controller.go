@@ -80,12 +80,17 @@ func UpdateWaliKelas(c *gin.Context) {
 		return
 	}
 
+	var prevWaliIDs []uint
+	if kelas.WaliKelasID != nil {
+		prevWaliIDs = append(prevWaliIDs, *kelas.WaliKelasID)
+	}
 	kelas.WaliKelasID = &req.GuruID
 
 	if err := database.DB.Save(&kelas).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memperbarui wali kelas")
 		return
 	}
+	sinkronTopikGuru(append(prevWaliIDs, req.GuruID)...)
 
 	utils.SuccessResponse(c, http.StatusOK, "Wali kelas berhasil diperbarui", kelas)
 }
@@ -104,27 +109,25 @@ func RemoveWaliKelas(c *gin.Context) {
 		return
 	}
 
+	var prevWaliIDs []uint
+	if kelas.WaliKelasID != nil {
+		prevWaliIDs = append(prevWaliIDs, *kelas.WaliKelasID)
+	}
 	kelas.WaliKelasID = nil
 
 	if err := database.DB.Save(&kelas).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus wali kelas dari kelas")
 		return
 	}
+	sinkronTopikGuru(prevWaliIDs...)
 
 	utils.SuccessResponse(c, http.StatusOK, "Wali kelas berhasil dihapus dari kelas", kelas)
 }
 
 func GetKelasWali(c *gin.Context) {
-	roleVal, ok := c.Get("role")
-	if !ok {
-		utils.ErrorResponse(c, http.StatusUnauthorized, "role tidak ditemukan di context")
-		return
-	}
-	role, _ := roleVal.(string)
-
 	db := database.DB
 
-	if role == "wali_kelas" {
+	if punyaRole(c, "wali_kelas") {
 		uidVal, ok := c.Get("user_id")
 		if !ok {
 			utils.ErrorResponse(c, http.StatusUnauthorized, "user_id tidak ditemukan di context")
@@ -155,7 +158,7 @@ func GetKelasWali(c *gin.Context) {
 		return
 	}
 
-	if role == "admin" {
+	if punyaRole(c, "admin") {
 		q := c.Query("kelas_id")
 		if q == "" {
 
@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE hari_liburs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    tanggal_mulai DATE NOT NULL,
+    tanggal_selesai DATE NOT NULL,
+    keterangan VARCHAR(255) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_hari_libur_mulai (tanggal_mulai),
+    INDEX idx_hari_libur_selesai (tanggal_selesai)
+);
+
+CREATE TABLE pembatalan_jadwals (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    mapel_id INT NOT NULL,
+    kelas_id INT NOT NULL,
+    tanggal DATE NOT NULL,
+    alasan VARCHAR(255),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_pembatalan (mapel_id, kelas_id, tanggal),
+    FOREIGN KEY (mapel_id) REFERENCES mata_pelajarans(id) ON DELETE CASCADE,
+    FOREIGN KEY (kelas_id) REFERENCES kelas(id) ON DELETE CASCADE
+);
+
+CREATE TABLE calendar_feed_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    role ENUM('guru','siswa') NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    revoked_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_calendar_token (token_hash),
+    INDEX idx_calendar_feed_user (user_id, role)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS calendar_feed_tokens;
+DROP TABLE IF EXISTS pembatalan_jadwals;
+DROP TABLE IF EXISTS hari_liburs;
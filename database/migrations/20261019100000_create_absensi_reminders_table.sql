@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE absensi_reminders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    tipe_absensi ENUM('kelas','mapel') NOT NULL,
+    kelas_id INT NOT NULL,
+    mapel_id INT NOT NULL DEFAULT 0, -- 0 untuk absensi kelas
+    tanggal DATE NOT NULL,
+    level ENUM('pengingat','eskalasi') NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_absensi_reminder (tipe_absensi, kelas_id, mapel_id, tanggal, level)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS absensi_reminders;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE orang_tuas (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    nama VARCHAR(100) NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    telepon VARCHAR(20),
+    password VARCHAR(255) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE orang_tua_siswas (
+    orang_tua_id INT NOT NULL,
+    siswa_id INT NOT NULL,
+    PRIMARY KEY (orang_tua_id, siswa_id),
+    FOREIGN KEY (orang_tua_id) REFERENCES orang_tuas(id) ON DELETE CASCADE,
+    FOREIGN KEY (siswa_id) REFERENCES siswas(id) ON DELETE CASCADE,
+    INDEX idx_orang_tua_siswas_siswa (siswa_id)
+);
+
+ALTER TABLE sessions MODIFY role ENUM('guru','admin','wali_kelas','siswa','orang_tua') NOT NULL;
+
+-- +goose Down
+ALTER TABLE sessions MODIFY role ENUM('guru','admin','wali_kelas','siswa') NOT NULL;
+DROP TABLE IF EXISTS orang_tua_siswas;
+DROP TABLE IF EXISTS orang_tuas;
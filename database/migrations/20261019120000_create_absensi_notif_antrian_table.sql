@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE absensi_notif_antrians (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    absensi_id INT NOT NULL,
+    siswa_id INT NOT NULL,
+    tanggal DATE NOT NULL,
+    kelas_id INT NOT NULL,
+    mapel_id INT NULL,
+    tipe_absensi ENUM('kelas','mapel') NOT NULL,
+    status ENUM('alpa','terlambat') NOT NULL,
+    sent_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_antrian_absensi (absensi_id),
+    KEY idx_antrian_siswa_tanggal (siswa_id, tanggal),
+    KEY idx_antrian_sent_at (sent_at),
+    FOREIGN KEY (absensi_id) REFERENCES absensi_siswas(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS absensi_notif_antrians;
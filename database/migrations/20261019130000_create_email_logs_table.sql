@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE email_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    user_id INT NOT NULL,
+    role VARCHAR(20),
+    email VARCHAR(100) NOT NULL,
+    subject VARCHAR(255),
+    lampiran INT NOT NULL DEFAULT 0,
+    status ENUM('terkirim','gagal') NOT NULL,
+    error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_email_logs_type (type),
+    INDEX idx_email_logs_user_id (user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_logs;
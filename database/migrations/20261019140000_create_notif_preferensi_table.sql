@@ -0,0 +1,49 @@
+-- +goose Up
+CREATE TABLE notif_preferensis (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    aktif BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_notif_pref (user_id, type, channel)
+);
+
+CREATE TABLE notif_jam_tenangs (
+    user_id INT PRIMARY KEY,
+    mulai CHAR(5) NOT NULL,
+    selesai CHAR(5) NOT NULL,
+    aktif BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE notif_tipe_wajibs (
+    type VARCHAR(100) PRIMARY KEY,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO notif_tipe_wajibs (type) VALUES
+    ('eskalasi_absensi_mapel'),
+    ('eskalasi_absensi_kelas');
+
+CREATE TABLE notif_push_tertundas (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    title VARCHAR(255),
+    body TEXT,
+    payload TEXT,
+    kirim_setelah DATETIME NOT NULL,
+    sent_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_push_tertunda_user (user_id),
+    INDEX idx_push_tertunda_kirim (kirim_setelah)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notif_push_tertundas;
+DROP TABLE IF EXISTS notif_tipe_wajibs;
+DROP TABLE IF EXISTS notif_jam_tenangs;
+DROP TABLE IF EXISTS notif_preferensis;
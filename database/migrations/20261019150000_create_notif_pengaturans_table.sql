@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE notif_pengaturans (
+    user_id INT PRIMARY KEY,
+    bahasa ENUM('id','en') NOT NULL DEFAULT 'id',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notif_pengaturans;
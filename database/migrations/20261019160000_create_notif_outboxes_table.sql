@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE notif_outboxes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    role VARCHAR(20),
+    payload TEXT NOT NULL,
+    recipients TEXT NOT NULL,
+    lampiran LONGTEXT,
+    selesai TEXT,
+    status ENUM('pending','proses','terkirim','dead') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    locked_at DATETIME NULL,
+    last_error TEXT,
+    sent_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_outbox_type (type),
+    INDEX idx_outbox_status_next (status, next_attempt_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notif_outboxes;
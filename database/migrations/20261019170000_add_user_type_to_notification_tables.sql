@@ -0,0 +1,114 @@
+-- +goose Up
+-- ID admin, guru, siswa dan orang tua berasal dari tabel berbeda sehingga
+-- bisa sama. Semua tabel yang menyimpan penerima/pemilik notifikasi kini
+-- memakai pasangan (user_type, user_id).
+
+ALTER TABLE notifications ADD COLUMN recipient_type VARCHAR(20) NULL AFTER recipient;
+ALTER TABLE device_tokens ADD COLUMN user_type VARCHAR(20) NULL AFTER user_id;
+ALTER TABLE email_logs ADD COLUMN user_type VARCHAR(20) NULL AFTER user_id;
+ALTER TABLE notif_preferensis ADD COLUMN user_type VARCHAR(20) NULL FIRST;
+ALTER TABLE notif_jam_tenangs ADD COLUMN user_type VARCHAR(20) NULL FIRST;
+ALTER TABLE notif_pengaturans ADD COLUMN user_type VARCHAR(20) NULL FIRST;
+ALTER TABLE notif_push_tertundas ADD COLUMN user_type VARCHAR(20) NULL AFTER id;
+
+-- id yang hanya ada di satu tabel akun bisa diatribusikan dengan pasti.
+CREATE TABLE tmp_principal_tunggal AS
+SELECT id, MIN(user_type) AS user_type
+FROM (
+    SELECT id, 'admin' AS user_type FROM admins
+    UNION ALL SELECT id, 'guru' FROM gurus
+    UNION ALL SELECT id, 'siswa' FROM siswas
+    UNION ALL SELECT id, 'orang_tua' FROM orang_tuas
+) u
+GROUP BY id
+HAVING COUNT(*) = 1;
+
+-- id yang sesinya hanya pernah dari satu jenis user (token device dan
+-- preferensi hanya bisa didaftarkan lewat sesi login).
+CREATE TABLE tmp_principal_sesi AS
+SELECT user_id AS id, MIN(IF(role = 'wali_kelas', 'guru', role)) AS user_type
+FROM sessions
+GROUP BY user_id
+HAVING COUNT(DISTINCT IF(role = 'wali_kelas', 'guru', role)) = 1;
+
+-- notifications: sejak routing channel, payload menyimpan penerima_role.
+UPDATE notifications
+SET recipient_type = IF(JSON_UNQUOTE(JSON_EXTRACT(payload, '$.penerima_role')) = 'wali_kelas', 'guru',
+                        JSON_UNQUOTE(JSON_EXTRACT(payload, '$.penerima_role')))
+WHERE JSON_VALID(payload)
+  AND JSON_UNQUOTE(JSON_EXTRACT(payload, '$.penerima_role')) IN ('admin', 'guru', 'wali_kelas', 'siswa', 'orang_tua');
+UPDATE notifications n JOIN tmp_principal_tunggal t ON t.id = n.recipient
+SET n.recipient_type = t.user_type
+WHERE n.recipient_type IS NULL;
+
+UPDATE email_logs
+SET user_type = IF(role = 'wali_kelas', 'guru', role)
+WHERE role IN ('admin', 'guru', 'wali_kelas', 'siswa', 'orang_tua');
+UPDATE email_logs e JOIN tmp_principal_tunggal t ON t.id = e.user_id
+SET e.user_type = t.user_type
+WHERE e.user_type IS NULL;
+
+UPDATE device_tokens d JOIN tmp_principal_tunggal t ON t.id = d.user_id SET d.user_type = t.user_type;
+UPDATE device_tokens d JOIN tmp_principal_sesi s ON s.id = d.user_id SET d.user_type = s.user_type WHERE d.user_type IS NULL;
+UPDATE notif_preferensis p JOIN tmp_principal_tunggal t ON t.id = p.user_id SET p.user_type = t.user_type;
+UPDATE notif_preferensis p JOIN tmp_principal_sesi s ON s.id = p.user_id SET p.user_type = s.user_type WHERE p.user_type IS NULL;
+UPDATE notif_jam_tenangs j JOIN tmp_principal_tunggal t ON t.id = j.user_id SET j.user_type = t.user_type;
+UPDATE notif_jam_tenangs j JOIN tmp_principal_sesi s ON s.id = j.user_id SET j.user_type = s.user_type WHERE j.user_type IS NULL;
+UPDATE notif_pengaturans g JOIN tmp_principal_tunggal t ON t.id = g.user_id SET g.user_type = t.user_type;
+UPDATE notif_pengaturans g JOIN tmp_principal_sesi s ON s.id = g.user_id SET g.user_type = s.user_type WHERE g.user_type IS NULL;
+UPDATE notif_push_tertundas p JOIN tmp_principal_tunggal t ON t.id = p.user_id SET p.user_type = t.user_type;
+UPDATE notif_push_tertundas p JOIN tmp_principal_sesi s ON s.id = p.user_id SET p.user_type = s.user_type WHERE p.user_type IS NULL;
+
+-- Baris yang tetap ambigu tidak boleh dikirim/ditampilkan ke akun yang
+-- salah. Token dan preferensi akan didaftarkan ulang oleh aplikasi saat
+-- login; notifications lama tanpa recipient_type tidak lagi tampil.
+DELETE FROM device_tokens WHERE user_type IS NULL;
+DELETE FROM notif_preferensis WHERE user_type IS NULL;
+DELETE FROM notif_jam_tenangs WHERE user_type IS NULL;
+DELETE FROM notif_pengaturans WHERE user_type IS NULL;
+DELETE FROM notif_push_tertundas WHERE user_type IS NULL;
+
+DROP TABLE tmp_principal_tunggal;
+DROP TABLE tmp_principal_sesi;
+
+ALTER TABLE notifications ADD INDEX idx_notifications_principal (recipient_type, recipient);
+ALTER TABLE device_tokens
+    MODIFY user_type VARCHAR(20) NOT NULL,
+    ADD INDEX idx_device_tokens_principal (user_type, user_id);
+ALTER TABLE notif_preferensis
+    MODIFY user_type VARCHAR(20) NOT NULL,
+    DROP INDEX uniq_notif_pref,
+    ADD UNIQUE KEY uniq_notif_pref (user_type, user_id, type, channel);
+ALTER TABLE notif_jam_tenangs
+    MODIFY user_type VARCHAR(20) NOT NULL,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (user_type, user_id);
+ALTER TABLE notif_pengaturans
+    MODIFY user_type VARCHAR(20) NOT NULL,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (user_type, user_id);
+ALTER TABLE notif_push_tertundas
+    MODIFY user_type VARCHAR(20) NOT NULL,
+    DROP INDEX idx_push_tertunda_user,
+    ADD INDEX idx_push_tertunda_user (user_type, user_id);
+
+-- +goose Down
+ALTER TABLE notif_push_tertundas
+    DROP INDEX idx_push_tertunda_user,
+    ADD INDEX idx_push_tertunda_user (user_id),
+    DROP COLUMN user_type;
+DELETE g1 FROM notif_pengaturans g1 JOIN notif_pengaturans g2
+    ON g1.user_id = g2.user_id AND g1.user_type > g2.user_type;
+ALTER TABLE notif_pengaturans DROP PRIMARY KEY, DROP COLUMN user_type, ADD PRIMARY KEY (user_id);
+DELETE j1 FROM notif_jam_tenangs j1 JOIN notif_jam_tenangs j2
+    ON j1.user_id = j2.user_id AND j1.user_type > j2.user_type;
+ALTER TABLE notif_jam_tenangs DROP PRIMARY KEY, DROP COLUMN user_type, ADD PRIMARY KEY (user_id);
+DELETE p1 FROM notif_preferensis p1 JOIN notif_preferensis p2
+    ON p1.user_id = p2.user_id AND p1.type = p2.type AND p1.channel = p2.channel AND p1.user_type > p2.user_type;
+ALTER TABLE notif_preferensis
+    DROP INDEX uniq_notif_pref,
+    DROP COLUMN user_type,
+    ADD UNIQUE KEY uniq_notif_pref (user_id, type, channel);
+ALTER TABLE email_logs DROP COLUMN user_type;
+ALTER TABLE device_tokens DROP INDEX idx_device_tokens_principal, DROP COLUMN user_type;
+ALTER TABLE notifications DROP INDEX idx_notifications_principal, DROP COLUMN recipient_type;
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE notif_pengaturans
+    ADD COLUMN digest_frekuensi ENUM('harian','per_jam') NOT NULL DEFAULT 'harian' AFTER bahasa,
+    ADD COLUMN digest_jam CHAR(5) NOT NULL DEFAULT '07:00' AFTER digest_frekuensi;
+
+CREATE TABLE notif_digest_types (
+    user_type VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_type, user_id, type)
+);
+
+CREATE TABLE notif_digest_antrians (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_type VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    title VARCHAR(255),
+    body TEXT,
+    digest_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_digest_antrian_user (user_type, user_id, digest_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notif_digest_antrians;
+DROP TABLE IF EXISTS notif_digest_types;
+ALTER TABLE notif_pengaturans DROP COLUMN digest_jam, DROP COLUMN digest_frekuensi;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Topic FCM yang sedang dilanggan setiap device token, dipakai untuk
+-- menghitung selisih saat kelas/role pemilik token berubah.
+CREATE TABLE device_token_topiks (
+    token VARCHAR(512) NOT NULL,
+    topic VARCHAR(200) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (token, topic),
+    INDEX idx_device_token_topiks_topic (topic)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS device_token_topiks;
@@ -0,0 +1,56 @@
+-- +goose Up
+CREATE TABLE pengumumans (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    judul VARCHAR(200) NOT NULL,
+    isi MEDIUMTEXT NOT NULL,
+    author_type VARCHAR(20) NOT NULL,
+    author_id INT NOT NULL,
+    author_role VARCHAR(20) NOT NULL,
+    author_nama VARCHAR(100),
+    status ENUM('terjadwal','terbit') NOT NULL DEFAULT 'terbit',
+    terbit_pada DATETIME NULL,
+    diterbitkan_pada DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_pengumuman_author (author_type, author_id),
+    INDEX idx_pengumuman_status (status, terbit_pada)
+);
+
+CREATE TABLE pengumuman_targets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    pengumuman_id INT NOT NULL,
+    jenis ENUM('role','kelas','tingkat','user') NOT NULL,
+    nilai VARCHAR(50) NOT NULL,
+    peran VARCHAR(20),
+    INDEX idx_pengumuman_targets_pengumuman (pengumuman_id),
+    FOREIGN KEY (pengumuman_id) REFERENCES pengumumans(id) ON DELETE CASCADE
+);
+
+CREATE TABLE pengumuman_lampirans (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    pengumuman_id INT NOT NULL,
+    nama VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100),
+    ukuran BIGINT,
+    path VARCHAR(255) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_pengumuman_lampirans_pengumuman (pengumuman_id),
+    FOREIGN KEY (pengumuman_id) REFERENCES pengumumans(id) ON DELETE CASCADE
+);
+
+CREATE TABLE pengumuman_penerimas (
+    pengumuman_id INT NOT NULL,
+    user_type VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL,
+    dibaca_pada DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (pengumuman_id, user_type, user_id),
+    INDEX idx_pengumuman_penerimas_user (user_type, user_id),
+    FOREIGN KEY (pengumuman_id) REFERENCES pengumumans(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS pengumuman_penerimas;
+DROP TABLE IF EXISTS pengumuman_lampirans;
+DROP TABLE IF EXISTS pengumuman_targets;
+DROP TABLE IF EXISTS pengumumans;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE notif_delivery_receipts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    outbox_id INT NULL,
+    notification_id INT NULL,
+    type VARCHAR(100),
+    user_type VARCHAR(20),
+    user_id INT,
+    token VARCHAR(512),
+    topic VARCHAR(200),
+    sender VARCHAR(20),
+    message_id VARCHAR(255),
+    success BOOLEAN NOT NULL DEFAULT FALSE,
+    error_code VARCHAR(50),
+    error_message TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_receipt_outbox (outbox_id),
+    INDEX idx_receipt_notification (notification_id),
+    INDEX idx_receipt_principal (user_type, user_id),
+    INDEX idx_receipt_created (created_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notif_delivery_receipts;
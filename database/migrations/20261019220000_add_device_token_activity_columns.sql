@@ -0,0 +1,15 @@
+-- +goose Up
+-- Versi aplikasi dan waktu terakhir device terlihat, dipakai untuk daftar
+-- "perangkat saya" dan pembersihan token yang lama tidak dipakai.
+ALTER TABLE device_tokens
+    ADD COLUMN app_version VARCHAR(50) NULL AFTER platform,
+    ADD COLUMN last_seen_at DATETIME NULL AFTER app_version,
+    ADD INDEX idx_device_tokens_last_seen (last_seen_at);
+
+UPDATE device_tokens SET last_seen_at = COALESCE(updated_at, created_at, NOW());
+
+-- +goose Down
+ALTER TABLE device_tokens
+    DROP INDEX idx_device_tokens_last_seen,
+    DROP COLUMN last_seen_at,
+    DROP COLUMN app_version;
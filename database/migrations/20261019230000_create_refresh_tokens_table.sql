@@ -0,0 +1,20 @@
+-- +goose Up
+-- Refresh token berotasi; hanya hash SHA-256 yang disimpan. session_id
+-- mengelompokkan satu keluarga token dari login yang sama.
+CREATE TABLE refresh_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    revoked_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_refresh_tokens_hash (token_hash),
+    INDEX idx_refresh_tokens_session (session_id),
+    INDEX idx_refresh_tokens_expires (expires_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
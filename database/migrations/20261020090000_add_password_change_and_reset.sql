@@ -0,0 +1,28 @@
+-- +goose Up
+-- Akun yang dibuat admin (password awal NIP/tanggal lahir) wajib mengganti
+-- password saat login pertama. Flag disalin ke sesi saat login.
+ALTER TABLE admins ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE AFTER password;
+ALTER TABLE gurus ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE AFTER password;
+ALTER TABLE siswas ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE AFTER password;
+ALTER TABLE orang_tuas ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE AFTER password;
+ALTER TABLE sessions ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE AFTER role;
+
+CREATE TABLE password_resets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_type VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    percobaan INT NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_password_resets_principal (user_type, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS password_resets;
+ALTER TABLE sessions DROP COLUMN must_change_password;
+ALTER TABLE orang_tuas DROP COLUMN must_change_password;
+ALTER TABLE siswas DROP COLUMN must_change_password;
+ALTER TABLE gurus DROP COLUMN must_change_password;
+ALTER TABLE admins DROP COLUMN must_change_password;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- Riwayat percobaan login dan status penguncian akun setelah gagal login
+-- berturut-turut.
+CREATE TABLE login_attempts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_type VARCHAR(20) NULL,
+    user_id INT NULL,
+    identifier VARCHAR(100) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NULL,
+    berhasil BOOLEAN NOT NULL,
+    alasan VARCHAR(30) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_login_attempts_principal (user_type, user_id),
+    INDEX idx_login_attempts_ip (ip, created_at)
+);
+
+CREATE TABLE login_lockouts (
+    user_type VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL,
+    gagal INT NOT NULL DEFAULT 0,
+    terkunci_sampai DATETIME NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_type, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS login_lockouts;
+DROP TABLE IF EXISTS login_attempts;
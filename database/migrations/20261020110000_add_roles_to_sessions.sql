@@ -0,0 +1,8 @@
+-- +goose Up
+-- Sesi membawa semua role akun (mis. guru sekaligus wali kelas); kolom role
+-- tetap menyimpan role aktif.
+ALTER TABLE sessions ADD COLUMN roles VARCHAR(100) NULL AFTER role;
+UPDATE sessions SET roles = IF(role = 'wali_kelas', 'guru,wali_kelas', role);
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN roles;
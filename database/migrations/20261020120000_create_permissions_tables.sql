@@ -0,0 +1,47 @@
+-- +goose Up
+-- Izin (permission) dan pemetaannya ke role. Handler memeriksa izin, bukan
+-- nama role; pemetaan bisa diubah admin tanpa rilis ulang.
+CREATE TABLE permissions (
+    kode VARCHAR(60) PRIMARY KEY,
+    deskripsi VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE role_permissions (
+    role VARCHAR(20) NOT NULL,
+    permission VARCHAR(60) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role, permission),
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission) REFERENCES permissions(kode) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (kode, deskripsi) VALUES
+    ('absensi.create.mapel', 'Mengisi absen mapel yang diajar'),
+    ('absensi.create.kelas', 'Mengisi absen kelas yang diwali'),
+    ('absensi.read.all', 'Melihat semua absensi'),
+    ('absensi.read.kelas', 'Melihat absensi kelas yang diwali'),
+    ('absensi.read.mapel', 'Melihat absensi mapel yang diajar semester ini'),
+    ('absensi.update.all', 'Mengubah semua absensi'),
+    ('absensi.update.own', 'Mengubah absensi yang dicatat sendiri, semester ini'),
+    ('absensi.update.kelas', 'Mengubah absensi kelas yang diwali, semester ini'),
+    ('absensi.delete.all', 'Menghapus semua absensi'),
+    ('absensi.delete.own', 'Menghapus absensi yang dicatat sendiri, semester ini'),
+    ('absensi.delete.kelas', 'Menghapus absensi kelas yang diwali, semester ini'),
+    ('rbac.manage', 'Melihat dan mengubah pemetaan role ke izin');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'absensi.read.all'),
+    ('admin', 'absensi.update.all'),
+    ('admin', 'absensi.delete.all'),
+    ('admin', 'rbac.manage'),
+    ('guru', 'absensi.create.mapel'),
+    ('guru', 'absensi.read.mapel'),
+    ('guru', 'absensi.update.own'),
+    ('guru', 'absensi.delete.own'),
+    ('wali_kelas', 'absensi.create.kelas'),
+    ('wali_kelas', 'absensi.read.kelas'),
+    ('wali_kelas', 'absensi.update.kelas'),
+    ('wali_kelas', 'absensi.delete.kelas');
+
+-- +goose Down
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Akun yang dinonaktifkan tidak bisa login dan sesinya ditolak. Admin baru
+-- hanya bisa dibuat admin lain atau lewat undangan sekali pakai.
+ALTER TABLE admins ADD COLUMN disabled_at DATETIME NULL AFTER must_change_password;
+ALTER TABLE gurus ADD COLUMN disabled_at DATETIME NULL AFTER must_change_password;
+ALTER TABLE siswas ADD COLUMN disabled_at DATETIME NULL AFTER must_change_password;
+ALTER TABLE orang_tuas ADD COLUMN disabled_at DATETIME NULL AFTER must_change_password;
+
+CREATE TABLE admin_invites (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    email VARCHAR(100) NULL,
+    dibuat_oleh INT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    used_by INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO permissions (kode, deskripsi) VALUES
+    ('admin.manage', 'Mengelola akun admin dan undangan admin'),
+    ('akun.disable', 'Menonaktifkan dan mengaktifkan kembali akun');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'admin.manage'),
+    ('admin', 'akun.disable');
+
+-- +goose Down
+DELETE FROM permissions WHERE kode IN ('admin.manage', 'akun.disable');
+DROP TABLE IF EXISTS admin_invites;
+ALTER TABLE orang_tuas DROP COLUMN disabled_at;
+ALTER TABLE siswas DROP COLUMN disabled_at;
+ALTER TABLE gurus DROP COLUMN disabled_at;
+ALTER TABLE admins DROP COLUMN disabled_at;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Metadata sesi agar user bisa melihat di mana saja ia login, dan exp access
+-- token terakhir untuk membersihkan sesi yang sudah berakhir.
+ALTER TABLE sessions
+    ADD COLUMN ip VARCHAR(45) NULL AFTER must_change_password,
+    ADD COLUMN user_agent VARCHAR(255) NULL AFTER ip,
+    ADD COLUMN device VARCHAR(100) NULL AFTER user_agent,
+    ADD COLUMN last_seen_at DATETIME NULL AFTER device,
+    ADD COLUMN expires_at DATETIME NULL AFTER last_seen_at,
+    ADD INDEX idx_sessions_expires_at (expires_at);
+
+INSERT INTO permissions (kode, deskripsi) VALUES
+    ('sesi.revoke', 'Mencabut semua sesi login sebuah akun');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'sesi.revoke');
+
+-- +goose Down
+DELETE FROM permissions WHERE kode = 'sesi.revoke';
+ALTER TABLE sessions
+    DROP INDEX idx_sessions_expires_at,
+    DROP COLUMN expires_at,
+    DROP COLUMN last_seen_at,
+    DROP COLUMN device,
+    DROP COLUMN user_agent,
+    DROP COLUMN ip;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Dua guru bisa mengajar mapel yang sama di kelas yang sama; reminder mapel
+-- dicatat per guru agar guru kedua tetap diingatkan. 0 untuk absensi kelas.
+ALTER TABLE absensi_reminders
+    ADD COLUMN guru_id INT NOT NULL DEFAULT 0 AFTER mapel_id,
+    DROP INDEX uniq_absensi_reminder,
+    ADD UNIQUE KEY uniq_absensi_reminder (tipe_absensi, kelas_id, mapel_id, guru_id, tanggal, level);
+
+-- +goose Down
+DELETE r1 FROM absensi_reminders r1
+    JOIN absensi_reminders r2
+      ON r1.tipe_absensi = r2.tipe_absensi AND r1.kelas_id = r2.kelas_id
+     AND r1.mapel_id = r2.mapel_id AND r1.tanggal = r2.tanggal
+     AND r1.level = r2.level AND r1.id > r2.id;
+ALTER TABLE absensi_reminders
+    DROP INDEX uniq_absensi_reminder,
+    DROP COLUMN guru_id,
+    ADD UNIQUE KEY uniq_absensi_reminder (tipe_absensi, kelas_id, mapel_id, tanggal, level);
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Percobaan ulang outbox memuat kembali baris inbox yang dibuat percobaan
+-- sebelumnya agar tanda terima push tetap tertaut ke notifikasinya.
+ALTER TABLE notifications
+    ADD COLUMN outbox_id INT NULL AFTER payload,
+    ADD INDEX idx_notifications_outbox (outbox_id);
+
+-- +goose Down
+ALTER TABLE notifications
+    DROP INDEX idx_notifications_outbox,
+    DROP COLUMN outbox_id;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Reset password dan buka kunci login memakai izin sendiri. Target akun
+-- admin tetap membutuhkan admin.manage (diperiksa di handler).
+INSERT INTO permissions (kode, deskripsi) VALUES
+    ('akun.reset_password', 'Mereset password dan membuka kunci login akun');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'akun.reset_password');
+
+-- +goose Down
+DELETE FROM permissions WHERE kode = 'akun.reset_password';
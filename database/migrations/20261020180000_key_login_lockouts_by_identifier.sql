@@ -0,0 +1,22 @@
+-- +goose Up
+-- Penguncian login dicatat per identifier, termasuk identifier yang tidak
+-- terdaftar, agar respons 429 tidak mengungkap akun yang ada. Hitungan
+-- lama per akun tidak bisa dipetakan ke identifier dan dibuang.
+DROP TABLE IF EXISTS login_lockouts;
+CREATE TABLE login_lockouts (
+    identifier VARCHAR(100) NOT NULL PRIMARY KEY,
+    gagal INT NOT NULL DEFAULT 0,
+    terkunci_sampai DATETIME NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS login_lockouts;
+CREATE TABLE login_lockouts (
+    user_type VARCHAR(20) NOT NULL,
+    user_id INT NOT NULL,
+    gagal INT NOT NULL DEFAULT 0,
+    terkunci_sampai DATETIME NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_type, user_id)
+);
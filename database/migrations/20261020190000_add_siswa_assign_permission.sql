@@ -0,0 +1,10 @@
+-- +goose Up
+-- Menempatkan siswa ke kelas/mapel diperiksa lewat izin, bukan role aktif.
+INSERT INTO permissions (kode, deskripsi) VALUES
+    ('siswa.assign', 'Menempatkan dan melepas siswa dari kelas atau mapel');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'siswa.assign');
+
+-- +goose Down
+DELETE FROM permissions WHERE kode = 'siswa.assign';
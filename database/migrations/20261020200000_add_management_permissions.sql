@@ -0,0 +1,41 @@
+-- +goose Up
+-- Endpoint pengelolaan data, kalender, notifikasi dan pengumuman diperiksa
+-- lewat izin, bukan nama role. Pemetaan awal sama dengan role yang
+-- sebelumnya diizinkan.
+INSERT INTO permissions (kode, deskripsi) VALUES
+    ('absensi.export', 'Mengekspor rekap absensi yang boleh dilihat ke CSV'),
+    ('guru.manage', 'Mengelola data guru, pengajaran mapel dan wali kelas'),
+    ('kelas.manage', 'Mengelola data kelas'),
+    ('mapel.manage', 'Mengelola data mata pelajaran'),
+    ('siswa.manage', 'Mengelola data siswa'),
+    ('orang_tua.manage', 'Mengelola data orang tua dan tautannya ke siswa'),
+    ('kalender.manage', 'Mengelola hari libur dan pembatalan jadwal'),
+    ('notif.manage', 'Mengelola tipe notifikasi wajib, outbox dan tanda terima'),
+    ('pengumuman.read', 'Membaca pengumuman yang ditujukan kepadanya'),
+    ('pengumuman.create.all', 'Membuat pengumuman untuk target mana pun'),
+    ('pengumuman.create.kelas', 'Membuat pengumuman untuk kelas yang diwali');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'absensi.export'),
+    ('guru', 'absensi.export'),
+    ('wali_kelas', 'absensi.export'),
+    ('admin', 'guru.manage'),
+    ('admin', 'kelas.manage'),
+    ('admin', 'mapel.manage'),
+    ('admin', 'siswa.manage'),
+    ('admin', 'orang_tua.manage'),
+    ('admin', 'kalender.manage'),
+    ('admin', 'notif.manage'),
+    ('admin', 'pengumuman.read'),
+    ('guru', 'pengumuman.read'),
+    ('wali_kelas', 'pengumuman.read'),
+    ('siswa', 'pengumuman.read'),
+    ('orang_tua', 'pengumuman.read'),
+    ('admin', 'pengumuman.create.all'),
+    ('wali_kelas', 'pengumuman.create.kelas');
+
+-- +goose Down
+DELETE FROM permissions WHERE kode IN (
+    'absensi.export', 'guru.manage', 'kelas.manage', 'mapel.manage',
+    'siswa.manage', 'orang_tua.manage', 'kalender.manage', 'notif.manage',
+    'pengumuman.read', 'pengumuman.create.all', 'pengumuman.create.kelas');
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Tiket sekali pakai untuk stream notifikasi (SSE); hanya hash SHA-256 yang
+-- disimpan.
+CREATE TABLE stream_tickets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    ticket_hash CHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_stream_tickets_hash (ticket_hash),
+    INDEX idx_stream_tickets_session (session_id),
+    INDEX idx_stream_tickets_expires (expires_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS stream_tickets;
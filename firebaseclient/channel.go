@@ -0,0 +1,148 @@
+package firebaseclient
+
+import (
+	"abs-be/models"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Pesan adalah isi notifikasi yang sama untuk semua channel.
+type Pesan struct {
+	Type        string
+	Title       string
+	Body        string
+	Payload     map[string]interface{}
+	PayloadJSON string
+	Lampiran    []Lampiran
+	// NotifIDs memetakan penerima ke baris inbox yang dibuat pada
+	// pengiriman ini, untuk tanda terima push.
+	NotifIDs map[models.Principal]uint
+}
+
+// Penerima berisi data kontak satu user. Email/Telepon kosong jika
+// tidak diketahui; channel yang membutuhkannya akan melewati user tsb.
+// Role adalah role penerima pada notifikasi ini (mis. wali_kelas),
+// sedangkan UserType menentukan tabel akunnya.
+type Penerima struct {
+	UserID   uint
+	UserType string
+	Role     string
+	Nama     string
+	Email    string
+	Telepon  string
+}
+
+func (p Penerima) Principal() models.Principal {
+	return models.Principal{UserType: p.UserType, ID: p.UserID}
+}
+
+// Channel adalah satu jalur pengiriman notifikasi (push, email, sms, ...).
+// Send mengembalikan *GagalSebagian bila hanya sebagian penerima gagal;
+// error lain berarti tidak ada penerima yang dianggap terkirim.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Pesan, to []Penerima) error
+}
+
+// GagalSebagian melaporkan penerima yang gagal pada satu Send. Penerima
+// lain sudah terkirim (atau memang dilewati, mis. tanpa email) sehingga
+// tidak perlu dikirim ulang.
+type GagalSebagian struct {
+	Gagal map[models.Principal]error
+}
+
+func (e *GagalSebagian) Error() string {
+	for _, err := range e.Gagal {
+		return fmt.Sprintf("%d penerima gagal, mis. %v", len(e.Gagal), err)
+	}
+	return "0 penerima gagal"
+}
+
+// tambah mencatat kegagalan p; dipakai channel yang mengirim per penerima.
+func (e *GagalSebagian) tambah(p models.Principal, err error) {
+	if e.Gagal == nil {
+		e.Gagal = map[models.Principal]error{}
+	}
+	e.Gagal[p] = err
+}
+
+// hasil mengembalikan nil bila tidak ada penerima yang gagal.
+func (e *GagalSebagian) hasil() error {
+	if len(e.Gagal) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	channelMu sync.RWMutex
+	channels  = map[string]Channel{"push": PushChannel{}}
+)
+
+// RegisterChannel mendaftarkan (atau mengganti) channel berdasarkan namanya.
+func RegisterChannel(ch Channel) {
+	channelMu.Lock()
+	defer channelMu.Unlock()
+	channels[ch.Name()] = ch
+}
+
+func getChannel(name string) (Channel, bool) {
+	channelMu.RLock()
+	defer channelMu.RUnlock()
+	ch, ok := channels[name]
+	return ch, ok
+}
+
+// InitChannels mendaftarkan semua provider sesuai env. Provider eksternal
+// yang belum dikonfigurasi, atau semua provider bila NOTIF_DRY_RUN=true,
+// diganti LogChannel dengan nama yang sama sehingga routing tetap jalan
+// tanpa layanan luar.
+func InitChannels() error {
+	dryRun := strings.EqualFold(os.Getenv("NOTIF_DRY_RUN"), "true")
+	logCh, err := NewLogChannel("log", os.Getenv("NOTIF_LOG_FILE"))
+	if err != nil {
+		return err
+	}
+	RegisterChannel(logCh)
+
+	pasang := func(name string, ch Channel, siap bool) {
+		if dryRun || !siap {
+			log.Printf("notify: channel %s memakai log provider", name)
+			RegisterChannel(logCh.As(name))
+			return
+		}
+		RegisterChannel(ch)
+	}
+
+	pasang("push", PushChannel{}, true)
+	email := NewEmailChannelFromEnv()
+	pasang("email", email, email.Configured())
+	sms := NewGatewayChannelFromEnv("sms", "SMS_GATEWAY")
+	pasang("sms", sms, sms.Configured())
+	wa := NewGatewayChannelFromEnv("whatsapp", "WA_GATEWAY")
+	pasang("whatsapp", wa, wa.Configured())
+
+	return loadRouting()
+}
+
+// KirimLangsung mengirim pesan ke satu user lewat channel tertentu tanpa
+// membuat inbox dan tanpa melihat preferensi/routing. Dipakai untuk pesan
+// transaksional seperti kode reset password.
+func KirimLangsung(ctx context.Context, channel string, msg Pesan, to models.Principal) error {
+	ch, ok := getChannel(channel)
+	if !ok {
+		return fmt.Errorf("channel %q tidak terdaftar", channel)
+	}
+	penerima, err := kontakPenerima("", []models.Principal{to})
+	if err != nil {
+		return err
+	}
+	if len(penerima) == 0 {
+		return fmt.Errorf("user %s/%d tidak ditemukan", to.UserType, to.ID)
+	}
+	return ch.Send(ctx, msg, penerima)
+}
@@ -0,0 +1,171 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// EmailChannel mengirim notifikasi lewat SMTP memakai template per type.
+//
+// Env: SMTP_HOST, SMTP_PORT (587), SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM,
+// SMTP_TLS = starttls (default, dipakai jika server mendukung) | tls
+// (implicit, biasanya port 465) | none (untuk stand-in lokal seperti MailHog).
+type EmailChannel struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	TLSMode  string
+	Timeout  time.Duration
+}
+
+func NewEmailChannelFromEnv() *EmailChannel {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	mode := strings.ToLower(os.Getenv("SMTP_TLS"))
+	if mode == "" {
+		mode = "starttls"
+	}
+	return &EmailChannel{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		TLSMode:  mode,
+		Timeout:  30 * time.Second,
+	}
+}
+
+func (e *EmailChannel) Configured() bool { return e.Host != "" && e.From != "" }
+
+func (e *EmailChannel) Name() string { return "email" }
+
+func (e *EmailChannel) Send(ctx context.Context, msg Pesan, to []Penerima) error {
+	gagal := &GagalSebagian{}
+	for _, p := range to {
+		if p.Email == "" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			gagal.tambah(p.Principal(), err)
+			continue
+		}
+		err := e.kirimSatu(msg, p)
+		catatEmail(msg, p, err)
+		if err != nil {
+			log.Printf("notify: email ke %s gagal: %v", p.Email, err)
+			gagal.tambah(p.Principal(), err)
+		}
+	}
+	return gagal.hasil()
+}
+
+func (e *EmailChannel) kirimSatu(msg Pesan, p Penerima) error {
+	htmlBody, textBody, err := renderEmail(msg.Type, emailData{
+		AppName:  emailAppName(),
+		Nama:     p.Nama,
+		Title:    msg.Title,
+		Body:     msg.Body,
+		Payload:  msg.Payload,
+		Lampiran: len(msg.Lampiran) > 0,
+	})
+	if err != nil {
+		return err
+	}
+	raw, err := buildMIME(e.From, p.Email, msg.Title, textBody, htmlBody, msg.Lampiran)
+	if err != nil {
+		return fmt.Errorf("susun email: %w", err)
+	}
+	return e.sendMail(p.Email, raw)
+}
+
+func (e *EmailChannel) sendMail(rcpt string, raw []byte) error {
+	addr := net.JoinHostPort(e.Host, e.Port)
+	tlsCfg := &tls.Config{ServerName: e.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: e.Timeout}
+	if e.TLSMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(e.Timeout))
+
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.TLSMode == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsCfg); err != nil {
+				return err
+			}
+		}
+	}
+	if e.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(rcpt); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func catatEmail(msg Pesan, p Penerima, sendErr error) {
+	if database.DB == nil {
+		return
+	}
+	row := models.EmailLog{
+		Type:     msg.Type,
+		UserID:   p.UserID,
+		UserType: p.UserType,
+		Role:     p.Role,
+		Email:    p.Email,
+		Subject:  msg.Title,
+		Lampiran: len(msg.Lampiran),
+		Status:   "terkirim",
+	}
+	if sendErr != nil {
+		row.Status = "gagal"
+		row.Error = sendErr.Error()
+	}
+	if err := database.DB.Create(&row).Error; err != nil {
+		log.Printf("notify: gagal mencatat email log: %v", err)
+	}
+}
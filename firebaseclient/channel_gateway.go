@@ -0,0 +1,79 @@
+package firebaseclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// GatewayChannel mengirim pesan teks ke gateway HTTP (SMS atau WhatsApp).
+// Request: POST <URL> {"to": "<telepon>", "message": "<teks>", "type": "<type>"}
+// dengan header Authorization: Bearer <TOKEN> bila token diisi.
+type GatewayChannel struct {
+	name   string
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewGatewayChannelFromEnv membaca <prefix>_URL dan <prefix>_TOKEN.
+func NewGatewayChannelFromEnv(name, prefix string) *GatewayChannel {
+	return &GatewayChannel{
+		name:   name,
+		URL:    os.Getenv(prefix + "_URL"),
+		Token:  os.Getenv(prefix + "_TOKEN"),
+		Client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *GatewayChannel) Configured() bool { return g.URL != "" }
+
+func (g *GatewayChannel) Name() string { return g.name }
+
+func (g *GatewayChannel) Send(ctx context.Context, msg Pesan, to []Penerima) error {
+	teks := msg.Title + "\n" + msg.Body
+	gagal := &GagalSebagian{}
+	for _, p := range to {
+		if p.Telepon == "" {
+			continue
+		}
+		if err := g.kirim(ctx, p.Telepon, teks, msg.Type); err != nil {
+			log.Printf("notify: %s ke %s gagal: %v", g.name, p.Telepon, err)
+			gagal.tambah(p.Principal(), err)
+		}
+	}
+	return gagal.hasil()
+}
+
+func (g *GatewayChannel) kirim(ctx context.Context, telepon, teks, typeStr string) error {
+	b, err := json.Marshal(map[string]string{
+		"to":      telepon,
+		"message": teks,
+		"type":    typeStr,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway membalas status %d", resp.StatusCode)
+	}
+	return nil
+}
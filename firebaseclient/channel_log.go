@@ -0,0 +1,72 @@
+package firebaseclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogChannel menulis setiap pengiriman sebagai satu baris JSON ke file
+// (atau ke log standar bila path kosong). Dipakai untuk pengujian lokal
+// dan sebagai pengganti provider yang belum dikonfigurasi.
+type LogChannel struct {
+	name string
+	w    io.Writer
+	mu   *sync.Mutex
+}
+
+func NewLogChannel(name, path string) (*LogChannel, error) {
+	var w io.Writer = log.Writer()
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	return &LogChannel{name: name, w: w, mu: &sync.Mutex{}}, nil
+}
+
+// As mengembalikan LogChannel yang menulis ke tujuan yang sama dengan nama lain.
+func (l *LogChannel) As(name string) *LogChannel {
+	return &LogChannel{name: name, w: l.w, mu: l.mu}
+}
+
+func (l *LogChannel) Name() string { return l.name }
+
+func (l *LogChannel) Send(ctx context.Context, msg Pesan, to []Penerima) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	enc := json.NewEncoder(l.w)
+	lampiran := make([]string, 0, len(msg.Lampiran))
+	for _, a := range msg.Lampiran {
+		lampiran = append(lampiran, a.Nama)
+	}
+	gagal := &GagalSebagian{}
+	for _, p := range to {
+		if err := enc.Encode(map[string]interface{}{
+			"waktu":    time.Now().Format(time.RFC3339),
+			"channel":  l.name,
+			"type":     msg.Type,
+			"title":    msg.Title,
+			"body":     msg.Body,
+			"payload":  msg.Payload,
+			"lampiran": lampiran,
+			"penerima": map[string]interface{}{
+				"user_id":   p.UserID,
+				"user_type": p.UserType,
+				"role":      p.Role,
+				"nama":      p.Nama,
+				"email":     p.Email,
+				"telepon":   p.Telepon,
+			},
+		}); err != nil {
+			gagal.tambah(p.Principal(), err)
+		}
+	}
+	return gagal.hasil()
+}
@@ -0,0 +1,50 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// PushChannel mengirim lewat FCM ke semua device token milik penerima.
+type PushChannel struct{}
+
+func (PushChannel) Name() string { return "push" }
+
+func (PushChannel) Send(ctx context.Context, msg Pesan, to []Penerima) error {
+	principals := make([]models.Principal, 0, len(to))
+	for _, p := range to {
+		principals = append(principals, p.Principal())
+	}
+	if len(principals) == 0 {
+		return nil
+	}
+
+	var tokens []models.DeviceToken
+	if err := database.DB.
+		Where("(user_type, user_id) IN ?", pasanganPrincipal(principals)).
+		Find(&tokens).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("gagal ambil device tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	tujuan := make([]tujuanToken, 0, len(tokens))
+	for _, t := range tokens {
+		p := models.Principal{UserType: t.UserType, ID: t.UserID}
+		tt := tujuanToken{Token: t.Token, Principal: p}
+		if id, ok := msg.NotifIDs[p]; ok {
+			tt.NotifID = &id
+		}
+		tujuan = append(tujuan, tt)
+	}
+
+	dataMap := map[string]string{
+		"type":    msg.Type,
+		"payload": msg.PayloadJSON,
+	}
+	return kirimKeTokens(ctx, msg.Title, msg.Body, dataMap, tujuan)
+}
@@ -0,0 +1,76 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// tokenTidakBerlaku menentukan dari kode error FCM apakah token sudah tidak
+// bisa dipakai lagi dan perlu dihapus.
+func tokenTidakBerlaku(kode string) bool {
+	switch kode {
+	case KodeUnregistered, KodeSenderIDMismatch:
+		return true
+	}
+	return false
+}
+
+// HapusDeviceToken melepas token dari semua topic yang tercatat lalu
+// menghapusnya, mis. saat user menghapus perangkat atau logout.
+func HapusDeviceToken(ctx context.Context, tokens ...string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	var tercatat []models.DeviceTokenTopik
+	if err := database.DB.Where("token IN ?", tokens).Find(&tercatat).Error; err != nil {
+		return fmt.Errorf("gagal ambil topic tercatat: %w", err)
+	}
+	if client, _ := currentSender(); client != nil {
+		perTopik := map[string][]string{}
+		for _, r := range tercatat {
+			perTopik[r.Topic] = append(perTopik[r.Topic], r.Token)
+		}
+		for topic, toks := range perTopik {
+			// Kegagalan melepas sudah dicatat kelolaTopik; token tetap dihapus.
+			kelolaTopik(ctx, client.UnsubscribeFromTopic, topic, toks)
+		}
+	}
+	return hapusTokenLokal(tokens...)
+}
+
+func hapusTokenLokal(tokens ...string) error {
+	if err := database.DB.Where("token IN ?", tokens).Delete(&models.DeviceTokenTopik{}).Error; err != nil {
+		return fmt.Errorf("gagal hapus topic token: %w", err)
+	}
+	if err := database.DB.Where("token IN ?", tokens).Delete(&models.DeviceToken{}).Error; err != nil {
+		return fmt.Errorf("gagal hapus device token: %w", err)
+	}
+	return nil
+}
+
+// BersihkanTokenUsang menghapus token yang tidak terlihat sejak batas dan
+// mengembalikan jumlahnya.
+func BersihkanTokenUsang(ctx context.Context, batas time.Time) (int, error) {
+	total := 0
+	for {
+		var tokens []string
+		if err := database.DB.Model(&models.DeviceToken{}).
+			Where("COALESCE(last_seen_at, updated_at) < ?", batas).
+			Limit(500).Pluck("token", &tokens).Error; err != nil {
+			return total, err
+		}
+		if len(tokens) == 0 {
+			return total, nil
+		}
+		if err := HapusDeviceToken(ctx, tokens...); err != nil {
+			return total, err
+		}
+		total += len(tokens)
+		if len(tokens) < 500 {
+			return total, nil
+		}
+	}
+}
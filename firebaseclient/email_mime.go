@@ -0,0 +1,100 @@
+package firebaseclient
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Lampiran adalah file yang ikut dikirim pada channel yang mendukungnya (email).
+type Lampiran struct {
+	Nama        string
+	ContentType string
+	Data        []byte
+}
+
+// buildMIME menyusun email multipart/mixed berisi multipart/alternative
+// (teks + HTML) dan lampiran.
+func buildMIME(from, to, subject, textBody, htmlBody string, lampiran []Lampiran) ([]byte, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	hdr := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + mixed.Boundary(),
+	}
+	head := strings.Join(hdr, "\r\n") + "\r\n\r\n"
+
+	altHeader := textproto.MIMEHeader{}
+	var altBuf bytes.Buffer
+	alt := multipart.NewWriter(&altBuf)
+	altHeader.Set("Content-Type", "multipart/alternative; boundary="+alt.Boundary())
+	for _, part := range []struct{ ctype, isi string }{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		w, err := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ctype},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.isi)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := alt.Close(); err != nil {
+		return nil, err
+	}
+	w, err := mixed.CreatePart(altHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(altBuf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, l := range lampiran {
+		ctype := l.ContentType
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+		w, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", ctype, l.Nama)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", l.Nama)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		enc := base64.StdEncoding.EncodeToString(l.Data)
+		for i := 0; i < len(enc); i += 76 {
+			end := i + 76
+			if end > len(enc) {
+				end = len(enc)
+			}
+			if _, err := w.Write([]byte(enc[i:end] + "\r\n")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return append([]byte(head), buf.Bytes()...), nil
+}
@@ -0,0 +1,60 @@
+package firebaseclient
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	texttemplate "text/template"
+)
+
+//go:embed templates/email/*
+var emailTemplateFS embed.FS
+
+type emailData struct {
+	AppName  string
+	Nama     string
+	Title    string
+	Body     string
+	Payload  map[string]interface{}
+	Lampiran bool
+}
+
+func emailAppName() string {
+	if v := os.Getenv("APP_NAME"); v != "" {
+		return v
+	}
+	return "Absensi Sekolah"
+}
+
+// renderEmail menghasilkan versi HTML dan teks untuk sebuah type.
+// Type tanpa template khusus memakai default.html / default.txt.
+func renderEmail(typeStr string, data emailData) (string, string, error) {
+	htmlName, textName := "default.html", "default.txt"
+	if _, err := emailTemplateFS.Open("templates/email/" + typeStr + ".html"); err == nil {
+		htmlName = typeStr + ".html"
+	}
+	if _, err := emailTemplateFS.Open("templates/email/" + typeStr + ".txt"); err == nil {
+		textName = typeStr + ".txt"
+	}
+
+	ht, err := htmltemplate.ParseFS(emailTemplateFS, "templates/email/layout.html", "templates/email/"+htmlName)
+	if err != nil {
+		return "", "", fmt.Errorf("parse template html %s: %w", htmlName, err)
+	}
+	var hb bytes.Buffer
+	if err := ht.ExecuteTemplate(&hb, "layout", data); err != nil {
+		return "", "", fmt.Errorf("render template html %s: %w", htmlName, err)
+	}
+
+	tt, err := texttemplate.ParseFS(emailTemplateFS, "templates/email/"+textName)
+	if err != nil {
+		return "", "", fmt.Errorf("parse template teks %s: %w", textName, err)
+	}
+	var tb bytes.Buffer
+	if err := tt.Execute(&tb, data); err != nil {
+		return "", "", fmt.Errorf("render template teks %s: %w", textName, err)
+	}
+	return hb.String(), tb.String(), nil
+}
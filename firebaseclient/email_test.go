@@ -0,0 +1,470 @@
+package firebaseclient
+
+import (
+	"abs-be/notifikasi"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// emailTerurai adalah hasil parse email yang disusun buildMIME.
+type emailTerurai struct {
+	header   mail.Header
+	subject  string
+	teks     string
+	html     string
+	lampiran map[string][]byte
+	ctype    map[string]string
+}
+
+func uraiEmail(t *testing.T, raw []byte) emailTerurai {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	hasil := emailTerurai{header: msg.Header, lampiran: map[string][]byte{}, ctype: map[string]string{}}
+	dec := new(mime.WordDecoder)
+	if hasil.subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		t.Fatalf("decode subject: %v", err)
+	}
+
+	mt, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mixed.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		pt, pp, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if pt == "multipart/alternative" {
+			alt := multipart.NewReader(part, pp["boundary"])
+			for {
+				ap, err := alt.NextRawPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("NextRawPart: %v", err)
+				}
+				if te := ap.Header.Get("Content-Transfer-Encoding"); te != "quoted-printable" {
+					t.Errorf("Content-Transfer-Encoding = %q, want quoted-printable", te)
+				}
+				isi, err := io.ReadAll(quotedprintable.NewReader(ap))
+				if err != nil {
+					t.Fatalf("baca quoted-printable: %v", err)
+				}
+				at, _, _ := mime.ParseMediaType(ap.Header.Get("Content-Type"))
+				switch at {
+				case "text/plain":
+					hasil.teks = string(isi)
+				case "text/html":
+					hasil.html = string(isi)
+				default:
+					t.Errorf("part alternative tak terduga: %s", at)
+				}
+			}
+			continue
+		}
+
+		_, dp, err := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+		if err != nil {
+			t.Fatalf("Content-Disposition lampiran: %v", err)
+		}
+		enc, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, baris := range strings.Split(strings.TrimRight(string(enc), "\r\n"), "\r\n") {
+			if len(baris) > 76 {
+				t.Errorf("baris base64 %d karakter, maksimal 76", len(baris))
+			}
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(enc), "\r\n", ""))
+		if err != nil {
+			t.Fatalf("decode base64 %s: %v", dp["filename"], err)
+		}
+		hasil.lampiran[dp["filename"]] = data
+		hasil.ctype[dp["filename"]] = pt
+	}
+	return hasil
+}
+
+func TestBuildMIME(t *testing.T) {
+	besar := bytes.Repeat([]byte("0123456789"), 50)
+	tests := []struct {
+		nama     string
+		subject  string
+		teks     string
+		html     string
+		lampiran []Lampiran
+	}{
+		{
+			nama:    "ascii tanpa lampiran",
+			subject: "Akun Guru Baru Dibuat",
+			teks:    "Halo Budi,\n\nAkun dibuat.",
+			html:    "<p>Akun dibuat.</p>",
+		},
+		{
+			nama:    "subject dan isi non-ascii",
+			subject: "Rekap selesai – kelas X café ✓",
+			teks:    "Tanggal: 20–10–2026, naïve = ya",
+			html:    "<p>Résumé ✓</p>",
+		},
+		{
+			nama:    "baris panjang dan lampiran",
+			subject: "Export Rekap Mapel Selesai",
+			teks:    strings.Repeat("a", 200),
+			html:    "<p>" + strings.Repeat("b", 200) + "</p>",
+			lampiran: []Lampiran{
+				{Nama: "rekap.xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Data: besar},
+				{Nama: "catatan.bin", Data: []byte{0, 1, 2, 0xff}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			raw, err := buildMIME("sekolah@example.com", "budi@example.com", tt.subject, tt.teks, tt.html, tt.lampiran)
+			if err != nil {
+				t.Fatalf("buildMIME: %v", err)
+			}
+			for _, baris := range strings.Split(string(raw), "\r\n") {
+				if len(baris) > 998 {
+					t.Fatalf("baris melebihi batas SMTP: %d karakter", len(baris))
+				}
+			}
+			head := string(raw[:bytes.Index(raw, []byte("\r\n\r\n"))])
+			for _, c := range head {
+				if c > 127 {
+					t.Fatalf("header berisi karakter non-ascii: %q", head)
+				}
+			}
+
+			got := uraiEmail(t, raw)
+			if got.header.Get("From") != "sekolah@example.com" || got.header.Get("To") != "budi@example.com" {
+				t.Errorf("From/To = %q/%q", got.header.Get("From"), got.header.Get("To"))
+			}
+			if got.header.Get("MIME-Version") != "1.0" {
+				t.Errorf("MIME-Version = %q", got.header.Get("MIME-Version"))
+			}
+			if _, err := got.header.Date(); err != nil {
+				t.Errorf("Date tidak valid: %v", err)
+			}
+			if got.subject != tt.subject {
+				t.Errorf("subject = %q, want %q", got.subject, tt.subject)
+			}
+			// quoted-printable mode teks mengubah baris baru menjadi CRLF.
+			if want := strings.ReplaceAll(tt.teks, "\n", "\r\n"); got.teks != want {
+				t.Errorf("teks = %q, want %q", got.teks, want)
+			}
+			if got.html != tt.html {
+				t.Errorf("html = %q, want %q", got.html, tt.html)
+			}
+			if len(got.lampiran) != len(tt.lampiran) {
+				t.Fatalf("lampiran = %d, want %d", len(got.lampiran), len(tt.lampiran))
+			}
+			for _, l := range tt.lampiran {
+				if !bytes.Equal(got.lampiran[l.Nama], l.Data) {
+					t.Errorf("isi lampiran %s berubah", l.Nama)
+				}
+				want := l.ContentType
+				if want == "" {
+					want = "application/octet-stream"
+				}
+				if got.ctype[l.Nama] != want {
+					t.Errorf("content type %s = %q, want %q", l.Nama, got.ctype[l.Nama], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEmailPerBahasa(t *testing.T) {
+	payload := notifikasi.CreateGuru{GuruID: 7, Nama: "Siti <Aminah>", Email: "siti@example.com"}
+	tests := []struct {
+		bahasa string
+		judul  string
+	}{
+		{notifikasi.BahasaID, "Akun Guru Baru Dibuat"},
+		{notifikasi.BahasaEN, "New Teacher Account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bahasa, func(t *testing.T) {
+			title, body, err := notifikasi.Render(payload, tt.bahasa)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if title != tt.judul {
+				t.Fatalf("judul = %q, want %q", title, tt.judul)
+			}
+			html, teks, err := renderEmail(payload.Type(), emailData{
+				AppName: "Absensi Uji",
+				Nama:    "Pak Admin",
+				Title:   title,
+				Body:    body,
+				Payload: notifikasi.ToMap(payload),
+			})
+			if err != nil {
+				t.Fatalf("renderEmail: %v", err)
+			}
+			for _, want := range []string{"<title>" + tt.judul + "</title>", "Halo Pak Admin,", "Siti &lt;Aminah&gt;", "siti@example.com", "Absensi Uji"} {
+				if !strings.Contains(html, want) {
+					t.Errorf("html tidak memuat %q", want)
+				}
+			}
+			if strings.Contains(html, "<Aminah>") {
+				t.Error("html tidak meng-escape payload")
+			}
+			for _, want := range []string{"Halo Pak Admin,", "Nama  : Siti <Aminah>", "Email : siti@example.com", "Absensi Uji - email ini"} {
+				if !strings.Contains(teks, want) {
+					t.Errorf("teks tidak memuat %q:\n%s", want, teks)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderEmailDefault(t *testing.T) {
+	tests := []struct {
+		nama     string
+		data     emailData
+		ada      []string
+		tidakAda []string
+	}{
+		{
+			nama:     "dengan nama",
+			data:     emailData{AppName: "Absensi", Nama: "Budi", Title: "Izin Disetujui", Body: "Izin tanggal 20 disetujui."},
+			ada:      []string{"Halo Budi,", "Izin Disetujui", "Izin tanggal 20 disetujui."},
+			tidakAda: nil,
+		},
+		{
+			nama:     "tanpa nama",
+			data:     emailData{AppName: "Absensi", Title: "Pengumuman", Body: "Besok libur."},
+			ada:      []string{"Pengumuman", "Besok libur."},
+			tidakAda: []string{"Halo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			html, teks, err := renderEmail("type_tanpa_template", tt.data)
+			if err != nil {
+				t.Fatalf("renderEmail: %v", err)
+			}
+			for _, s := range tt.ada {
+				if !strings.Contains(html, s) || !strings.Contains(teks, s) {
+					t.Errorf("email tidak memuat %q", s)
+				}
+			}
+			for _, s := range tt.tidakAda {
+				if strings.Contains(html, s) || strings.Contains(teks, s) {
+					t.Errorf("email seharusnya tidak memuat %q", s)
+				}
+			}
+		})
+	}
+}
+
+// smtpPalsu adalah server SMTP minimal yang menyimpan setiap email yang
+// diterimanya. Penerima yang ditolak dibalas 550.
+type smtpPalsu struct {
+	ln    net.Listener
+	tolak map[string]bool
+
+	mu    sync.Mutex
+	masuk []emailMasuk
+}
+
+type emailMasuk struct {
+	from string
+	rcpt string
+	data []byte
+}
+
+func jalankanSMTPPalsu(t *testing.T, tolak ...string) *smtpPalsu {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpPalsu{ln: ln, tolak: map[string]bool{}}
+	for _, r := range tolak {
+		s.tolak[r] = true
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.layani(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpPalsu) layani(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 smtp palsu")
+	var cur emailMasuk
+	for {
+		baris, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		perintah := strings.ToUpper(baris)
+		switch {
+		case strings.HasPrefix(perintah, "EHLO"), strings.HasPrefix(perintah, "HELO"):
+			_ = tp.PrintfLine("250 smtp palsu")
+		case strings.HasPrefix(perintah, "MAIL FROM:"):
+			cur = emailMasuk{from: strings.Trim(baris[len("MAIL FROM:"):], "<> ")}
+			_ = tp.PrintfLine("250 OK")
+		case strings.HasPrefix(perintah, "RCPT TO:"):
+			cur.rcpt = strings.Trim(baris[len("RCPT TO:"):], "<> ")
+			if s.tolak[cur.rcpt] {
+				_ = tp.PrintfLine("550 mailbox tidak ada")
+				continue
+			}
+			_ = tp.PrintfLine("250 OK")
+		case perintah == "DATA":
+			_ = tp.PrintfLine("354 lanjut")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			cur.data = data
+			s.mu.Lock()
+			s.masuk = append(s.masuk, cur)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 OK")
+		case perintah == "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 tidak didukung")
+		}
+	}
+}
+
+func (s *smtpPalsu) diterima() []emailMasuk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]emailMasuk(nil), s.masuk...)
+}
+
+func (s *smtpPalsu) channel() *EmailChannel {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return &EmailChannel{Host: host, Port: port, From: "sekolah@example.com", TLSMode: "none", Timeout: 5 * time.Second}
+}
+
+func TestEmailChannelSend(t *testing.T) {
+	srv := jalankanSMTPPalsu(t)
+	ch := srv.channel()
+
+	payload := notifikasi.ExportRekapMapel{MapelID: 3, KelasID: 4, Tanggal: "2026-10-19", Filename: "rekap.xlsx", RecordCount: 30}
+	title, body, err := notifikasi.Render(payload, notifikasi.BahasaEN)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	msg := Pesan{
+		Type:     payload.Type(),
+		Title:    title,
+		Body:     body,
+		Payload:  notifikasi.ToMap(payload),
+		Lampiran: []Lampiran{{Nama: "rekap.xlsx", Data: []byte("isi rekap")}},
+	}
+	to := []Penerima{
+		{UserID: 1, UserType: "guru", Nama: "Budi", Email: "budi@example.com"},
+		{UserID: 2, UserType: "guru", Nama: "Tanpa Email"},
+		{UserID: 3, UserType: "guru", Nama: "Ani Ç", Email: "ani@example.com"},
+	}
+	if err := ch.Send(context.Background(), msg, to); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	masuk := srv.diterima()
+	if len(masuk) != 2 {
+		t.Fatalf("email diterima = %d, want 2 (penerima tanpa email dilewati)", len(masuk))
+	}
+	for i, rcpt := range []string{"budi@example.com", "ani@example.com"} {
+		m := masuk[i]
+		if m.from != "sekolah@example.com" || m.rcpt != rcpt {
+			t.Errorf("envelope = %s -> %s, want sekolah@example.com -> %s", m.from, m.rcpt, rcpt)
+		}
+		got := uraiEmail(t, m.data)
+		if got.subject != title {
+			t.Errorf("subject = %q, want %q", got.subject, title)
+		}
+		if got.header.Get("To") != rcpt {
+			t.Errorf("To = %q, want %q", got.header.Get("To"), rcpt)
+		}
+		if string(got.lampiran["rekap.xlsx"]) != "isi rekap" {
+			t.Errorf("lampiran tidak ikut terkirim ke %s", rcpt)
+		}
+		if got.html == "" || got.teks == "" {
+			t.Errorf("email ke %s tanpa versi teks/html", rcpt)
+		}
+	}
+	if got := uraiEmail(t, masuk[1].data); !strings.Contains(got.teks, "Halo Ani Ç,") {
+		t.Errorf("teks tidak menyapa penerima:\n%s", got.teks)
+	}
+}
+
+func TestEmailChannelSendSebagianGagal(t *testing.T) {
+	srv := jalankanSMTPPalsu(t, "hilang@example.com")
+	ch := srv.channel()
+
+	msg := Pesan{Type: "pengumuman", Title: "Rapat", Body: "Rapat jam 9."}
+	to := []Penerima{
+		{UserID: 1, UserType: "guru", Email: "hilang@example.com"},
+		{UserID: 2, UserType: "guru", Email: "ada@example.com"},
+	}
+	err := ch.Send(context.Background(), msg, to)
+	var sebagian *GagalSebagian
+	if !errors.As(err, &sebagian) {
+		t.Fatalf("Send() error = %v, want *GagalSebagian", err)
+	}
+	if _, ok := sebagian.Gagal[to[0].Principal()]; !ok || len(sebagian.Gagal) != 1 {
+		t.Errorf("penerima gagal = %v, want hanya %v", sebagian.Gagal, to[0].Principal())
+	}
+	masuk := srv.diterima()
+	if len(masuk) != 1 || masuk[0].rcpt != "ada@example.com" {
+		t.Fatalf("email diterima = %+v, want hanya ada@example.com", masuk)
+	}
+}
+
+func TestEmailChannelConfigured(t *testing.T) {
+	tests := []struct {
+		nama string
+		ch   EmailChannel
+		want bool
+	}{
+		{"lengkap", EmailChannel{Host: "smtp.example.com", From: "a@example.com"}, true},
+		{"tanpa host", EmailChannel{From: "a@example.com"}, false},
+		{"tanpa from", EmailChannel{Host: "smtp.example.com"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.ch.Configured(); got != tt.want {
+			t.Errorf("%s: Configured() = %v, want %v", tt.nama, got, tt.want)
+		}
+	}
+}
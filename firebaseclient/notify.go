@@ -3,37 +3,68 @@ package firebaseclient
 import (
 	"abs-be/database"
 	"abs-be/models"
+	"abs-be/realtime"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"firebase.google.com/go/v4/messaging"
 	"google.golang.org/grpc/status"
-	"gorm.io/gorm"
 )
 
+// SendToTokens mengirim pesan yang sama ke daftar token tanpa pemilik yang
+// diketahui.
 func SendToTokens(ctx context.Context, title, body string, data map[string]string, tokens []string) error {
-	client := MessagingClient()
+	tujuan := make([]tujuanToken, 0, len(tokens))
+	for _, tok := range tokens {
+		tujuan = append(tujuan, tujuanToken{Token: tok})
+	}
+	return kirimKeTokens(ctx, title, body, data, tujuan)
+}
+
+// Batas token per panggilan multicast FCM.
+const multicastChunk = 500
+
+// kirimKeTokens mengirim multicast per 500 token dan mencatat tanda terima
+// setiap token. Pemilik token yang gagal sementara (mis. UNAVAILABLE)
+// dilaporkan lewat *GagalSebagian agar dikirim ulang; token yang memang
+// tidak berlaku lagi tidak dihitung gagal.
+func kirimKeTokens(ctx context.Context, title, body string, data map[string]string, tujuan []tujuanToken) error {
+	client, nama := currentSender()
 	if client == nil {
-		return fmt.Errorf("firebase client not initialized")
+		return fmt.Errorf("firebase sender not initialized")
 	}
-	if len(tokens) == 0 {
+	if len(tujuan) == 0 {
 		return nil
 	}
+	catat := pencatatReceipt(ctx, nama, data["type"])
+	gagal := kirimMulticast(ctx, client, title, body, data, tujuan, catat)
+	catat.simpan()
+
+	return gagal.hasil()
+}
 
-	chunkSize := 500
-	for i := 0; i < len(tokens); i += chunkSize {
-		end := i + chunkSize
-		if end > len(tokens) {
-			end = len(tokens)
+// kirimMulticast mengirim per chunk dan mencatat hasil setiap token di
+// catat. Chunk yang gagal dikirim seluruhnya diulang per token. Pemilik
+// token yang gagal sementara dikembalikan di GagalSebagian.
+func kirimMulticast(ctx context.Context, client Sender, title, body string, data map[string]string, tujuan []tujuanToken, catat *receiptBatch) *GagalSebagian {
+	gagal := &GagalSebagian{}
+	for i := 0; i < len(tujuan); i += multicastChunk {
+		end := i + multicastChunk
+		if end > len(tujuan) {
+			end = len(tujuan)
+		}
+		chunk := tujuan[i:end]
+		tokens := make([]string, 0, len(chunk))
+		for _, t := range chunk {
+			tokens = append(tokens, t.Token)
 		}
-		chunk := tokens[i:end]
 
 		msg := &messaging.MulticastMessage{
-			Tokens: chunk,
+			Tokens: tokens,
 			Notification: &messaging.Notification{
 				Title: title,
 				Body:  body,
@@ -55,27 +86,35 @@ func SendToTokens(ctx context.Context, title, body string, data map[string]strin
 			}
 
 			log.Printf("SendMulticast failed, falling back to individual sends for %d tokens", len(chunk))
-			fallbackSendPerToken(ctx, client, title, body, data, chunk)
+			fallbackSendPerToken(ctx, client, title, body, data, chunk, catat, gagal)
 			continue
 		}
 
 		for idx, r := range resp.Responses {
-			if !r.Success {
-				tok := chunk[idx]
-				if r.Error != nil {
-					handleFCMErrorForToken(tok, r.Error)
-				}
+			if idx >= len(chunk) {
+				break
+			}
+			catat.token(chunk[idx], r.MessageID, r.Error)
+			if !r.Success && r.Error != nil {
+				handleFCMErrorForToken(chunk[idx].Token, r.Error)
+				catatGagalToken(gagal, chunk[idx], r.Error)
 			}
 		}
 	}
+	return gagal
+}
 
-	return nil
+// catatGagalToken menandai pemilik token gagal bila error-nya sementara.
+func catatGagalToken(gagal *GagalSebagian, t tujuanToken, err error) {
+	if errorSementara(kodeErrorFCM(err)) {
+		gagal.tambah(t.Principal, err)
+	}
 }
 
-func fallbackSendPerToken(ctx context.Context, client *messaging.Client, title, body string, data map[string]string, tokens []string) {
-	for _, tok := range tokens {
+func fallbackSendPerToken(ctx context.Context, client Sender, title, body string, data map[string]string, tujuan []tujuanToken, catat *receiptBatch, gagal *GagalSebagian) {
+	for _, t := range tujuan {
 		m := &messaging.Message{
-			Token: tok,
+			Token: t.Token,
 			Notification: &messaging.Notification{
 				Title: title,
 				Body:  body,
@@ -88,48 +127,51 @@ func fallbackSendPerToken(ctx context.Context, client *messaging.Client, title,
 				},
 			},
 		}
-		_, err := client.Send(ctx, m)
+		id, err := client.Send(ctx, m)
+		catat.token(t, id, err)
 		if err != nil {
-			log.Printf("Send to token failed: %s -> %v", tok, err)
-			handleFCMErrorForToken(tok, err)
+			log.Printf("Send to token failed: %s -> %v", t.Token, err)
+			handleFCMErrorForToken(t.Token, err)
+			catatGagalToken(gagal, t, err)
 			continue
 		}
-		// time.Sleep(10 * time.Millisecond)
 	}
 }
 
+// handleFCMErrorForToken menghapus token yang menurut kode error FCM sudah
+// tidak berlaku.
 func handleFCMErrorForToken(tok string, err error) {
-	errStr := err.Error()
-	log.Printf("FCM error for token %s: %v", tok, errStr)
-	if strings.Contains(errStr, "registration token not registered") ||
-		strings.Contains(errStr, "invalid registration token") ||
-		strings.Contains(errStr, "not registered") {
-		if err := database.DB.Where("token = ?", tok).Delete(&models.DeviceToken{}).Error; err != nil && err != gorm.ErrRecordNotFound {
-			log.Printf("failed delete device token %s: %v", tok, err)
-		} else {
-			log.Printf("removed invalid token: %s", tok)
-		}
+	kode := kodeErrorFCM(err)
+	log.Printf("FCM error for token %s: %s: %v", tok, kode, err)
+	if !tokenTidakBerlaku(kode) {
+		return
 	}
-	if s, ok := status.FromError(err); ok {
-		log.Printf("FCM grpc status: code=%v message=%q", s.Code(), s.Message())
+	if err := hapusTokenLokal(tok); err != nil {
+		log.Printf("failed delete device token %s: %v", tok, err)
+		return
 	}
+	log.Printf("removed invalid token: %s", tok)
 }
 
 var SendNotify = NotifyUsers
 
-func NotifyUsers(ctx context.Context, typeStr, title, body string, payload map[string]interface{}, userIDs []uint) error {
-	uniqMap := make(map[uint]struct{}, len(userIDs))
-	var recipients []uint
-	for _, id := range userIDs {
-		if id == 0 {
-			continue
-		}
-		if _, ok := uniqMap[id]; !ok {
-			uniqMap[id] = struct{}{}
-			recipients = append(recipients, id)
-		}
-	}
+func NotifyUsers(ctx context.Context, typeStr, title, body string, payload map[string]interface{}, to []models.Principal) error {
+	return NotifyUsersWithLampiran(ctx, typeStr, title, body, payload, to)
+}
+
+// NotifyUsersWithLampiran sama dengan NotifyUsers, ditambah lampiran yang
+// dikirim oleh channel yang mendukungnya (email).
+func NotifyUsersWithLampiran(ctx context.Context, typeStr, title, body string, payload map[string]interface{}, to []models.Principal, lampiran ...Lampiran) error {
+	return kirimLangkah(ctx, typeStr, title, body, payload, to, lampiran, map[string]bool{})
+}
 
+// kirimLangkah menyimpan notifikasi in-app lalu mengirim ke setiap channel.
+// Setiap langkah ("inapp", "digest" atau nama channel) yang berhasil untuk
+// seorang penerima dicatat di selesai dan dilewati pada pemanggilan
+// berikutnya, sehingga pengiriman ulang tidak menggandakan langkah yang
+// sudah sukses.
+func kirimLangkah(ctx context.Context, typeStr, title, body string, payload map[string]interface{}, to []models.Principal, lampiran []Lampiran, selesai map[string]bool) error {
+	recipients := unikPrincipal(to)
 	if len(recipients) == 0 {
 		return nil
 	}
@@ -140,46 +182,253 @@ func NotifyUsers(ctx context.Context, typeStr, title, body string, payload map[s
 		payloadBytes = []byte("{}")
 	}
 
-	now := time.Now()
-	notifs := make([]models.Notification, 0, len(recipients))
-	for _, rid := range recipients {
-		notifs = append(notifs, models.Notification{
-			Title:     title,
-			Body:      body,
-			Type:      typeStr,
-			Payload:   string(payloadBytes),
-			Recipient: rid,
-			Read:      false,
-			CreatedAt: now,
-			UpdatedAt: now,
-		})
-	}
-	if err := database.DB.Create(&notifs).Error; err != nil {
-		log.Printf("notify: gagal menyimpan notifications: %v", err)
-	}
-
-	var tokens []string
-	if err := database.DB.
-		Model(&models.DeviceToken{}).
-		Where("user_id IN ?", recipients).
-		Pluck("token", &tokens).Error; err != nil && err != gorm.ErrRecordNotFound {
-		log.Printf("notify: gagal ambil device tokens: %v", err)
-		return fmt.Errorf("gagal ambil device tokens: %w", err)
-	}
-
-	if len(tokens) == 0 {
-		return nil
+	pref := muatPreferensi(typeStr, recipients)
+
+	var firstErr error
+	notifIDs := notifIDsSebelumnya(ctx, recipients, selesai)
+	if belum := belumSelesai(selesai, "inapp", recipients); len(belum) > 0 {
+		now := time.Now()
+		outboxID := outboxIDDari(ctx)
+		notifs := make([]models.Notification, 0, len(belum))
+		for _, rp := range belum {
+			if !pref.izinkan(rp, "inapp") {
+				continue
+			}
+			notifs = append(notifs, models.Notification{
+				Title:         title,
+				Body:          body,
+				Type:          typeStr,
+				Payload:       string(payloadBytes),
+				OutboxID:      outboxID,
+				Recipient:     rp.ID,
+				RecipientType: rp.UserType,
+				Read:          false,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		}
+		if len(notifs) > 0 {
+			if err := database.DB.Create(&notifs).Error; err != nil {
+				log.Printf("notify: gagal menyimpan notifications: %v", err)
+				firstErr = fmt.Errorf("inapp: %w", err)
+			} else {
+				realtime.PublishNotifikasi(notifs)
+				for _, n := range notifs {
+					notifIDs[models.Principal{UserType: n.RecipientType, ID: n.Recipient}] = n.ID
+				}
+			}
+		}
+		if firstErr == nil {
+			tandaiSelesai(selesai, "inapp", belum)
+		}
+	}
+
+	// Penerima mode digest tetap mendapat baris inbox di atas, tetapi
+	// channel lain diganti satu ringkasan berkala (jobs digest).
+	var langsung, digest []models.Principal
+	for _, rp := range recipients {
+		if pref.masukDigest(rp) {
+			digest = append(digest, rp)
+		} else {
+			langsung = append(langsung, rp)
+		}
+	}
+	if belum := belumSelesai(selesai, "digest", digest); len(belum) > 0 {
+		rows := make([]models.NotifDigestAntrian, 0, len(belum))
+		for _, rp := range belum {
+			rows = append(rows, models.NotifDigestAntrian{
+				UserType: rp.UserType,
+				UserID:   rp.ID,
+				Type:     typeStr,
+				Title:    title,
+				Body:     body,
+			})
+		}
+		if err := database.DB.Create(&rows).Error; err != nil {
+			log.Printf("notify: gagal menyimpan antrian digest: %v", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("digest: %w", err)
+			}
+		} else {
+			tandaiSelesai(selesai, "digest", belum)
+		}
+	}
+	if len(langsung) == 0 {
+		return firstErr
 	}
 
-	dataMap := map[string]string{
-		"type":    typeStr,
-		"payload": string(payloadBytes),
+	err = dispatch(ctx, Pesan{
+		Type:        typeStr,
+		Title:       title,
+		Body:        body,
+		Payload:     payload,
+		PayloadJSON: string(payloadBytes),
+		Lampiran:    lampiran,
+		NotifIDs:    notifIDs,
+	}, langsung, pref, selesai)
+	if firstErr == nil {
+		firstErr = err
 	}
+	return firstErr
+}
 
-	if err := SendToTokens(ctx, title, body, dataMap, tokens); err != nil {
-		log.Printf("notify: SendToTokens error: %v", err)
-		return err
+// kunciLangkah adalah kunci selesai satu langkah untuk satu penerima. Bahasa
+// tidak ikut dalam kunci, sehingga penerima yang mengganti bahasa di antara
+// dua percobaan tidak menerima ulang langkah yang sudah berhasil.
+func kunciLangkah(langkah string, p models.Principal) string {
+	return fmt.Sprintf("%s/%s/%d", langkah, p.UserType, p.ID)
+}
+
+func belumSelesai(selesai map[string]bool, langkah string, to []models.Principal) []models.Principal {
+	hasil := make([]models.Principal, 0, len(to))
+	for _, p := range to {
+		if !selesai[kunciLangkah(langkah, p)] {
+			hasil = append(hasil, p)
+		}
 	}
+	return hasil
+}
 
-	return nil
+func tandaiSelesai(selesai map[string]bool, langkah string, to []models.Principal) {
+	for _, p := range to {
+		selesai[kunciLangkah(langkah, p)] = true
+	}
+}
+
+// notifIDsSebelumnya memuat baris inbox yang dibuat percobaan outbox
+// sebelumnya, supaya tanda terima push pada percobaan ulang tetap tertaut
+// ke inbox penerimanya.
+func notifIDsSebelumnya(ctx context.Context, recipients []models.Principal, selesai map[string]bool) map[models.Principal]uint {
+	hasil := map[models.Principal]uint{}
+	outboxID := outboxIDDari(ctx)
+	if outboxID == nil {
+		return hasil
+	}
+	var sudah []models.Principal
+	for _, p := range recipients {
+		if selesai[kunciLangkah("inapp", p)] {
+			sudah = append(sudah, p)
+		}
+	}
+	if len(sudah) == 0 {
+		return hasil
+	}
+
+	var rows []models.Notification
+	if err := database.DB.Select("id", "recipient", "recipient_type").
+		Where("outbox_id = ? AND (recipient_type, recipient) IN ?", *outboxID, pasanganPrincipal(sudah)).
+		Find(&rows).Error; err != nil {
+		log.Printf("notify: gagal memuat inbox outbox %d: %v", *outboxID, err)
+		return hasil
+	}
+	for _, n := range rows {
+		hasil[models.Principal{UserType: n.RecipientType, ID: n.Recipient}] = n.ID
+	}
+	return hasil
+}
+
+// unikPrincipal membuang principal duplikat dan yang tidak lengkap.
+func unikPrincipal(to []models.Principal) []models.Principal {
+	seen := make(map[models.Principal]struct{}, len(to))
+	hasil := make([]models.Principal, 0, len(to))
+	for _, p := range to {
+		if !p.Valid() {
+			continue
+		}
+		if _, ok := seen[p]; !ok {
+			seen[p] = struct{}{}
+			hasil = append(hasil, p)
+		}
+	}
+	return hasil
+}
+
+// pasanganPrincipal menyiapkan argumen untuk "(user_type, user_id) IN ?".
+func pasanganPrincipal(to []models.Principal) [][]interface{} {
+	hasil := make([][]interface{}, 0, len(to))
+	for _, p := range to {
+		hasil = append(hasil, []interface{}{p.UserType, p.ID})
+	}
+	return hasil
+}
+
+// dispatch mengirim pesan ke setiap channel sesuai routing type-nya.
+// Penerima yang mematikan channel tsb dilewati, dan push untuk penerima
+// yang sedang jam tenang ditahan. Kegagalan satu channel tidak
+// menghentikan channel lain.
+func dispatch(ctx context.Context, msg Pesan, recipients []models.Principal, pref *filterPreferensi, selesai map[string]bool) error {
+	route := routeFor(msg.Type)
+	role, _ := msg.Payload["penerima_role"].(string)
+	to, err := kontakPenerima(role, recipients)
+	if err != nil {
+		log.Printf("notify: %v", err)
+		to = make([]Penerima, 0, len(recipients))
+		for _, p := range recipients {
+			to = append(to, Penerima{UserID: p.ID, UserType: p.UserType, Role: role})
+		}
+	}
+
+	now := time.Now()
+	var firstErr error
+	for _, name := range route.Channels {
+		ch, ok := getChannel(name)
+		if !ok {
+			log.Printf("notify: channel %s belum terdaftar, dilewati", name)
+			continue
+		}
+
+		kirim := make([]Penerima, 0, len(to))
+		tahan := map[time.Time][]models.Principal{}
+		for _, p := range to {
+			if selesai[kunciLangkah(name, p.Principal())] {
+				continue
+			}
+			if !pref.izinkan(p.Principal(), name) {
+				continue
+			}
+			if name == "push" {
+				if sampai, ok := pref.tahanSampai(p.Principal(), now); ok {
+					tahan[sampai] = append(tahan[sampai], p.Principal())
+					continue
+				}
+			}
+			kirim = append(kirim, p)
+		}
+		for sampai, ids := range tahan {
+			if err := tahanPush(msg, ids, sampai); err != nil {
+				log.Printf("notify: gagal menahan push %s: %v", msg.Type, err)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", name, err)
+				}
+				continue
+			}
+			tandaiSelesai(selesai, name, ids)
+		}
+		if len(kirim) == 0 {
+			continue
+		}
+
+		err := ch.Send(ctx, msg, kirim)
+		if err != nil {
+			log.Printf("notify: channel %s gagal untuk type %s: %v", name, msg.Type, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		// Penerima yang sudah terkirim tetap ditandai meskipun sebagian
+		// lain gagal, agar percobaan ulang hanya mengirim sisanya.
+		var sebagian *GagalSebagian
+		if err != nil && !errors.As(err, &sebagian) {
+			continue
+		}
+		for _, p := range kirim {
+			if sebagian != nil {
+				if _, gagal := sebagian.Gagal[p.Principal()]; gagal {
+					continue
+				}
+			}
+			selesai[kunciLangkah(name, p.Principal())] = true
+		}
+	}
+	return firstErr
 }
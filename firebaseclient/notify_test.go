@@ -0,0 +1,216 @@
+package firebaseclient
+
+import (
+	"abs-be/models"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// multicastGagal meneruskan ke RecordingSender, tetapi panggilan multicast
+// ke-n (dimulai dari 1) yang terdaftar di gagalKe gagal seluruhnya.
+type multicastGagal struct {
+	*RecordingSender
+	gagalKe   map[int]bool
+	multicast []int
+}
+
+func (m *multicastGagal) SendEachForMulticast(ctx context.Context, msg *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	m.multicast = append(m.multicast, len(msg.Tokens))
+	if m.gagalKe[len(m.multicast)] {
+		return nil, errors.New("multicast tidak tersedia")
+	}
+	return m.RecordingSender.SendEachForMulticast(ctx, msg)
+}
+
+func tujuanUji(n int) []tujuanToken {
+	hasil := make([]tujuanToken, 0, n)
+	for i := 0; i < n; i++ {
+		id := uint(100 + i)
+		hasil = append(hasil, tujuanToken{
+			Token:     fmt.Sprintf("tok-%d", i),
+			Principal: models.Principal{UserType: models.UserTypeSiswa, ID: uint(i + 1)},
+			NotifID:   &id,
+		})
+	}
+	return hasil
+}
+
+func TestKirimMulticastPerChunk(t *testing.T) {
+	tests := []struct {
+		nama   string
+		jumlah int
+		want   []int
+	}{
+		{"kurang dari satu chunk", 3, []int{3}},
+		{"tepat satu chunk", multicastChunk, []int{multicastChunk}},
+		{"lebih dari dua chunk", 2*multicastChunk + 1, []int{multicastChunk, multicastChunk, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			rec := &multicastGagal{RecordingSender: NewRecordingSender()}
+			catat := &receiptBatch{sender: SenderRecording, typeStr: "pengumuman"}
+			data := map[string]string{"type": "pengumuman"}
+
+			gagal := kirimMulticast(context.Background(), rec, "Libur", "Besok libur", data, tujuanUji(tt.jumlah), catat)
+			if gagal.hasil() != nil {
+				t.Errorf("gagal = %v, want nil", gagal.Gagal)
+			}
+
+			if !reflect.DeepEqual(rec.multicast, tt.want) {
+				t.Errorf("ukuran multicast = %v, want %v", rec.multicast, tt.want)
+			}
+			if n := len(rec.Pesan()); n != tt.jumlah {
+				t.Errorf("pesan terkirim = %d, want %d", n, tt.jumlah)
+			}
+			if len(catat.rows) != tt.jumlah {
+				t.Fatalf("receipt = %d baris, want %d", len(catat.rows), tt.jumlah)
+			}
+			for i, r := range catat.rows {
+				if !r.Success || r.MessageID == "" {
+					t.Errorf("receipt %d = %+v, want sukses dengan message id", i, r)
+				}
+			}
+		})
+	}
+}
+
+func TestKirimMulticastFallbackPerToken(t *testing.T) {
+	rec := &multicastGagal{RecordingSender: NewRecordingSender(), gagalKe: map[int]bool{2: true}}
+	tujuan := tujuanUji(multicastChunk + 2)
+	rec.Gagalkan(tujuan[multicastChunk].Token, KodeQuotaExceeded)
+	catat := &receiptBatch{sender: SenderRecording, typeStr: "pengumuman"}
+
+	gagal := kirimMulticast(context.Background(), rec, "Libur", "Besok libur", map[string]string{"type": "pengumuman"}, tujuan, catat)
+	if len(gagal.Gagal) != 1 || gagal.Gagal[tujuan[multicastChunk].Principal] == nil {
+		t.Errorf("gagal = %v, want hanya pemilik %s", gagal.Gagal, tujuan[multicastChunk].Token)
+	}
+
+	// Chunk kedua gagal sebagai multicast lalu dikirim satu per satu.
+	if !reflect.DeepEqual(rec.multicast, []int{multicastChunk, 2}) {
+		t.Errorf("ukuran multicast = %v, want [%d 2]", rec.multicast, multicastChunk)
+	}
+	pesan := rec.Pesan()
+	if len(pesan) != len(tujuan)-1 {
+		t.Fatalf("pesan terkirim = %d, want %d", len(pesan), len(tujuan)-1)
+	}
+	terakhir := pesan[len(pesan)-1]
+	if terakhir.Token != tujuan[multicastChunk+1].Token || terakhir.Notification.Title != "Libur" || terakhir.Data["type"] != "pengumuman" {
+		t.Errorf("pesan fallback = %+v", terakhir)
+	}
+
+	if len(catat.rows) != len(tujuan) {
+		t.Fatalf("receipt = %d baris, want %d", len(catat.rows), len(tujuan))
+	}
+	if r := catat.rows[multicastChunk]; r.Success || r.ErrorCode != KodeQuotaExceeded || r.Token != tujuan[multicastChunk].Token {
+		t.Errorf("receipt token gagal = %+v", r)
+	}
+	if r := catat.rows[multicastChunk+1]; !r.Success || r.MessageID == "" {
+		t.Errorf("receipt fallback sukses = %+v", r)
+	}
+}
+
+func TestReceiptPerToken(t *testing.T) {
+	rec := NewRecordingSender()
+	tujuan := tujuanUji(3)
+	tujuan[2].NotifID = nil
+	rec.Gagalkan(tujuan[1].Token, KodeInvalidArgument)
+	outbox := uint(42)
+	catat := pencatatReceipt(DenganOutboxID(context.Background(), outbox), SenderRecording, "izin_siswa")
+
+	// Token yang ditolak permanen tidak perlu dikirim ulang.
+	if gagal := kirimMulticast(context.Background(), rec, "Izin", "Izin disetujui", map[string]string{"type": "izin_siswa"}, tujuan, catat); gagal.hasil() != nil {
+		t.Errorf("gagal = %v, want nil untuk %s", gagal.Gagal, KodeInvalidArgument)
+	}
+
+	want := []struct {
+		sukses  bool
+		kode    string
+		notifID *uint
+	}{
+		{true, "", tujuan[0].NotifID},
+		{false, KodeInvalidArgument, tujuan[1].NotifID},
+		{true, "", nil},
+	}
+	if len(catat.rows) != len(want) {
+		t.Fatalf("receipt = %d baris, want %d", len(catat.rows), len(want))
+	}
+	for i, w := range want {
+		r := catat.rows[i]
+		if r.Success != w.sukses || r.ErrorCode != w.kode {
+			t.Errorf("receipt %d: success=%v kode=%q, want %v %q", i, r.Success, r.ErrorCode, w.sukses, w.kode)
+		}
+		if r.OutboxID == nil || *r.OutboxID != outbox {
+			t.Errorf("receipt %d: outbox_id = %v, want %d", i, r.OutboxID, outbox)
+		}
+		if !reflect.DeepEqual(r.NotificationID, w.notifID) {
+			t.Errorf("receipt %d: notification_id = %v, want %v", i, r.NotificationID, w.notifID)
+		}
+		if r.Token != tujuan[i].Token || r.UserType != tujuan[i].Principal.UserType || r.UserID != tujuan[i].Principal.ID {
+			t.Errorf("receipt %d: token/pemilik = %s %s/%d", i, r.Token, r.UserType, r.UserID)
+		}
+		if r.Type != "izin_siswa" || r.Sender != SenderRecording {
+			t.Errorf("receipt %d: type/sender = %s/%s", i, r.Type, r.Sender)
+		}
+		if w.sukses == (r.MessageID == "") {
+			t.Errorf("receipt %d: message_id = %q", i, r.MessageID)
+		}
+	}
+}
+
+func TestKirimKeTokensGagalSementara(t *testing.T) {
+	lamaSender, lamaNama := currentSender()
+	t.Cleanup(func() { SetSender(lamaNama, lamaSender) })
+	rec := NewRecordingSender()
+	SetSender(SenderRecording, rec)
+
+	tujuan := tujuanUji(4)
+	tujuan[3].Principal = tujuan[0].Principal
+	rec.Gagalkan(tujuan[0].Token, KodeUnavailable)
+	rec.Gagalkan(tujuan[1].Token, KodeInternal)
+	rec.Gagalkan(tujuan[2].Token, KodeInvalidArgument)
+
+	err := kirimKeTokens(context.Background(), "Libur", "Besok libur", map[string]string{"type": "pengumuman"}, tujuan)
+	var sebagian *GagalSebagian
+	if !errors.As(err, &sebagian) {
+		t.Fatalf("kirimKeTokens() error = %v, want *GagalSebagian", err)
+	}
+	// Pemilik tok-0 tetap gagal walaupun token keduanya (tok-3) terkirim;
+	// token yang ditolak INVALID_ARGUMENT tidak dihitung.
+	want := []models.Principal{tujuan[0].Principal, tujuan[1].Principal}
+	if len(sebagian.Gagal) != len(want) {
+		t.Fatalf("penerima gagal = %v, want %v", sebagian.Gagal, want)
+	}
+	for _, p := range want {
+		if sebagian.Gagal[p] == nil {
+			t.Errorf("%v tidak dilaporkan gagal", p)
+		}
+	}
+}
+
+func TestSendToTokens(t *testing.T) {
+	lamaSender, lamaNama := currentSender()
+	t.Cleanup(func() { SetSender(lamaNama, lamaSender) })
+
+	SetSender("", nil)
+	if err := SendToTokens(context.Background(), "a", "b", nil, []string{"x"}); err == nil {
+		t.Error("SendToTokens tanpa sender seharusnya gagal")
+	}
+
+	rec := NewRecordingSender()
+	SetSender(SenderRecording, rec)
+	if err := SendToTokens(context.Background(), "Halo", "Isi", map[string]string{"type": "uji"}, []string{"x", "y"}); err != nil {
+		t.Fatalf("SendToTokens: %v", err)
+	}
+	var tokens []string
+	for _, m := range rec.Pesan() {
+		tokens = append(tokens, m.Token)
+	}
+	if !reflect.DeepEqual(tokens, []string{"x", "y"}) {
+		t.Errorf("token terkirim = %v, want [x y]", tokens)
+	}
+}
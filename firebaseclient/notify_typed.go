@@ -0,0 +1,68 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"context"
+	"fmt"
+	"log"
+)
+
+// Notify merender payload dengan template terdaftar sesuai bahasa tiap
+// penerima lalu mengirimkannya. role (mis. "wali_kelas") menentukan jenis
+// user penerima dan mengisi "penerima_role".
+func Notify(ctx context.Context, role string, p notifikasi.Payload, userIDs []uint, lampiran ...Lampiran) error {
+	return Kirim(ctx, role, p, userIDs, map[string]bool{}, lampiran...)
+}
+
+// Kirim sama dengan Notify, tetapi mencatat langkah yang sudah berhasil per
+// penerima ("inapp/guru/5", "push/guru/5", ...) di selesai dan melewatinya.
+// Dipakai outbox supaya percobaan ulang hanya mengulang langkah yang gagal.
+func Kirim(ctx context.Context, role string, p notifikasi.Payload, userIDs []uint, selesai map[string]bool, lampiran ...Lampiran) error {
+	if models.UserTypeDariRole(role) == "" {
+		return fmt.Errorf("%s: role penerima %q tidak dikenal", p.Type(), role)
+	}
+	perBahasa := kelompokkanBahasa(models.PrincipalsDariRole(role, userIDs))
+
+	payload := notifikasi.ToMap(p)
+	payload["penerima_role"] = role
+
+	var firstErr error
+	for bahasa, to := range perBahasa {
+		title, body, err := notifikasi.Render(p, bahasa)
+		if err != nil {
+			return err
+		}
+		if err := kirimLangkah(ctx, p.Type(), title, body, payload, to, lampiran, selesai); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s (%s): %w", p.Type(), bahasa, err)
+		}
+	}
+	return firstErr
+}
+
+func kelompokkanBahasa(to []models.Principal) map[string][]models.Principal {
+	def := notifikasi.BahasaDefault()
+	hasil := map[string][]models.Principal{}
+	to = unikPrincipal(to)
+	if len(to) == 0 {
+		return hasil
+	}
+
+	var rows []models.NotifPengaturan
+	if err := database.DB.Where("(user_type, user_id) IN ?", pasanganPrincipal(to)).Find(&rows).Error; err != nil {
+		log.Printf("notify: gagal ambil bahasa penerima: %v", err)
+	}
+	bahasa := make(map[models.Principal]string, len(rows))
+	for _, r := range rows {
+		bahasa[models.Principal{UserType: r.UserType, ID: r.UserID}] = r.Bahasa
+	}
+	for _, p := range to {
+		b := bahasa[p]
+		if !notifikasi.BahasaValid(b) {
+			b = def
+		}
+		hasil[b] = append(hasil[b], p)
+	}
+	return hasil
+}
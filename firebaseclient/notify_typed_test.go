@@ -0,0 +1,46 @@
+package firebaseclient
+
+import (
+	"abs-be/models"
+	"context"
+	"testing"
+)
+
+func TestDispatchUlangPerPenerima(t *testing.T) {
+	pulihkanNotif(t)
+	logCh, path := logChannelUji(t)
+	RegisterChannel(logCh.As("email"))
+	t.Setenv("NOTIF_ROUTING", `{"pengumuman": {"channels": ["email"]}}`)
+	if err := loadRouting(); err != nil {
+		t.Fatalf("loadRouting: %v", err)
+	}
+
+	// Penerima 1 sudah dikirimi pada percobaan sebelumnya, mungkin dalam
+	// kelompok bahasa lain; hanya penerima 2 yang dikirim ulang.
+	sudah := models.Principal{UserType: models.UserTypeGuru, ID: 1}
+	baru := models.Principal{UserType: models.UserTypeGuru, ID: 2}
+	selesai := map[string]bool{kunciLangkah("email", sudah): true}
+	msg := Pesan{Type: "pengumuman", Title: "Announcement"}
+	if err := dispatch(context.Background(), msg, []models.Principal{sudah, baru}, nil, selesai); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	baris := bacaLog(t, path)
+	if len(baris) != 1 {
+		t.Fatalf("email terkirim ke %d penerima, want 1", len(baris))
+	}
+	if got := baris[0]["penerima"].(map[string]interface{})["user_id"]; got != float64(baru.ID) {
+		t.Errorf("email terkirim ke %v, want user %d", got, baru.ID)
+	}
+	if !selesai[kunciLangkah("email", baru)] {
+		t.Errorf("langkah email penerima %d tidak dicatat: %v", baru.ID, selesai)
+	}
+}
+
+func TestNotifIDsSebelumnyaTanpaOutbox(t *testing.T) {
+	to := []models.Principal{{UserType: models.UserTypeGuru, ID: 1}}
+	selesai := map[string]bool{kunciLangkah("inapp", to[0]): true}
+	if got := notifIDsSebelumnya(context.Background(), to, selesai); len(got) != 0 {
+		t.Errorf("notifIDsSebelumnya tanpa outbox = %v, want kosong", got)
+	}
+}
@@ -0,0 +1,202 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ChannelPreferensi adalah channel yang bisa dimatikan user. "inapp" berarti
+// baris di tabel notifications; "semua" mematikan/menyalakan seluruhnya.
+var ChannelPreferensi = []string{"inapp", "push", "email", "sms", "whatsapp"}
+
+const ChannelSemua = "semua"
+
+func ChannelPreferensiValid(ch string) bool {
+	if ch == ChannelSemua {
+		return true
+	}
+	for _, c := range ChannelPreferensi {
+		if c == ch {
+			return true
+		}
+	}
+	return false
+}
+
+func IsTypeWajib(typeStr string) (bool, error) {
+	var n int64
+	if err := database.DB.Model(&models.NotifTipeWajib{}).Where("type = ?", typeStr).Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// filterPreferensi memuat preferensi dan jam tenang semua penerima satu
+// notifikasi sekaligus.
+type filterPreferensi struct {
+	wajib  bool
+	pref   map[models.Principal]map[string]bool
+	tenang map[models.Principal]models.NotifJamTenang
+	digest map[models.Principal]bool
+}
+
+func muatPreferensi(typeStr string, to []models.Principal) *filterPreferensi {
+	f := &filterPreferensi{
+		pref:   map[models.Principal]map[string]bool{},
+		tenang: map[models.Principal]models.NotifJamTenang{},
+		digest: map[models.Principal]bool{},
+	}
+	wajib, err := IsTypeWajib(typeStr)
+	if err != nil {
+		log.Printf("notify: gagal cek type wajib %s: %v", typeStr, err)
+	}
+	f.wajib = wajib
+	if wajib || len(to) == 0 {
+		return f
+	}
+	pasangan := pasanganPrincipal(to)
+
+	var prefs []models.NotifPreferensi
+	if err := database.DB.Where("type = ? AND (user_type, user_id) IN ?", typeStr, pasangan).Find(&prefs).Error; err != nil {
+		log.Printf("notify: gagal ambil preferensi: %v", err)
+	}
+	for _, p := range prefs {
+		k := models.Principal{UserType: p.UserType, ID: p.UserID}
+		if f.pref[k] == nil {
+			f.pref[k] = map[string]bool{}
+		}
+		f.pref[k][p.Channel] = p.Aktif
+	}
+
+	var jam []models.NotifJamTenang
+	if err := database.DB.Where("aktif = ? AND (user_type, user_id) IN ?", true, pasangan).Find(&jam).Error; err != nil {
+		log.Printf("notify: gagal ambil jam tenang: %v", err)
+	}
+	for _, j := range jam {
+		f.tenang[models.Principal{UserType: j.UserType, ID: j.UserID}] = j
+	}
+
+	var digest []models.NotifDigestType
+	if err := database.DB.Where("type = ? AND (user_type, user_id) IN ?", typeStr, pasangan).Find(&digest).Error; err != nil {
+		log.Printf("notify: gagal ambil pilihan digest: %v", err)
+	}
+	for _, d := range digest {
+		f.digest[models.Principal{UserType: d.UserType, ID: d.UserID}] = true
+	}
+	return f
+}
+
+// masukDigest bernilai true bila penerima memilih type ini dikirim lewat
+// ringkasan, bukan satu per satu.
+func (f *filterPreferensi) masukDigest(penerima models.Principal) bool {
+	if f == nil || f.wajib {
+		return false
+	}
+	return f.digest[penerima]
+}
+
+func (f *filterPreferensi) izinkan(penerima models.Principal, channel string) bool {
+	if f == nil || f.wajib {
+		return true
+	}
+	p := f.pref[penerima]
+	if aktif, ok := p[channel]; ok {
+		return aktif
+	}
+	if aktif, ok := p[ChannelSemua]; ok {
+		return aktif
+	}
+	return true
+}
+
+// tahanSampai mengembalikan akhir jam tenang bila now berada di dalamnya.
+func (f *filterPreferensi) tahanSampai(penerima models.Principal, now time.Time) (time.Time, bool) {
+	if f == nil || f.wajib {
+		return time.Time{}, false
+	}
+	j, ok := f.tenang[penerima]
+	if !ok {
+		return time.Time{}, false
+	}
+	return AkhirJamTenang(j.Mulai, j.Selesai, now)
+}
+
+// AkhirJamTenang menghitung akhir rentang [mulai, selesai) yang sedang
+// berlangsung pada now. Rentang boleh melewati tengah malam (22:00-06:00).
+func AkhirJamTenang(mulai, selesai string, now time.Time) (time.Time, bool) {
+	m, err1 := time.Parse("15:04", mulai)
+	s, err2 := time.Parse("15:04", selesai)
+	if err1 != nil || err2 != nil || mulai == selesai {
+		return time.Time{}, false
+	}
+	now = now.In(time.Local)
+	menit := now.Hour()*60 + now.Minute()
+	mMenit := m.Hour()*60 + m.Minute()
+	sMenit := s.Hour()*60 + s.Minute()
+	akhirHariIni := time.Date(now.Year(), now.Month(), now.Day(), s.Hour(), s.Minute(), 0, 0, time.Local)
+
+	if mMenit < sMenit {
+		if menit >= mMenit && menit < sMenit {
+			return akhirHariIni, true
+		}
+		return time.Time{}, false
+	}
+	if menit < sMenit {
+		return akhirHariIni, true
+	}
+	if menit >= mMenit {
+		return akhirHariIni.AddDate(0, 0, 1), true
+	}
+	return time.Time{}, false
+}
+
+func tahanPush(msg Pesan, to []models.Principal, sampai time.Time) error {
+	rows := make([]models.NotifPushTertunda, 0, len(to))
+	for _, p := range to {
+		rows = append(rows, models.NotifPushTertunda{
+			UserType:     p.UserType,
+			UserID:       p.ID,
+			Type:         msg.Type,
+			Title:        msg.Title,
+			Body:         msg.Body,
+			Payload:      msg.PayloadJSON,
+			KirimSetelah: sampai,
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return database.DB.Create(&rows).Error
+}
+
+// KirimPushTertunda mengirim push yang jam tenangnya sudah berakhir.
+func KirimPushTertunda(ctx context.Context, now time.Time) error {
+	var rows []models.NotifPushTertunda
+	if err := database.DB.
+		Where("sent_at IS NULL AND kirim_setelah <= ?", now).
+		Order("id").Limit(500).
+		Find(&rows).Error; err != nil {
+		return fmt.Errorf("ambil push tertunda: %w", err)
+	}
+
+	push, ok := getChannel("push")
+	if !ok {
+		return fmt.Errorf("channel push belum terdaftar")
+	}
+	for _, r := range rows {
+		msg := Pesan{Type: r.Type, Title: r.Title, Body: r.Body, PayloadJSON: r.Payload}
+		if err := push.Send(ctx, msg, []Penerima{{UserID: r.UserID, UserType: r.UserType}}); err != nil {
+			// Dibiarkan belum terkirim agar diulang pada putaran berikutnya.
+			log.Printf("notify: push tertunda %d gagal: %v", r.ID, err)
+			continue
+		}
+		if err := database.DB.Model(&r).Update("sent_at", now).Error; err != nil {
+			return fmt.Errorf("tandai push tertunda: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"context"
+	"log"
+)
+
+type ctxKey int
+
+const ctxOutboxID ctxKey = iota
+
+// DenganOutboxID menandai ctx dengan id outbox yang sedang dikirim agar
+// tanda terima pengirimannya bisa ditelusuri dari outbox.
+func DenganOutboxID(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, ctxOutboxID, id)
+}
+
+func outboxIDDari(ctx context.Context) *uint {
+	if id, ok := ctx.Value(ctxOutboxID).(uint); ok && id != 0 {
+		return &id
+	}
+	return nil
+}
+
+// tujuanToken adalah satu device token beserta pemilik dan baris inbox
+// notifikasinya, bila diketahui.
+type tujuanToken struct {
+	Token     string
+	Principal models.Principal
+	NotifID   *uint
+}
+
+// receiptBatch mengumpulkan tanda terima satu pengiriman lalu menyimpannya
+// sekaligus.
+type receiptBatch struct {
+	outboxID *uint
+	sender   string
+	typeStr  string
+	rows     []models.NotifDeliveryReceipt
+}
+
+func pencatatReceipt(ctx context.Context, sender, typeStr string) *receiptBatch {
+	return &receiptBatch{outboxID: outboxIDDari(ctx), sender: sender, typeStr: typeStr}
+}
+
+func (b *receiptBatch) token(t tujuanToken, messageID string, err error) {
+	b.tambah(models.NotifDeliveryReceipt{
+		NotificationID: t.NotifID,
+		UserType:       t.Principal.UserType,
+		UserID:         t.Principal.ID,
+		Token:          t.Token,
+		MessageID:      messageID,
+	}, err)
+}
+
+func (b *receiptBatch) topic(topic, messageID string, err error) {
+	b.tambah(models.NotifDeliveryReceipt{Topic: topic, MessageID: messageID}, err)
+}
+
+func (b *receiptBatch) tambah(r models.NotifDeliveryReceipt, err error) {
+	r.OutboxID = b.outboxID
+	r.Type = b.typeStr
+	r.Sender = b.sender
+	r.Success = err == nil
+	if err != nil {
+		r.ErrorCode = kodeErrorFCM(err)
+		r.ErrorMessage = err.Error()
+	}
+	b.rows = append(b.rows, r)
+}
+
+// simpan menulis tanda terima; kegagalan hanya dicatat agar tidak
+// mengulang pengiriman yang sudah terjadi.
+func (b *receiptBatch) simpan() {
+	if len(b.rows) == 0 || database.DB == nil {
+		return
+	}
+	if err := database.DB.CreateInBatches(&b.rows, 500).Error; err != nil {
+		log.Printf("notify: gagal menyimpan %d tanda terima: %v", len(b.rows), err)
+	}
+	b.rows = nil
+}
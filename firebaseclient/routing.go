@@ -0,0 +1,138 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Route menentukan channel yang dipakai sebuah type notifikasi.
+type Route struct {
+	Channels []string `json:"channels"`
+}
+
+var (
+	routingMu sync.RWMutex
+	routing   = defaultRouting()
+)
+
+// loadRouting membaca konfigurasi dari NOTIF_ROUTING (JSON) atau
+// NOTIF_ROUTING_FILE. Contoh:
+//
+//	{"default": {"channels": ["push"]},
+//	 "absensi_siswa_ortu": {"channels": ["push", "whatsapp"]}}
+func loadRouting() error {
+	raw := []byte(os.Getenv("NOTIF_ROUTING"))
+	if len(raw) == 0 {
+		if path := os.Getenv("NOTIF_ROUTING_FILE"); path != "" {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("baca NOTIF_ROUTING_FILE: %w", err)
+			}
+			raw = b
+		}
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	cfg := map[string]Route{}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("routing notifikasi tidak valid: %w", err)
+	}
+	for typ, r := range cfg {
+		for _, name := range r.Channels {
+			if _, ok := getChannel(name); !ok {
+				return fmt.Errorf("routing %s: channel %q tidak dikenal", typ, name)
+			}
+		}
+	}
+	for typ, r := range defaultRouting() {
+		if _, ok := cfg[typ]; !ok {
+			cfg[typ] = r
+		}
+	}
+
+	routingMu.Lock()
+	routing = cfg
+	routingMu.Unlock()
+	return nil
+}
+
+func defaultRouting() map[string]Route {
+	pushEmail := []string{"push", "email"}
+	return map[string]Route{
+		"default":            {Channels: []string{"push"}},
+		"create_guru":        {Channels: pushEmail},
+		"create_siswa":       {Channels: pushEmail},
+		"export_rekap_mapel": {Channels: pushEmail},
+		"export_rekap_kelas": {Channels: pushEmail},
+	}
+}
+
+func routeFor(typeStr string) Route {
+	routingMu.RLock()
+	defer routingMu.RUnlock()
+	if r, ok := routing[typeStr]; ok {
+		return r
+	}
+	return routing["default"]
+}
+
+// kontakPenerima melengkapi data kontak dari tabel sesuai jenis user tiap
+// principal. role hanya dicatat di Penerima.Role; bila kosong dipakai
+// jenis usernya.
+func kontakPenerima(role string, to []models.Principal) ([]Penerima, error) {
+	if database.DB == nil {
+		return nil, fmt.Errorf("ambil kontak: database belum terhubung")
+	}
+	perJenis := map[string][]uint{}
+	for _, p := range to {
+		perJenis[p.UserType] = append(perJenis[p.UserType], p.ID)
+	}
+
+	hasil := make([]Penerima, 0, len(to))
+	for jenis, ids := range perJenis {
+		r := role
+		if r == "" {
+			r = jenis
+		}
+		tabel := map[string]string{
+			models.UserTypeAdmin:    "admins",
+			models.UserTypeGuru:     "gurus",
+			models.UserTypeSiswa:    "siswas",
+			models.UserTypeOrangTua: "orang_tuas",
+		}[jenis]
+		if tabel == "" {
+			return nil, fmt.Errorf("jenis user %q tidak dikenal", jenis)
+		}
+
+		kolom := "id, nama, email, telepon"
+		if tabel == "admins" {
+			kolom = "id, nama, email, '' AS telepon"
+		}
+		var rows []struct {
+			ID      uint
+			Nama    string
+			Email   string
+			Telepon string
+		}
+		if err := database.DB.Table(tabel).Select(kolom).Where("id IN ?", ids).Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("ambil kontak %s: %w", jenis, err)
+		}
+		for _, row := range rows {
+			hasil = append(hasil, Penerima{
+				UserID:   row.ID,
+				UserType: jenis,
+				Role:     r,
+				Nama:     row.Nama,
+				Email:    row.Email,
+				Telepon:  row.Telepon,
+			})
+		}
+	}
+	return hasil, nil
+}
@@ -0,0 +1,301 @@
+package firebaseclient
+
+import (
+	"abs-be/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// channelGagal selalu gagal dan menghitung berapa kali dipanggil.
+type channelGagal struct {
+	name  string
+	kirim int
+}
+
+func (c *channelGagal) Name() string { return c.name }
+
+func (c *channelGagal) Send(ctx context.Context, msg Pesan, to []Penerima) error {
+	c.kirim++
+	return errors.New("provider tidak tersedia")
+}
+
+// channelSebagian gagal hanya untuk penerima di gagal.
+type channelSebagian struct {
+	name    string
+	gagal   map[models.Principal]bool
+	dikirim []models.Principal
+}
+
+func (c *channelSebagian) Name() string { return c.name }
+
+func (c *channelSebagian) Send(ctx context.Context, msg Pesan, to []Penerima) error {
+	hasil := &GagalSebagian{}
+	for _, p := range to {
+		c.dikirim = append(c.dikirim, p.Principal())
+		if c.gagal[p.Principal()] {
+			hasil.tambah(p.Principal(), errors.New("gateway menolak"))
+		}
+	}
+	return hasil.hasil()
+}
+
+// pulihkanNotif mengembalikan channel dan routing global setelah test.
+func pulihkanNotif(t *testing.T) {
+	t.Helper()
+	channelMu.Lock()
+	simpanCh := make(map[string]Channel, len(channels))
+	for k, v := range channels {
+		simpanCh[k] = v
+	}
+	channelMu.Unlock()
+	routingMu.RLock()
+	simpanRoute := routing
+	routingMu.RUnlock()
+
+	t.Cleanup(func() {
+		channelMu.Lock()
+		channels = simpanCh
+		channelMu.Unlock()
+		routingMu.Lock()
+		routing = simpanRoute
+		routingMu.Unlock()
+	})
+}
+
+func logChannelUji(t *testing.T) (*LogChannel, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notif.log")
+	ch, err := NewLogChannel("log", path)
+	if err != nil {
+		t.Fatalf("NewLogChannel: %v", err)
+	}
+	return ch, path
+}
+
+func bacaLog(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("buka log: %v", err)
+	}
+	defer f.Close()
+	var hasil []map[string]interface{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var baris map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &baris); err != nil {
+			t.Fatalf("baris log bukan JSON: %v", err)
+		}
+		hasil = append(hasil, baris)
+	}
+	return hasil
+}
+
+func TestLoadRoutingPerType(t *testing.T) {
+	pulihkanNotif(t)
+	logCh, _ := logChannelUji(t)
+	RegisterChannel(logCh.As("whatsapp"))
+	RegisterChannel(logCh.As("email"))
+
+	t.Setenv("NOTIF_ROUTING", `{
+		"default": {"channels": ["push"]},
+		"absensi_siswa_ortu": {"channels": ["push", "whatsapp"]},
+		"create_guru": {"channels": ["email"]}
+	}`)
+	if err := loadRouting(); err != nil {
+		t.Fatalf("loadRouting: %v", err)
+	}
+
+	tests := []struct {
+		typ  string
+		want []string
+	}{
+		{"absensi_siswa_ortu", []string{"push", "whatsapp"}},
+		{"create_guru", []string{"email"}},
+		{"create_siswa", []string{"push", "email"}},
+		{"tidak_ada", []string{"push"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			if got := routeFor(tt.typ).Channels; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("routeFor(%q) = %v, want %v", tt.typ, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRoutingFile(t *testing.T) {
+	pulihkanNotif(t)
+	logCh, _ := logChannelUji(t)
+	RegisterChannel(logCh.As("sms"))
+
+	path := filepath.Join(t.TempDir(), "routing.json")
+	if err := os.WriteFile(path, []byte(`{"izin_siswa": {"channels": ["sms"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOTIF_ROUTING", "")
+	t.Setenv("NOTIF_ROUTING_FILE", path)
+	if err := loadRouting(); err != nil {
+		t.Fatalf("loadRouting: %v", err)
+	}
+	if got := routeFor("izin_siswa").Channels; !reflect.DeepEqual(got, []string{"sms"}) {
+		t.Errorf("routeFor(izin_siswa) = %v, want [sms]", got)
+	}
+	if got := routeFor("lain").Channels; !reflect.DeepEqual(got, []string{"push"}) {
+		t.Errorf("default tidak dipertahankan: %v", got)
+	}
+}
+
+func TestLoadRoutingTidakValid(t *testing.T) {
+	tests := []struct {
+		nama  string
+		cfg   string
+		pesan string
+	}{
+		{"channel tidak dikenal", `{"izin_siswa": {"channels": ["push", "fax"]}}`, `channel "fax" tidak dikenal`},
+		{"json rusak", `{"izin_siswa": `, "routing notifikasi tidak valid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			pulihkanNotif(t)
+			sebelum := routeFor("izin_siswa").Channels
+
+			t.Setenv("NOTIF_ROUTING", tt.cfg)
+			err := loadRouting()
+			if err == nil || !strings.Contains(err.Error(), tt.pesan) {
+				t.Fatalf("loadRouting() error = %v, want berisi %q", err, tt.pesan)
+			}
+			if got := routeFor("izin_siswa").Channels; !reflect.DeepEqual(got, sebelum) {
+				t.Errorf("routing berubah setelah konfigurasi gagal: %v", got)
+			}
+		})
+	}
+}
+
+func TestDispatchChannelGagalTidakMenghentikanLainnya(t *testing.T) {
+	pulihkanNotif(t)
+	logCh, path := logChannelUji(t)
+	gagal := &channelGagal{name: "sms"}
+	RegisterChannel(gagal)
+	RegisterChannel(logCh.As("email"))
+
+	t.Setenv("NOTIF_ROUTING", `{"pengumuman": {"channels": ["sms", "email", "fax_belum_ada"]}}`)
+	RegisterChannel(logCh.As("fax_belum_ada"))
+	if err := loadRouting(); err != nil {
+		t.Fatalf("loadRouting: %v", err)
+	}
+	// Channel yang terdaftar saat konfigurasi dimuat lalu hilang hanya
+	// dilewati saat dispatch.
+	channelMu.Lock()
+	delete(channels, "fax_belum_ada")
+	channelMu.Unlock()
+
+	to := []models.Principal{
+		{UserType: models.UserTypeGuru, ID: 1},
+		{UserType: models.UserTypeSiswa, ID: 2},
+	}
+	msg := Pesan{Type: "pengumuman", Title: "Libur", Body: "Besok libur", Payload: map[string]interface{}{"penerima_role": "guru"}}
+	selesai := map[string]bool{}
+
+	err := dispatch(context.Background(), msg, to, nil, selesai)
+	if err == nil || !strings.HasPrefix(err.Error(), "sms: ") {
+		t.Fatalf("dispatch() error = %v, want error dari channel sms", err)
+	}
+	want := map[string]bool{"email/guru/1": true, "email/siswa/2": true}
+	if !reflect.DeepEqual(selesai, want) {
+		t.Errorf("selesai = %v, want %v", selesai, want)
+	}
+
+	baris := bacaLog(t, path)
+	if len(baris) != len(to) {
+		t.Fatalf("email terkirim ke %d penerima, want %d", len(baris), len(to))
+	}
+	for _, b := range baris {
+		if b["channel"] != "email" || b["title"] != "Libur" {
+			t.Errorf("baris log tidak sesuai: %v", b)
+		}
+	}
+
+	// Percobaan ulang hanya mengulang channel yang gagal.
+	if err := dispatch(context.Background(), msg, to, nil, selesai); err == nil {
+		t.Fatal("dispatch ulang seharusnya tetap gagal di sms")
+	}
+	if gagal.kirim != 2 {
+		t.Errorf("sms dipanggil %d kali, want 2", gagal.kirim)
+	}
+	if n := len(bacaLog(t, path)); n != len(to) {
+		t.Errorf("email dikirim ulang: %d baris, want %d", n, len(to))
+	}
+}
+
+func TestDispatchMenghormatiPreferensi(t *testing.T) {
+	pulihkanNotif(t)
+	logCh, path := logChannelUji(t)
+	RegisterChannel(logCh.As("email"))
+	t.Setenv("NOTIF_ROUTING", `{"pengumuman": {"channels": ["email"]}}`)
+	if err := loadRouting(); err != nil {
+		t.Fatalf("loadRouting: %v", err)
+	}
+
+	mati := models.Principal{UserType: models.UserTypeGuru, ID: 1}
+	aktif := models.Principal{UserType: models.UserTypeGuru, ID: 2}
+	pref := &filterPreferensi{
+		pref: map[models.Principal]map[string]bool{mati: {"email": false}},
+	}
+	msg := Pesan{Type: "pengumuman", Title: "Rapat"}
+	if err := dispatch(context.Background(), msg, []models.Principal{mati, aktif}, pref, map[string]bool{}); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	baris := bacaLog(t, path)
+	if len(baris) != 1 {
+		t.Fatalf("email terkirim ke %d penerima, want 1", len(baris))
+	}
+	penerima := baris[0]["penerima"].(map[string]interface{})
+	if penerima["user_id"] != float64(aktif.ID) {
+		t.Errorf("email terkirim ke %v, want user %d", penerima["user_id"], aktif.ID)
+	}
+}
+
+func TestDispatchGagalSebagianHanyaMengulangYangGagal(t *testing.T) {
+	pulihkanNotif(t)
+	gagal := models.Principal{UserType: models.UserTypeOrangTua, ID: 2}
+	sms := &channelSebagian{name: "sms", gagal: map[models.Principal]bool{gagal: true}}
+	RegisterChannel(sms)
+	t.Setenv("NOTIF_ROUTING", `{"pengumuman": {"channels": ["sms"]}}`)
+	if err := loadRouting(); err != nil {
+		t.Fatalf("loadRouting: %v", err)
+	}
+
+	to := []models.Principal{
+		{UserType: models.UserTypeOrangTua, ID: 1},
+		gagal,
+		{UserType: models.UserTypeOrangTua, ID: 3},
+	}
+	msg := Pesan{Type: "pengumuman", Title: "Libur"}
+	selesai := map[string]bool{}
+
+	if err := dispatch(context.Background(), msg, to, nil, selesai); err == nil {
+		t.Fatal("dispatch() seharusnya melaporkan penerima yang gagal")
+	}
+	want := map[string]bool{"sms/orang_tua/1": true, "sms/orang_tua/3": true}
+	if !reflect.DeepEqual(selesai, want) {
+		t.Errorf("selesai = %v, want %v", selesai, want)
+	}
+
+	sms.dikirim = nil
+	delete(sms.gagal, gagal)
+	if err := dispatch(context.Background(), msg, to, nil, selesai); err != nil {
+		t.Fatalf("dispatch ulang: %v", err)
+	}
+	if !reflect.DeepEqual(sms.dikirim, []models.Principal{gagal}) {
+		t.Errorf("dikirim ulang = %v, want hanya %v", sms.dikirim, gagal)
+	}
+}
@@ -0,0 +1,275 @@
+package firebaseclient
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// Sender adalah bagian FCM yang dipakai paket ini. *messaging.Client
+// memenuhinya langsung; NoopSender dan RecordingSender dipakai saat
+// kredensial Firebase tidak tersedia atau saat pengujian.
+type Sender interface {
+	Send(ctx context.Context, message *messaging.Message) (string, error)
+	SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+}
+
+const (
+	SenderFCM       = "fcm"
+	SenderNoop      = "noop"
+	SenderRecording = "recording"
+)
+
+var (
+	senderMu   sync.RWMutex
+	sender     Sender
+	senderNama string
+)
+
+// SetSender mengganti sender yang dipakai semua pengiriman FCM.
+func SetSender(nama string, s Sender) {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	sender = s
+	senderNama = nama
+}
+
+func currentSender() (Sender, string) {
+	senderMu.RLock()
+	defer senderMu.RUnlock()
+	return sender, senderNama
+}
+
+// InitSender memilih sender dari FCM_SENDER (fcm, noop atau recording).
+// Bila kosong, fcm dipakai jika FIREBASE_SA_FILE diisi dan noop jika tidak.
+// Sender noop tetap terpasang bila inisialisasi gagal, sehingga server
+// tetap bisa berjalan tanpa kredensial; error dikembalikan untuk dicatat.
+func InitSender() error {
+	saFile := os.Getenv("FIREBASE_SA_FILE")
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("FCM_SENDER")))
+	if mode == "" {
+		mode = SenderNoop
+		if saFile != "" {
+			mode = SenderFCM
+		}
+	}
+
+	SetSender(SenderNoop, NoopSender{})
+	switch mode {
+	case SenderFCM:
+		if saFile == "" {
+			return fmt.Errorf("FCM_SENDER=fcm membutuhkan FIREBASE_SA_FILE")
+		}
+		if err := InitFirebase(saFile); err != nil {
+			return fmt.Errorf("firebase init: %w", err)
+		}
+		client := MessagingClient()
+		if client == nil {
+			return fmt.Errorf("firebase messaging client is nil after init")
+		}
+		SetSender(SenderFCM, client)
+	case SenderNoop:
+	case SenderRecording:
+		SetSender(SenderRecording, NewRecordingSender())
+	default:
+		return fmt.Errorf("FCM_SENDER %q tidak dikenal (fcm, noop, recording)", mode)
+	}
+	log.Printf("notify: FCM sender %s", mode)
+	return nil
+}
+
+var noopSeq atomic.Uint64
+
+func noopMessageID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, noopSeq.Add(1))
+}
+
+// NoopSender menerima semua pesan tanpa mengirim apa pun.
+type NoopSender struct{}
+
+func (NoopSender) Send(ctx context.Context, m *messaging.Message) (string, error) {
+	return noopMessageID("noop"), nil
+}
+
+func (NoopSender) SendEachForMulticast(ctx context.Context, m *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	resp := &messaging.BatchResponse{SuccessCount: len(m.Tokens)}
+	for range m.Tokens {
+		resp.Responses = append(resp.Responses, &messaging.SendResponse{Success: true, MessageID: noopMessageID("noop")})
+	}
+	return resp, nil
+}
+
+func (NoopSender) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return &messaging.TopicManagementResponse{SuccessCount: len(tokens)}, nil
+}
+
+func (NoopSender) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return &messaging.TopicManagementResponse{SuccessCount: len(tokens)}, nil
+}
+
+// ErrorKode adalah error dengan kode FCM, dipakai RecordingSender untuk
+// mensimulasikan kegagalan per token.
+type ErrorKode struct {
+	Kode string
+}
+
+func (e *ErrorKode) Error() string   { return "fcm: " + e.Kode }
+func (e *ErrorKode) KodeFCM() string { return e.Kode }
+
+// RecordingSender menyimpan semua pesan di memori. Token yang didaftarkan
+// lewat Gagalkan akan gagal dengan kode tsb.
+type RecordingSender struct {
+	mu    sync.Mutex
+	pesan []*messaging.Message
+	topik map[string]map[string]bool
+	gagal map[string]string
+}
+
+func NewRecordingSender() *RecordingSender {
+	return &RecordingSender{topik: map[string]map[string]bool{}, gagal: map[string]string{}}
+}
+
+// Gagalkan membuat pengiriman ke token gagal dengan kode FCM tsb.
+func (r *RecordingSender) Gagalkan(token, kode string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gagal[token] = kode
+}
+
+// Pesan mengembalikan salinan pesan yang berhasil "dikirim".
+func (r *RecordingSender) Pesan() []*messaging.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*messaging.Message(nil), r.pesan...)
+}
+
+// Pelanggan mengembalikan token yang melanggan topic.
+func (r *RecordingSender) Pelanggan(topic string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tokens []string
+	for t := range r.topik[topic] {
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+func (r *RecordingSender) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pesan = nil
+	r.topik = map[string]map[string]bool{}
+	r.gagal = map[string]string{}
+}
+
+func (r *RecordingSender) Send(ctx context.Context, m *messaging.Message) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if kode, ok := r.gagal[m.Token]; ok && m.Token != "" {
+		return "", &ErrorKode{Kode: kode}
+	}
+	r.pesan = append(r.pesan, m)
+	return noopMessageID("recording"), nil
+}
+
+func (r *RecordingSender) SendEachForMulticast(ctx context.Context, m *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	resp := &messaging.BatchResponse{}
+	for _, tok := range m.Tokens {
+		id, err := r.Send(ctx, &messaging.Message{
+			Token:        tok,
+			Data:         m.Data,
+			Notification: m.Notification,
+			Android:      m.Android,
+			Webpush:      m.Webpush,
+			APNS:         m.APNS,
+		})
+		if err != nil {
+			resp.FailureCount++
+			resp.Responses = append(resp.Responses, &messaging.SendResponse{Error: err})
+			continue
+		}
+		resp.SuccessCount++
+		resp.Responses = append(resp.Responses, &messaging.SendResponse{Success: true, MessageID: id})
+	}
+	return resp, nil
+}
+
+func (r *RecordingSender) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.topik[topic] == nil {
+		r.topik[topic] = map[string]bool{}
+	}
+	for _, t := range tokens {
+		r.topik[topic][t] = true
+	}
+	return &messaging.TopicManagementResponse{SuccessCount: len(tokens)}, nil
+}
+
+func (r *RecordingSender) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range tokens {
+		delete(r.topik[topic], t)
+	}
+	return &messaging.TopicManagementResponse{SuccessCount: len(tokens)}, nil
+}
+
+// Kode error FCM yang dicatat di tanda terima.
+const (
+	KodeUnregistered        = "UNREGISTERED"
+	KodeInvalidArgument     = "INVALID_ARGUMENT"
+	KodeSenderIDMismatch    = "SENDER_ID_MISMATCH"
+	KodeQuotaExceeded       = "QUOTA_EXCEEDED"
+	KodeThirdPartyAuthError = "THIRD_PARTY_AUTH_ERROR"
+	KodeUnavailable         = "UNAVAILABLE"
+	KodeInternal            = "INTERNAL"
+	KodeUnknown             = "UNKNOWN"
+)
+
+// errorSementara melaporkan apakah pengiriman dengan kode ini layak
+// diulang. Token yang tidak berlaku atau pesan yang ditolak tidak akan
+// berhasil pada percobaan berikutnya.
+func errorSementara(kode string) bool {
+	switch kode {
+	case KodeUnregistered, KodeSenderIDMismatch, KodeInvalidArgument:
+		return false
+	}
+	return true
+}
+
+// kodeErrorFCM menggolongkan error FCM berdasarkan kode error bertipe dari
+// SDK, bukan teks pesannya.
+func kodeErrorFCM(err error) string {
+	if err == nil {
+		return ""
+	}
+	if k, ok := err.(interface{ KodeFCM() string }); ok {
+		return k.KodeFCM()
+	}
+	switch {
+	case messaging.IsUnregistered(err):
+		return KodeUnregistered
+	case messaging.IsInvalidArgument(err):
+		return KodeInvalidArgument
+	case messaging.IsSenderIDMismatch(err):
+		return KodeSenderIDMismatch
+	case messaging.IsQuotaExceeded(err):
+		return KodeQuotaExceeded
+	case messaging.IsThirdPartyAuthError(err):
+		return KodeThirdPartyAuthError
+	case messaging.IsUnavailable(err):
+		return KodeUnavailable
+	case messaging.IsInternal(err):
+		return KodeInternal
+	}
+	return KodeUnknown
+}
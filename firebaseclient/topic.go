@@ -0,0 +1,325 @@
+package firebaseclient
+
+import (
+	"abs-be/database"
+	"abs-be/models"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// Batas token per panggilan subscribe/unsubscribe FCM.
+const topicChunk = 1000
+
+// sinkronMu mencegah dua sinkronisasi menghitung selisih dari catatan
+// device_token_topiks yang sama secara bersamaan.
+var sinkronMu sync.Mutex
+
+func TopicRole(role string) string       { return "role-" + role }
+func TopicKelas(kelasID uint) string     { return fmt.Sprintf("kelas-%d", kelasID) }
+func TopicTingkat(tingkat string) string { return "tingkat-" + tingkat }
+
+// TopicOrtuKelas dan TopicOrtuTingkat menjangkau orang tua dari siswa di
+// kelas/tingkat tsb, terpisah dari siswanya sendiri.
+func TopicOrtuKelas(kelasID uint) string     { return fmt.Sprintf("ortu-kelas-%d", kelasID) }
+func TopicOrtuTingkat(tingkat string) string { return "ortu-tingkat-" + tingkat }
+
+// KirimKeTopik mengirim satu pesan ke semua device yang melanggan topic.
+func KirimKeTopik(ctx context.Context, topic string, msg Pesan) error {
+	client, nama := currentSender()
+	if client == nil {
+		return fmt.Errorf("firebase sender not initialized")
+	}
+	id, err := client.Send(ctx, &messaging.Message{
+		Topic: topic,
+		Notification: &messaging.Notification{
+			Title: msg.Title,
+			Body:  msg.Body,
+		},
+		Data: map[string]string{
+			"type":    msg.Type,
+			"payload": msg.PayloadJSON,
+		},
+		Webpush: &messaging.WebpushConfig{
+			Notification: &messaging.WebpushNotification{
+				Title: msg.Title,
+				Body:  msg.Body,
+			},
+		},
+	})
+	catat := pencatatReceipt(ctx, nama, msg.Type)
+	catat.topic(topic, id, err)
+	catat.simpan()
+	if err != nil {
+		return fmt.Errorf("kirim ke topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+type kelasTopik struct {
+	ID      uint
+	Tingkat string
+}
+
+// topikPrincipal menghitung topic yang seharusnya dilanggan user.
+func topikPrincipal(p models.Principal) ([]string, error) {
+	topics := []string{TopicRole(p.UserType)}
+	var kelas []kelasTopik
+
+	switch p.UserType {
+	case models.UserTypeSiswa:
+		if err := database.DB.Raw(`
+			SELECT k.id, k.tingkat FROM kelas k
+			WHERE k.id IN (SELECT kelas_id FROM siswas WHERE id = ? AND kelas_id IS NOT NULL)
+			   OR k.id IN (SELECT kelas_id FROM kelas_siswas WHERE siswa_id = ?)`, p.ID, p.ID).
+			Scan(&kelas).Error; err != nil {
+			return nil, err
+		}
+		for _, k := range kelas {
+			topics = append(topics, TopicKelas(k.ID), TopicTingkat(k.Tingkat))
+		}
+	case models.UserTypeOrangTua:
+		if err := database.DB.Raw(`
+			SELECT k.id, k.tingkat FROM kelas k
+			WHERE k.id IN (SELECT s.kelas_id FROM siswas s JOIN orang_tua_siswas os ON os.siswa_id = s.id
+			               WHERE os.orang_tua_id = ? AND s.kelas_id IS NOT NULL)
+			   OR k.id IN (SELECT ks.kelas_id FROM kelas_siswas ks JOIN orang_tua_siswas os ON os.siswa_id = ks.siswa_id
+			               WHERE os.orang_tua_id = ?)`, p.ID, p.ID).
+			Scan(&kelas).Error; err != nil {
+			return nil, err
+		}
+		for _, k := range kelas {
+			topics = append(topics, TopicOrtuKelas(k.ID), TopicOrtuTingkat(k.Tingkat))
+		}
+	case models.UserTypeGuru:
+		var wali int64
+		if err := database.DB.Model(&models.Kelas{}).Where("wali_kelas_id = ?", p.ID).Count(&wali).Error; err != nil {
+			return nil, err
+		}
+		if wali > 0 {
+			topics = append(topics, TopicRole("wali_kelas"))
+		}
+	}
+
+	seen := map[string]struct{}{}
+	unik := topics[:0]
+	for _, t := range topics {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		unik = append(unik, t)
+	}
+	sort.Strings(unik)
+	return unik, nil
+}
+
+// SinkronTopik menyamakan langganan topic semua device token milik para
+// user dengan kelas/role mereka saat ini.
+func SinkronTopik(ctx context.Context, ps ...models.Principal) error {
+	ps = unikPrincipal(ps)
+	if len(ps) == 0 {
+		return nil
+	}
+	var tokens []models.DeviceToken
+	if err := database.DB.Where("(user_type, user_id) IN ?", pasanganPrincipal(ps)).Find(&tokens).Error; err != nil {
+		return fmt.Errorf("gagal ambil device tokens: %w", err)
+	}
+	return sinkronTokens(ctx, tokens)
+}
+
+// SinkronTopikToken menyinkronkan satu token, mis. setelah didaftarkan atau
+// berpindah pemilik.
+func SinkronTopikToken(ctx context.Context, token string) error {
+	var tokens []models.DeviceToken
+	if err := database.DB.Where("token = ?", token).Find(&tokens).Error; err != nil {
+		return fmt.Errorf("gagal ambil device token: %w", err)
+	}
+	return sinkronTokens(ctx, tokens)
+}
+
+// SinkronTopikAsync menjalankan SinkronTopik di luar request; kegagalan
+// hanya dicatat karena job rekonsiliasi akan mengulanginya.
+func SinkronTopikAsync(ps ...models.Principal) {
+	go func() {
+		if err := SinkronTopik(context.Background(), ps...); err != nil {
+			log.Printf("topic: gagal sinkron topic: %v", err)
+		}
+	}()
+}
+
+func sinkronTokens(ctx context.Context, tokens []models.DeviceToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	client, _ := currentSender()
+	if client == nil {
+		return fmt.Errorf("firebase sender not initialized")
+	}
+
+	sinkronMu.Lock()
+	defer sinkronMu.Unlock()
+
+	daftar := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		daftar = append(daftar, t.Token)
+	}
+	var tercatat []models.DeviceTokenTopik
+	if err := database.DB.Where("token IN ?", daftar).Find(&tercatat).Error; err != nil {
+		return fmt.Errorf("gagal ambil topic tercatat: %w", err)
+	}
+	lama := map[string]map[string]bool{}
+	for _, r := range tercatat {
+		if lama[r.Token] == nil {
+			lama[r.Token] = map[string]bool{}
+		}
+		lama[r.Token][r.Topic] = true
+	}
+
+	tambah, lepas, err := selisihTopik(tokens, lama, topikPrincipal)
+	if err != nil {
+		return err
+	}
+	dilanggan, dilepas, firstErr := terapkanTopik(ctx, client, tambah, lepas)
+
+	for topic, toks := range dilepas {
+		if err := database.DB.Where("topic = ? AND token IN ?", topic, toks).Delete(&models.DeviceTokenTopik{}).Error; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for topic, toks := range dilanggan {
+		rows := make([]models.DeviceTokenTopik, 0, len(toks))
+		for _, tok := range toks {
+			rows = append(rows, models.DeviceTokenTopik{Token: tok, Topic: topic})
+		}
+		if err := database.DB.Create(&rows).Error; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// selisihTopik membandingkan topic yang tercatat per token (lama) dengan
+// topic yang seharusnya dilanggan pemiliknya, dan mengembalikan token yang
+// perlu dilanggankan/dilepas per topic. target dipanggil sekali per
+// pemilik.
+func selisihTopik(tokens []models.DeviceToken, lama map[string]map[string]bool, target func(models.Principal) ([]string, error)) (tambah, lepas map[string][]string, err error) {
+	perPemilik := map[models.Principal][]string{}
+	tambah = map[string][]string{}
+	lepas = map[string][]string{}
+	for _, t := range tokens {
+		p := models.Principal{UserType: t.UserType, ID: t.UserID}
+		topics, ok := perPemilik[p]
+		if !ok {
+			if topics, err = target(p); err != nil {
+				return nil, nil, fmt.Errorf("gagal hitung topic %s/%d: %w", p.UserType, p.ID, err)
+			}
+			perPemilik[p] = topics
+		}
+		ingin := map[string]bool{}
+		for _, topic := range topics {
+			ingin[topic] = true
+			if !lama[t.Token][topic] {
+				tambah[topic] = append(tambah[topic], t.Token)
+			}
+		}
+		for topic := range lama[t.Token] {
+			if !ingin[topic] {
+				lepas[topic] = append(lepas[topic], t.Token)
+			}
+		}
+	}
+	return tambah, lepas, nil
+}
+
+// terapkanTopik melepas lalu melanggankan token di FCM dan mengembalikan
+// token yang berhasil per topic. Error menandakan sebagian token gagal.
+func terapkanTopik(ctx context.Context, client Sender, tambah, lepas map[string][]string) (dilanggan, dilepas map[string][]string, err error) {
+	dilanggan = map[string][]string{}
+	dilepas = map[string][]string{}
+	for topic, toks := range lepas {
+		berhasil := kelolaTopik(ctx, client.UnsubscribeFromTopic, topic, toks)
+		if len(berhasil) > 0 {
+			dilepas[topic] = berhasil
+		}
+		if len(berhasil) < len(toks) && err == nil {
+			err = fmt.Errorf("sebagian token gagal dilepas dari topic %s", topic)
+		}
+	}
+	for topic, toks := range tambah {
+		berhasil := kelolaTopik(ctx, client.SubscribeToTopic, topic, toks)
+		if len(berhasil) > 0 {
+			dilanggan[topic] = berhasil
+		}
+		if len(berhasil) < len(toks) && err == nil {
+			err = fmt.Errorf("sebagian token gagal dilanggankan ke topic %s", topic)
+		}
+	}
+	return dilanggan, dilepas, err
+}
+
+type aksiTopik func(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+
+// kelolaTopik menjalankan subscribe/unsubscribe per 1000 token dan
+// mengembalikan token yang berhasil.
+func kelolaTopik(ctx context.Context, aksi aksiTopik, topic string, tokens []string) []string {
+	var berhasil []string
+	for i := 0; i < len(tokens); i += topicChunk {
+		end := i + topicChunk
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		chunk := tokens[i:end]
+
+		resp, err := aksi(ctx, chunk, topic)
+		if err != nil {
+			log.Printf("topic: gagal kelola topic %s untuk %d token: %v", topic, len(chunk), err)
+			continue
+		}
+		gagal := map[int]bool{}
+		if resp != nil {
+			for _, e := range resp.Errors {
+				gagal[e.Index] = true
+				log.Printf("topic: token %s gagal pada topic %s: %s", chunk[e.Index], topic, e.Reason)
+			}
+		}
+		for idx, tok := range chunk {
+			if !gagal[idx] {
+				berhasil = append(berhasil, tok)
+			}
+		}
+	}
+	return berhasil
+}
+
+// SinkronSemuaTopik merekonsiliasi langganan topic seluruh device token.
+func SinkronSemuaTopik(ctx context.Context) error {
+	var ps []models.Principal
+	if err := database.DB.Model(&models.DeviceToken{}).
+		Distinct("user_type", "user_id AS id").
+		Scan(&ps).Error; err != nil {
+		return err
+	}
+	var firstErr error
+	for i := 0; i < len(ps); i += 200 {
+		end := i + 200
+		if end > len(ps) {
+			end = len(ps)
+		}
+		if err := SinkronTopik(ctx, ps[i:end]...); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// Catatan topic milik token yang sudah dihapus tidak perlu dilepas di
+	// FCM lagi; token tsb sudah tidak berlaku.
+	if err := database.DB.Where("token NOT IN (SELECT token FROM device_tokens)").
+		Delete(&models.DeviceTokenTopik{}).Error; err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,262 @@
+package firebaseclient
+
+import (
+	"abs-be/models"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// topicPalsu mencatat setiap panggilan subscribe/unsubscribe. Token di
+// gagalToken ditolak per token, dan semua panggilan ke topic di gagalTopic
+// gagal seluruhnya.
+type topicPalsu struct {
+	NoopSender
+	gagalToken map[string]bool
+	gagalTopic map[string]bool
+	panggilan  []panggilanTopik
+}
+
+type panggilanTopik struct {
+	aksi   string
+	topic  string
+	tokens []string
+}
+
+func (f *topicPalsu) kelola(aksi string, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	f.panggilan = append(f.panggilan, panggilanTopik{aksi: aksi, topic: topic, tokens: append([]string(nil), tokens...)})
+	if f.gagalTopic[topic] {
+		return nil, errors.New("fcm tidak tersedia")
+	}
+	resp := &messaging.TopicManagementResponse{}
+	for i, tok := range tokens {
+		if f.gagalToken[tok] {
+			resp.FailureCount++
+			resp.Errors = append(resp.Errors, &messaging.ErrorInfo{Index: i, Reason: "invalid-registration-token"})
+			continue
+		}
+		resp.SuccessCount++
+	}
+	return resp, nil
+}
+
+func (f *topicPalsu) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return f.kelola("subscribe", tokens, topic)
+}
+
+func (f *topicPalsu) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return f.kelola("unsubscribe", tokens, topic)
+}
+
+func urutkan(m map[string][]string) map[string][]string {
+	for _, v := range m {
+		sort.Strings(v)
+	}
+	return m
+}
+
+func TestSelisihTopik(t *testing.T) {
+	guru := models.Principal{UserType: models.UserTypeGuru, ID: 1}
+	siswa := models.Principal{UserType: models.UserTypeSiswa, ID: 1}
+	target := map[models.Principal][]string{
+		guru:  {TopicRole("guru"), TopicRole("wali_kelas")},
+		siswa: {TopicKelas(3), TopicRole("siswa"), TopicTingkat("X")},
+	}
+
+	tests := []struct {
+		nama       string
+		tokens     []models.DeviceToken
+		lama       map[string]map[string]bool
+		wantTambah map[string][]string
+		wantLepas  map[string][]string
+	}{
+		{
+			nama:   "token baru dilanggankan ke semua topic",
+			tokens: []models.DeviceToken{{Token: "g1", UserType: "guru", UserID: 1}},
+			lama:   map[string]map[string]bool{},
+			wantTambah: map[string][]string{
+				"role-guru":       {"g1"},
+				"role-wali_kelas": {"g1"},
+			},
+			wantLepas: map[string][]string{},
+		},
+		{
+			nama:       "sudah sesuai tidak berubah",
+			tokens:     []models.DeviceToken{{Token: "g1", UserType: "guru", UserID: 1}},
+			lama:       map[string]map[string]bool{"g1": {"role-guru": true, "role-wali_kelas": true}},
+			wantTambah: map[string][]string{},
+			wantLepas:  map[string][]string{},
+		},
+		{
+			nama:   "pindah kelas melepas topic lama",
+			tokens: []models.DeviceToken{{Token: "s1", UserType: "siswa", UserID: 1}},
+			lama: map[string]map[string]bool{
+				"s1": {"role-siswa": true, "kelas-2": true, "tingkat-X": true},
+			},
+			wantTambah: map[string][]string{"kelas-3": {"s1"}},
+			wantLepas:  map[string][]string{"kelas-2": {"s1"}},
+		},
+		{
+			nama: "token berpindah pemilik",
+			tokens: []models.DeviceToken{
+				{Token: "t1", UserType: "siswa", UserID: 1},
+				{Token: "g2", UserType: "guru", UserID: 1},
+			},
+			lama: map[string]map[string]bool{
+				"t1": {"role-guru": true, "role-wali_kelas": true},
+				"g2": {"role-guru": true},
+			},
+			wantTambah: map[string][]string{
+				"kelas-3":         {"t1"},
+				"role-siswa":      {"t1"},
+				"tingkat-X":       {"t1"},
+				"role-wali_kelas": {"g2"},
+			},
+			wantLepas: map[string][]string{
+				"role-guru":       {"t1"},
+				"role-wali_kelas": {"t1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			dipanggil := map[models.Principal]int{}
+			tambah, lepas, err := selisihTopik(tt.tokens, tt.lama, func(p models.Principal) ([]string, error) {
+				dipanggil[p]++
+				return target[p], nil
+			})
+			if err != nil {
+				t.Fatalf("selisihTopik: %v", err)
+			}
+			if !reflect.DeepEqual(urutkan(tambah), tt.wantTambah) {
+				t.Errorf("tambah = %v, want %v", tambah, tt.wantTambah)
+			}
+			if !reflect.DeepEqual(urutkan(lepas), tt.wantLepas) {
+				t.Errorf("lepas = %v, want %v", lepas, tt.wantLepas)
+			}
+			for p, n := range dipanggil {
+				if n != 1 {
+					t.Errorf("topic %v dihitung %d kali, want 1", p, n)
+				}
+			}
+		})
+	}
+}
+
+func TestSelisihTopikSekaliPerPemilik(t *testing.T) {
+	tokens := []models.DeviceToken{
+		{Token: "a", UserType: "guru", UserID: 1},
+		{Token: "b", UserType: "guru", UserID: 1},
+	}
+	n := 0
+	tambah, _, err := selisihTopik(tokens, nil, func(models.Principal) ([]string, error) {
+		n++
+		return []string{"role-guru"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("target dipanggil %d kali, want 1", n)
+	}
+	if got := urutkan(tambah)["role-guru"]; !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("tambah role-guru = %v, want [a b]", got)
+	}
+
+	_, _, err = selisihTopik(tokens, nil, func(models.Principal) ([]string, error) {
+		return nil, errors.New("db putus")
+	})
+	if err == nil {
+		t.Error("error target tidak diteruskan")
+	}
+}
+
+func TestTerapkanTopik(t *testing.T) {
+	f := &topicPalsu{
+		gagalToken: map[string]bool{"rusak": true},
+		gagalTopic: map[string]bool{"kelas-9": true},
+	}
+	tambah := map[string][]string{
+		"kelas-3":    {"s1", "rusak", "s2"},
+		"role-siswa": {"s1"},
+	}
+	lepas := map[string][]string{
+		"kelas-2": {"s1", "s2"},
+		"kelas-9": {"s3"},
+	}
+
+	dilanggan, dilepas, err := terapkanTopik(context.Background(), f, tambah, lepas)
+	if err == nil {
+		t.Fatal("terapkanTopik seharusnya melaporkan token yang gagal")
+	}
+	wantLanggan := map[string][]string{"kelas-3": {"s1", "s2"}, "role-siswa": {"s1"}}
+	if !reflect.DeepEqual(urutkan(dilanggan), wantLanggan) {
+		t.Errorf("dilanggan = %v, want %v", dilanggan, wantLanggan)
+	}
+	wantLepas := map[string][]string{"kelas-2": {"s1", "s2"}}
+	if !reflect.DeepEqual(urutkan(dilepas), wantLepas) {
+		t.Errorf("dilepas = %v, want %v", dilepas, wantLepas)
+	}
+
+	// Semua unsubscribe dijalankan sebelum subscribe.
+	sudahSubscribe := false
+	for _, p := range f.panggilan {
+		if p.aksi == "subscribe" {
+			sudahSubscribe = true
+		} else if sudahSubscribe {
+			t.Errorf("unsubscribe %s dijalankan setelah subscribe", p.topic)
+		}
+	}
+}
+
+func TestTerapkanTopikBerhasil(t *testing.T) {
+	f := &topicPalsu{}
+	dilanggan, dilepas, err := terapkanTopik(context.Background(), f,
+		map[string][]string{"role-guru": {"g1"}},
+		map[string][]string{"role-siswa": {"g1"}})
+	if err != nil {
+		t.Fatalf("terapkanTopik: %v", err)
+	}
+	if !reflect.DeepEqual(dilanggan, map[string][]string{"role-guru": {"g1"}}) ||
+		!reflect.DeepEqual(dilepas, map[string][]string{"role-siswa": {"g1"}}) {
+		t.Errorf("dilanggan = %v, dilepas = %v", dilanggan, dilepas)
+	}
+}
+
+func TestKelolaTopikPerChunk(t *testing.T) {
+	tokens := make([]string, 2*topicChunk+5)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("tok-%d", i)
+	}
+	f := &topicPalsu{gagalToken: map[string]bool{"tok-0": true, "tok-1500": true}}
+
+	berhasil := kelolaTopik(context.Background(), f.SubscribeToTopic, "role-siswa", tokens)
+
+	var ukuran []int
+	for _, p := range f.panggilan {
+		ukuran = append(ukuran, len(p.tokens))
+	}
+	if !reflect.DeepEqual(ukuran, []int{topicChunk, topicChunk, 5}) {
+		t.Errorf("ukuran chunk = %v, want [%d %d 5]", ukuran, topicChunk, topicChunk)
+	}
+	if len(berhasil) != len(tokens)-2 {
+		t.Fatalf("berhasil = %d token, want %d", len(berhasil), len(tokens)-2)
+	}
+	for _, tok := range berhasil {
+		if tok == "tok-0" || tok == "tok-1500" {
+			t.Errorf("token gagal %s dianggap berhasil", tok)
+		}
+	}
+}
+
+func TestKelolaTopikChunkGagal(t *testing.T) {
+	f := &topicPalsu{gagalTopic: map[string]bool{"role-guru": true}}
+	if got := kelolaTopik(context.Background(), f.UnsubscribeFromTopic, "role-guru", []string{"a", "b"}); len(got) != 0 {
+		t.Errorf("berhasil = %v, want kosong saat panggilan gagal", got)
+	}
+}
@@ -5,7 +5,7 @@ go 1.23.6
 require (
 	firebase.google.com/go/v4 v4.18.0
 	github.com/golang-jwt/jwt/v5 v5.2.2
-	golang.org/x/oauth2 v0.30.0
+	golang.org/x/net v0.42.0
 	google.golang.org/api v0.236.0
 	google.golang.org/grpc v1.72.2
 )
@@ -73,7 +73,7 @@ require (
 	go.opentelemetry.io/otel/sdk/metric v1.36.0 // indirect
 	go.opentelemetry.io/otel/trace v1.36.0 // indirect
 	golang.org/x/arch v0.15.0 // indirect
-	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/oauth2 v0.30.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
 	golang.org/x/time v0.11.0 // indirect
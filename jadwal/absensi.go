@@ -0,0 +1,99 @@
+package jadwal
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusBelumDiisi = "belum_diisi"
+	StatusSebagian   = "sebagian"
+	StatusLengkap    = "lengkap"
+)
+
+func SiswaIDsKelas(db *gorm.DB, kelasID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Table("siswas").
+		Select("DISTINCT siswas.id").
+		Joins("LEFT JOIN kelas_siswas ks ON ks.siswa_id = siswas.id").
+		Where("siswas.kelas_id = ? OR ks.kelas_id = ?", kelasID, kelasID).
+		Pluck("siswas.id", &ids).Error
+	return ids, err
+}
+
+// RosterMapel mengembalikan siswa kelas yang terdaftar di mapel (mapel_siswas).
+// Jika belum ada siswa kelas yang didaftarkan ke mapel, seluruh siswa kelas dianggap peserta.
+func RosterMapel(db *gorm.DB, kelasID, mapelID uint) ([]uint, error) {
+	kelasIDs, err := SiswaIDsKelas(db, kelasID)
+	if err != nil || len(kelasIDs) == 0 {
+		return kelasIDs, err
+	}
+	var enrolled []uint
+	if err := db.Table("mapel_siswas").
+		Where("mapel_id = ? AND siswa_id IN ?", mapelID, kelasIDs).
+		Pluck("siswa_id", &enrolled).Error; err != nil {
+		return nil, err
+	}
+	if len(enrolled) == 0 {
+		return kelasIDs, nil
+	}
+	return enrolled, nil
+}
+
+// JumlahTerisi menghitung siswa dari roster yang sudah memiliki baris absensi pada tanggal tersebut.
+// mapelID nil berarti absensi kelas.
+func JumlahTerisi(db *gorm.DB, kelasID uint, mapelID *uint, tanggal time.Time, roster []uint) (int64, error) {
+	if len(roster) == 0 {
+		return 0, nil
+	}
+	q := db.Table("absensi_siswas").
+		Where("kelas_id = ? AND tanggal = ? AND siswa_id IN ?", kelasID, tanggal.Format("2006-01-02"), roster)
+	if mapelID != nil {
+		q = q.Where("tipe_absensi = ? AND mapel_id = ?", "mapel", *mapelID)
+	} else {
+		q = q.Where("tipe_absensi = ?", "kelas")
+	}
+	var n int64
+	err := q.Distinct("siswa_id").Count(&n).Error
+	return n, err
+}
+
+func StatusPengisian(terisi, total int64) string {
+	switch {
+	case terisi == 0:
+		return StatusBelumDiisi
+	case terisi < total:
+		return StatusSebagian
+	default:
+		return StatusLengkap
+	}
+}
+
+// DeepLinkAbsensi membangun payload navigasi aplikasi ke layar pengisian absensi.
+// mapelID 0 berarti absensi kelas.
+func DeepLinkAbsensi(kelasID, mapelID uint, tanggal time.Time) map[string]interface{} {
+	scheme := os.Getenv("APP_DEEPLINK_SCHEME")
+	if scheme == "" {
+		scheme = "absapp"
+	}
+	tgl := tanggal.Format("2006-01-02")
+	params := map[string]interface{}{
+		"kelas_id": kelasID,
+		"tanggal":  tgl,
+	}
+	screen := "absensi_kelas"
+	uri := fmt.Sprintf("%s://absensi/kelas?kelas_id=%d&tanggal=%s", scheme, kelasID, tgl)
+	if mapelID != 0 {
+		screen = "absensi_mapel"
+		params["mapel_id"] = mapelID
+		uri = fmt.Sprintf("%s://absensi/mapel?kelas_id=%d&mapel_id=%d&tanggal=%s", scheme, kelasID, mapelID, tgl)
+	}
+	return map[string]interface{}{
+		"screen": screen,
+		"params": params,
+		"uri":    uri,
+	}
+}
@@ -0,0 +1,161 @@
+package jadwal
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var namaHari = map[time.Weekday]string{
+	time.Sunday:    "Minggu",
+	time.Monday:    "Senin",
+	time.Tuesday:   "Selasa",
+	time.Wednesday: "Rabu",
+	time.Thursday:  "Kamis",
+	time.Friday:    "Jumat",
+	time.Saturday:  "Sabtu",
+}
+
+type Sesi struct {
+	GuruID      uint   `json:"guru_id"`
+	GuruNama    string `json:"guru_nama,omitempty"`
+	MapelID     uint   `json:"mapel_id"`
+	MapelNama   string `json:"mapel_nama"`
+	MapelKode   string `json:"mapel_kode"`
+	KelasID     uint   `json:"kelas_id"`
+	KelasNama   string `json:"kelas_nama"`
+	Hari        string `json:"hari"`
+	JamMulai    string `json:"jam_mulai"`
+	JamSelesai  string `json:"jam_selesai"`
+	TahunAjaran string `json:"tahun_ajaran"`
+	Semester    string `json:"semester"`
+}
+
+func NamaHari(t time.Time) string {
+	return namaHari[t.Weekday()]
+}
+
+func HariKe(hari string) (time.Weekday, bool) {
+	for wd, nama := range namaHari {
+		if nama == hari {
+			return wd, true
+		}
+	}
+	return time.Sunday, false
+}
+
+func TahunAjaran(t time.Time) string {
+	year := t.Year()
+	if t.Month() >= 7 {
+		return fmt.Sprintf("%d/%d", year, year+1)
+	}
+	return fmt.Sprintf("%d/%d", year-1, year)
+}
+
+func Semester(t time.Time) string {
+	if t.Month() >= 7 {
+		return "ganjil"
+	}
+	return "genap"
+}
+
+// RentangSemester mengembalikan tanggal pertama dan terakhir semester
+// (ganjil: Juli-Desember tahun pertama, genap: Januari-Juni tahun kedua).
+func RentangSemester(tahunAjaran, semester string, loc *time.Location) (time.Time, time.Time, error) {
+	var awal, akhir int
+	if _, err := fmt.Sscanf(tahunAjaran, "%d/%d", &awal, &akhir); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("tahun ajaran tidak valid: %s", tahunAjaran)
+	}
+	if semester == "ganjil" {
+		return time.Date(awal, time.July, 1, 0, 0, 0, 0, loc), time.Date(awal, time.December, 31, 0, 0, 0, 0, loc), nil
+	}
+	return time.Date(akhir, time.January, 1, 0, 0, 0, 0, loc), time.Date(akhir, time.June, 30, 0, 0, 0, 0, loc), nil
+}
+
+// WaktuSesi menggabungkan tanggal dengan jam "HH:MM[:SS]" dari kolom TIME.
+func WaktuSesi(tanggal time.Time, jam string) (time.Time, error) {
+	var h, m, s int
+	if _, err := fmt.Sscanf(jam, "%d:%d:%d", &h, &m, &s); err != nil {
+		if _, err := fmt.Sscanf(jam, "%d:%d", &h, &m); err != nil {
+			return time.Time{}, fmt.Errorf("format jam tidak valid: %s", jam)
+		}
+	}
+	y, mo, d := tanggal.Date()
+	return time.Date(y, mo, d, h, m, s, 0, tanggal.Location()), nil
+}
+
+func baseSesiQuery(db *gorm.DB) *gorm.DB {
+	return db.Table("guru_mapel_kelas").
+		Select(`guru_mapel_kelas.guru_id, gurus.nama AS guru_nama,
+			guru_mapel_kelas.mapel_id, mata_pelajarans.nama AS mapel_nama, mata_pelajarans.kode AS mapel_kode,
+			guru_mapel_kelas.kelas_id, kelas.nama AS kelas_nama,
+			mata_pelajarans.hari, mata_pelajarans.jam_mulai, mata_pelajarans.jam_selesai,
+			guru_mapel_kelas.tahun_ajaran, guru_mapel_kelas.semester`).
+		Joins("JOIN mata_pelajarans ON mata_pelajarans.id = guru_mapel_kelas.mapel_id").
+		Joins("JOIN kelas ON kelas.id = guru_mapel_kelas.kelas_id").
+		Joins("JOIN gurus ON gurus.id = guru_mapel_kelas.guru_id").
+		Where("mata_pelajarans.is_active = ?", true)
+}
+
+func SesiGuru(db *gorm.DB, guruID uint, tahunAjaran, semester string) ([]Sesi, error) {
+	var rows []Sesi
+	err := baseSesiQuery(db).
+		Where("guru_mapel_kelas.guru_id = ? AND guru_mapel_kelas.tahun_ajaran = ? AND guru_mapel_kelas.semester = ?", guruID, tahunAjaran, semester).
+		Order("mata_pelajarans.hari, mata_pelajarans.jam_mulai").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// SesiPadaHari mengembalikan semua sesi mengajar pada hari tertentu untuk periode yang diberikan.
+func SesiPadaHari(db *gorm.DB, hari, tahunAjaran, semester string) ([]Sesi, error) {
+	var rows []Sesi
+	err := baseSesiQuery(db).
+		Where("mata_pelajarans.hari = ? AND guru_mapel_kelas.tahun_ajaran = ? AND guru_mapel_kelas.semester = ?", hari, tahunAjaran, semester).
+		Order("mata_pelajarans.jam_mulai").
+		Scan(&rows).Error
+	return rows, err
+}
+
+func KelasIDsSiswa(db *gorm.DB, siswaID uint) ([]uint, error) {
+	var utama []uint
+	if err := db.Table("siswas").Where("id = ? AND kelas_id IS NOT NULL", siswaID).Pluck("kelas_id", &utama).Error; err != nil {
+		return nil, err
+	}
+	var relasi []uint
+	if err := db.Table("kelas_siswas").Where("siswa_id = ?", siswaID).Pluck("kelas_id", &relasi).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[uint]struct{}{}
+	ids := make([]uint, 0, len(utama)+len(relasi))
+	for _, id := range append(utama, relasi...) {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SesiSiswa menurunkan jadwal siswa dari mapel yang diikuti (mapel_siswas)
+// dan penugasan guru di kelas siswa pada periode yang diberikan.
+func SesiSiswa(db *gorm.DB, siswaID uint, tahunAjaran, semester string) ([]Sesi, error) {
+	kelasIDs, err := KelasIDsSiswa(db, siswaID)
+	if err != nil {
+		return nil, err
+	}
+	if len(kelasIDs) == 0 {
+		return []Sesi{}, nil
+	}
+
+	var rows []Sesi
+	err = baseSesiQuery(db).
+		Joins("JOIN mapel_siswas ON mapel_siswas.mapel_id = guru_mapel_kelas.mapel_id").
+		Where("mapel_siswas.siswa_id = ? AND guru_mapel_kelas.kelas_id IN ? AND guru_mapel_kelas.tahun_ajaran = ? AND guru_mapel_kelas.semester = ?",
+			siswaID, kelasIDs, tahunAjaran, semester).
+		Order("mata_pelajarans.hari, mata_pelajarans.jam_mulai").
+		Scan(&rows).Error
+	return rows, err
+}
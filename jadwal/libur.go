@@ -0,0 +1,46 @@
+package jadwal
+
+import (
+	"time"
+
+	"abs-be/models"
+
+	"gorm.io/gorm"
+)
+
+func HariLiburAntara(db *gorm.DB, dari, sampai time.Time) ([]models.HariLibur, error) {
+	var libur []models.HariLibur
+	err := db.Where("tanggal_mulai <= ? AND tanggal_selesai >= ?", sampai.Format("2006-01-02"), dari.Format("2006-01-02")).
+		Order("tanggal_mulai").
+		Find(&libur).Error
+	return libur, err
+}
+
+func PembatalanAntara(db *gorm.DB, dari, sampai time.Time) ([]models.PembatalanJadwal, error) {
+	var batal []models.PembatalanJadwal
+	err := db.Where("tanggal BETWEEN ? AND ?", dari.Format("2006-01-02"), sampai.Format("2006-01-02")).
+		Order("tanggal").
+		Find(&batal).Error
+	return batal, err
+}
+
+// CekLibur mengembalikan keterangan libur jika tanggal jatuh pada hari libur sekolah.
+func CekLibur(db *gorm.DB, tanggal time.Time) (string, bool, error) {
+	libur, err := HariLiburAntara(db, tanggal, tanggal)
+	if err != nil {
+		return "", false, err
+	}
+	if len(libur) == 0 {
+		return "", false, nil
+	}
+	return libur[0].Keterangan, true, nil
+}
+
+func CekPembatalan(db *gorm.DB, mapelID, kelasID uint, tanggal time.Time) (string, bool, error) {
+	var batal models.PembatalanJadwal
+	res := db.Where("mapel_id = ? AND kelas_id = ? AND tanggal = ?", mapelID, kelasID, tanggal.Format("2006-01-02")).Limit(1).Find(&batal)
+	if res.Error != nil {
+		return "", false, res.Error
+	}
+	return batal.Alasan, res.RowsAffected > 0, nil
+}
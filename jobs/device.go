@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"abs-be/firebaseclient"
+)
+
+// RegisterBersihkanDeviceToken menghapus device token yang tidak terlihat
+// selama DEVICE_TOKEN_STALE_HARI (default 60) hari, diperiksa setiap
+// DEVICE_TOKEN_CLEANUP_INTERVAL_MENIT (default 1440) menit.
+func RegisterBersihkanDeviceToken() {
+	interval := envMenit("DEVICE_TOKEN_CLEANUP_INTERVAL_MENIT", 1440)
+	usang := envHari("DEVICE_TOKEN_STALE_HARI", 60)
+	Every("bersihkan_device_token", interval, func(ctx context.Context, now time.Time) error {
+		n, err := firebaseclient.BersihkanTokenUsang(ctx, now.Add(-usang))
+		if n > 0 {
+			log.Printf("jobs: %d device token usang dihapus", n)
+		}
+		return err
+	})
+}
@@ -0,0 +1,133 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"abs-be/database"
+	"abs-be/jadwal"
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/outbox"
+
+	"gorm.io/gorm"
+)
+
+const digestContohMaks = 3
+
+// RegisterDigest mengirim ringkasan notifikasi yang dipilih user masuk
+// digest, harian atau per jam sesuai pengaturan masing-masing.
+func RegisterDigest() {
+	interval := envMenit("NOTIF_DIGEST_INTERVAL_MENIT", 1)
+	Every("notif_digest", interval, func(ctx context.Context, now time.Time) error {
+		return kirimDigest(database.DB, now)
+	})
+}
+
+func kirimDigest(db *gorm.DB, now time.Time) error {
+	now = now.In(time.Local)
+
+	var principals []models.Principal
+	if err := db.Model(&models.NotifDigestAntrian{}).
+		Distinct("user_type", "user_id AS id").
+		Where("digest_at IS NULL").
+		Scan(&principals).Error; err != nil {
+		return err
+	}
+	if len(principals) == 0 {
+		return nil
+	}
+
+	for _, p := range principals {
+		pengaturan := models.NotifPengaturan{DigestFrekuensi: "harian", DigestJam: "07:00"}
+		if err := db.Where("user_type = ? AND user_id = ?", p.UserType, p.ID).
+			Limit(1).Find(&pengaturan).Error; err != nil {
+			return err
+		}
+		batas, err := batasDigest(pengaturan.DigestFrekuensi, pengaturan.DigestJam, now)
+		if err != nil {
+			log.Printf("jobs: digest %s/%d jam %q tidak valid: %v", p.UserType, p.ID, pengaturan.DigestJam, err)
+			continue
+		}
+		if err := kirimDigestPrincipal(db, p, pengaturan.DigestFrekuensi, batas, now); err != nil {
+			log.Printf("jobs: gagal kirim digest %s/%d: %v", p.UserType, p.ID, err)
+		}
+	}
+	return nil
+}
+
+// batasDigest mengembalikan jadwal kirim terakhir yang sudah lewat;
+// notifikasi yang masuk sebelum batas ini siap diringkas.
+func batasDigest(frekuensi, jam string, now time.Time) (time.Time, error) {
+	hariIni := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	jadwalHariIni, err := jadwal.WaktuSesi(hariIni, jam)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if frekuensi == "per_jam" {
+		batas := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), jadwalHariIni.Minute(), 0, 0, time.Local)
+		if now.Before(batas) {
+			batas = batas.Add(-time.Hour)
+		}
+		return batas, nil
+	}
+	if now.Before(jadwalHariIni) {
+		return jadwalHariIni.AddDate(0, 0, -1), nil
+	}
+	return jadwalHariIni, nil
+}
+
+func kirimDigestPrincipal(db *gorm.DB, p models.Principal, frekuensi string, batas, now time.Time) error {
+	var rows []models.NotifDigestAntrian
+	if err := db.Where("user_type = ? AND user_id = ? AND digest_at IS NULL AND created_at < ?", p.UserType, p.ID, batas).
+		Order("created_at DESC, id DESC").
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// rows terurut dari yang terbaru, jadi judul tiap type diambil dari
+	// notifikasi terakhir dan contoh berisi isi yang paling baru.
+	payload := notifikasi.DigestNotifikasi{
+		Frekuensi: frekuensi,
+		Sampai:    batas.Format("2006-01-02 15:04"),
+		Total:     len(rows),
+	}
+	idx := map[string]int{}
+	ids := make([]uint, 0, len(rows))
+	mulai := rows[0].CreatedAt
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+		if r.CreatedAt.Before(mulai) {
+			mulai = r.CreatedAt
+		}
+		i, ok := idx[r.Type]
+		if !ok {
+			i = len(payload.Ringkasan)
+			idx[r.Type] = i
+			payload.Ringkasan = append(payload.Ringkasan, notifikasi.DigestItem{Type: r.Type, Judul: r.Title})
+		}
+		item := &payload.Ringkasan[i]
+		item.Jumlah++
+		if len(item.Contoh) < digestContohMaks && r.Body != "" {
+			item.Contoh = append(item.Contoh, r.Body)
+		}
+	}
+	payload.Mulai = mulai.In(time.Local).Format("2006-01-02 15:04")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.NotifDigestAntrian{}).
+			Where("id IN ? AND digest_at IS NULL", ids).
+			Update("digest_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		return outbox.Enqueue(tx, p.UserType, payload, []uint{p.ID})
+	})
+}
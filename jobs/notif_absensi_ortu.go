@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"abs-be/database"
+	"abs-be/jadwal"
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/outbox"
+
+	"gorm.io/gorm"
+)
+
+type notifOrtuConfig struct {
+	jamKirim       string
+	intervalCek    time.Duration
+	jendelaSusulan time.Duration
+}
+
+func loadNotifOrtuConfig() notifOrtuConfig {
+	return notifOrtuConfig{
+		jamKirim:       envString("NOTIF_ABSENSI_ORTU_JAM", "14:00"),
+		intervalCek:    envMenit("NOTIF_ABSENSI_ORTU_INTERVAL_MENIT", 5),
+		jendelaSusulan: envMenit("NOTIF_ABSENSI_ORTU_SUSULAN_MENIT", 30),
+	}
+}
+
+// RegisterNotifAbsensiOrtu mengirim rangkuman alpa/terlambat ke orang tua.
+// Antrian diisi oleh hook AbsensiSiswa; semua entri seorang siswa pada
+// tanggal yang sama digabung menjadi satu pesan yang dikirim setelah
+// NOTIF_ABSENSI_ORTU_JAM.
+//
+// Entri yang dicatat atau statusnya berubah setelah rangkuman suatu tanggal
+// terkirim dikirim di hari yang sama sebagai pesan susulan, setelah
+// NOTIF_ABSENSI_ORTU_SUSULAN_MENIT (default 30) berlalu sejak entri tertua
+// yang menunggu dan sejak pesan terakhir. Entri yang masuk dalam jendela itu
+// digabung menjadi satu susulan.
+func RegisterNotifAbsensiOrtu() {
+	cfg := loadNotifOrtuConfig()
+	Every("notif_absensi_ortu", cfg.intervalCek, func(ctx context.Context, now time.Time) error {
+		return kirimNotifAbsensiOrtu(database.DB, cfg, now)
+	})
+}
+
+type antrianDetail struct {
+	models.AbsensiNotifAntrian
+	NamaSiswa string
+	NamaKelas string
+	NamaMapel *string
+	Susulan   bool
+}
+
+func kirimNotifAbsensiOrtu(db *gorm.DB, cfg notifOrtuConfig, now time.Time) error {
+	now = now.In(time.Local)
+	hariIni := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	batas, err := jadwal.WaktuSesi(hariIni, cfg.jamKirim)
+	if err != nil {
+		return fmt.Errorf("NOTIF_ABSENSI_ORTU_JAM: %w", err)
+	}
+
+	// Antrian yang belum pernah dikirim: hari-hari sebelumnya kapan saja
+	// (mis. tertunda karena server mati), hari ini setelah jam kirim.
+	// Susulan: setelah jendela susulan berlalu sejak pesan terakhir dan
+	// sejak entri tertua yang menunggu; seluruh entri tanggal tsb yang
+	// menunggu ikut dalam satu pesan.
+	hari := hariIni.Format("2006-01-02")
+	setelahJam := !now.Before(batas)
+	jendela := now.Add(-cfg.jendelaSusulan)
+	terakhir := `(SELECT MAX(b.sent_at) FROM absensi_notif_antrians b
+		WHERE b.siswa_id = a.siswa_id AND b.tanggal = a.tanggal)`
+	tertuaMenunggu := `(SELECT MIN(c.updated_at) FROM absensi_notif_antrians c
+		WHERE c.siswa_id = a.siswa_id AND c.tanggal = a.tanggal AND c.sent_at IS NULL)`
+	q := db.Table("absensi_notif_antrians AS a").
+		Joins("JOIN siswas s ON s.id = a.siswa_id").
+		Joins("JOIN kelas k ON k.id = a.kelas_id").
+		Joins("LEFT JOIN mata_pelajarans mp ON mp.id = a.mapel_id").
+		Select("a.*, s.nama AS nama_siswa, k.nama AS nama_kelas, mp.nama AS nama_mapel, "+terakhir+" IS NOT NULL AS susulan").
+		Where("a.sent_at IS NULL").
+		Where("("+terakhir+" IS NULL AND (a.tanggal < ? OR (? AND a.tanggal = ?))) OR ("+terakhir+" <= ? AND "+tertuaMenunggu+" <= ?)",
+			hari, setelahJam, hari, jendela, jendela)
+
+	var rows []antrianDetail
+	if err := q.Order("a.siswa_id, a.tanggal, a.id").Scan(&rows).Error; err != nil {
+		return fmt.Errorf("ambil antrian notifikasi absensi: %w", err)
+	}
+
+	type kunci struct {
+		siswaID uint
+		tanggal string
+	}
+	grup := make(map[kunci][]antrianDetail)
+	var urutan []kunci
+	for _, r := range rows {
+		k := kunci{r.SiswaID, r.Tanggal.Format("2006-01-02")}
+		if _, ok := grup[k]; !ok {
+			urutan = append(urutan, k)
+		}
+		grup[k] = append(grup[k], r)
+	}
+
+	for _, k := range urutan {
+		items := grup[k]
+		ids := make([]uint, 0, len(items))
+		for _, it := range items {
+			ids = append(ids, it.ID)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.AbsensiNotifAntrian{}).
+				Where("id IN ?", ids).
+				Update("sent_at", now).Error; err != nil {
+				return fmt.Errorf("tandai antrian terkirim: %w", err)
+			}
+			return kirimRangkumanAbsensi(tx, k.siswaID, k.tanggal, items)
+		})
+		if err != nil {
+			log.Printf("notif ortu: siswa %d tanggal %s: %v", k.siswaID, k.tanggal, err)
+		}
+	}
+	return nil
+}
+
+func kirimRangkumanAbsensi(db *gorm.DB, siswaID uint, tanggal string, items []antrianDetail) error {
+	var ortuIDs []uint
+	if err := db.Table("orang_tua_siswas").
+		Where("siswa_id = ?", siswaID).
+		Pluck("orang_tua_id", &ortuIDs).Error; err != nil {
+		return fmt.Errorf("ambil orang tua: %w", err)
+	}
+	penerima := ortuIDs
+	penerimaRole := "orang_tua"
+	if len(penerima) == 0 {
+		penerima = []uint{siswaID}
+		penerimaRole = "siswa"
+	}
+
+	p := notifikasi.AbsensiSiswaOrtu{
+		SiswaID:   siswaID,
+		SiswaNama: items[0].NamaSiswa,
+		Tanggal:   tanggal,
+		Detail:    make([]notifikasi.AbsensiOrtuItem, 0, len(items)),
+		Susulan:   items[0].Susulan,
+	}
+	for _, it := range items {
+		d := notifikasi.AbsensiOrtuItem{
+			AbsensiID:   it.AbsensiID,
+			TipeAbsensi: it.TipeAbsensi,
+			KelasID:     it.KelasID,
+			Kelas:       it.NamaKelas,
+			Status:      it.Status,
+		}
+		if it.MapelID != nil && it.NamaMapel != nil {
+			d.MapelID = *it.MapelID
+			d.Mapel = *it.NamaMapel
+		}
+		p.Detail = append(p.Detail, d)
+	}
+	return outbox.Enqueue(db, penerimaRole, p, penerima)
+}
@@ -0,0 +1,19 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"abs-be/database"
+	"abs-be/outbox"
+)
+
+// RegisterOutbox mengirim notifikasi dari outbox setiap
+// OUTBOX_POLL_DETIK (default 5) detik.
+func RegisterOutbox() {
+	cfg := outbox.LoadConfig()
+	interval := envDetik("OUTBOX_POLL_DETIK", 5)
+	Every("outbox", interval, func(ctx context.Context, now time.Time) error {
+		return outbox.ProsesBatch(ctx, database.DB, cfg, now)
+	})
+}
@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"abs-be/database"
+	"abs-be/pengumuman"
+)
+
+// RegisterPengumuman menerbitkan pengumuman terjadwal yang waktunya sudah
+// tiba, dicek setiap PENGUMUMAN_INTERVAL_MENIT (default 1) menit.
+func RegisterPengumuman() {
+	interval := envMenit("PENGUMUMAN_INTERVAL_MENIT", 1)
+	Every("pengumuman_terjadwal", interval, func(ctx context.Context, now time.Time) error {
+		return pengumuman.TerbitkanTerjadwal(database.DB, now)
+	})
+}
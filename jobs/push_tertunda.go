@@ -0,0 +1,16 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"abs-be/firebaseclient"
+)
+
+// RegisterPushTertunda melepas push yang ditahan selama jam tenang user.
+func RegisterPushTertunda() {
+	interval := envMenit("NOTIF_PUSH_TERTUNDA_INTERVAL_MENIT", 1)
+	Every("push_tertunda", interval, func(ctx context.Context, now time.Time) error {
+		return firebaseclient.KirimPushTertunda(ctx, now)
+	})
+}
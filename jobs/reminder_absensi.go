@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"abs-be/database"
+	"abs-be/jadwal"
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/outbox"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type reminderConfig struct {
+	delay       time.Duration
+	eskalasi    time.Duration
+	batasKelas  string
+	intervalCek time.Duration
+}
+
+func loadReminderConfig() reminderConfig {
+	return reminderConfig{
+		delay:       envMenit("REMINDER_ABSENSI_DELAY_MENIT", 30),
+		eskalasi:    envMenit("REMINDER_ABSENSI_ESKALASI_MENIT", 120),
+		batasKelas:  envString("REMINDER_ABSENSI_KELAS_JAM", "08:00"),
+		intervalCek: envMenit("REMINDER_ABSENSI_INTERVAL_MENIT", 5),
+	}
+}
+
+func RegisterReminderAbsensi() {
+	cfg := loadReminderConfig()
+	Every("reminder_absensi", cfg.intervalCek, func(ctx context.Context, now time.Time) error {
+		return cekAbsensiBelumDiisi(ctx, database.DB, cfg, now)
+	})
+}
+
+func cekAbsensiBelumDiisi(ctx context.Context, db *gorm.DB, cfg reminderConfig, now time.Time) error {
+	now = now.In(time.Local)
+	tanggal := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if now.Weekday() == time.Sunday {
+		return nil
+	}
+	if _, libur, err := jadwal.CekLibur(db, tanggal); err != nil {
+		return err
+	} else if libur {
+		return nil
+	}
+
+	if err := cekAbsensiMapel(ctx, db, cfg, now, tanggal); err != nil {
+		return err
+	}
+	return cekAbsensiKelas(ctx, db, cfg, now, tanggal)
+}
+
+func cekAbsensiMapel(ctx context.Context, db *gorm.DB, cfg reminderConfig, now, tanggal time.Time) error {
+	sesi, err := jadwal.SesiPadaHari(db, jadwal.NamaHari(tanggal), jadwal.TahunAjaran(tanggal), jadwal.Semester(tanggal))
+	if err != nil {
+		return fmt.Errorf("ambil sesi hari ini: %w", err)
+	}
+
+	for _, s := range sesi {
+		selesai, err := jadwal.WaktuSesi(tanggal, s.JamSelesai)
+		if err != nil {
+			log.Printf("reminder: jam selesai mapel %d tidak valid: %v", s.MapelID, err)
+			continue
+		}
+		if now.Before(selesai.Add(cfg.delay)) {
+			continue
+		}
+		if _, batal, err := jadwal.CekPembatalan(db, s.MapelID, s.KelasID, tanggal); err != nil {
+			return err
+		} else if batal {
+			continue
+		}
+
+		roster, err := jadwal.RosterMapel(db, s.KelasID, s.MapelID)
+		if err != nil {
+			return err
+		}
+		if len(roster) == 0 {
+			continue
+		}
+		mapelID := s.MapelID
+		terisi, err := jadwal.JumlahTerisi(db, s.KelasID, &mapelID, tanggal, roster)
+		if err != nil {
+			return err
+		}
+		if terisi > 0 {
+			continue
+		}
+
+		p := notifikasi.ReminderAbsensiMapel{
+			KelasID:    s.KelasID,
+			KelasNama:  s.KelasNama,
+			MapelID:    s.MapelID,
+			MapelNama:  s.MapelNama,
+			GuruID:     s.GuruID,
+			GuruNama:   s.GuruNama,
+			Tanggal:    tanggal.Format("2006-01-02"),
+			JamMulai:   s.JamMulai,
+			JamSelesai: s.JamSelesai,
+			DeepLink:   jadwal.DeepLinkAbsensi(s.KelasID, s.MapelID, tanggal),
+		}
+
+		kirimReminder(db, "mapel", s.KelasID, s.MapelID, s.GuruID, tanggal, "pengingat", "guru", p, []uint{s.GuruID})
+		if !now.Before(selesai.Add(cfg.eskalasi)) {
+			kirimReminder(db, "mapel", s.KelasID, s.MapelID, s.GuruID, tanggal, "eskalasi", "admin", notifikasi.EskalasiAbsensiMapel(p), adminIDs(db))
+		}
+	}
+	return nil
+}
+
+func cekAbsensiKelas(ctx context.Context, db *gorm.DB, cfg reminderConfig, now, tanggal time.Time) error {
+	batas, err := jadwal.WaktuSesi(tanggal, cfg.batasKelas)
+	if err != nil {
+		return fmt.Errorf("REMINDER_ABSENSI_KELAS_JAM: %w", err)
+	}
+	if now.Before(batas.Add(cfg.delay)) {
+		return nil
+	}
+
+	var kelasList []models.Kelas
+	if err := db.Preload("WaliKelas").
+		Where("wali_kelas_id IS NOT NULL AND tahun_ajaran = ?", jadwal.TahunAjaran(tanggal)).
+		Find(&kelasList).Error; err != nil {
+		return fmt.Errorf("ambil kelas: %w", err)
+	}
+
+	for _, k := range kelasList {
+		roster, err := jadwal.SiswaIDsKelas(db, k.ID)
+		if err != nil {
+			return err
+		}
+		if len(roster) == 0 {
+			continue
+		}
+		terisi, err := jadwal.JumlahTerisi(db, k.ID, nil, tanggal, roster)
+		if err != nil {
+			return err
+		}
+		if terisi > 0 {
+			continue
+		}
+
+		namaWali := ""
+		if k.WaliKelas != nil {
+			namaWali = k.WaliKelas.Nama
+		}
+		p := notifikasi.ReminderAbsensiKelas{
+			KelasID:       k.ID,
+			KelasNama:     k.Nama,
+			WaliKelasID:   *k.WaliKelasID,
+			WaliKelasNama: namaWali,
+			Tanggal:       tanggal.Format("2006-01-02"),
+			DeepLink:      jadwal.DeepLinkAbsensi(k.ID, 0, tanggal),
+		}
+
+		kirimReminder(db, "kelas", k.ID, 0, 0, tanggal, "pengingat", "wali_kelas", p, []uint{*k.WaliKelasID})
+		if !now.Before(batas.Add(cfg.eskalasi)) {
+			kirimReminder(db, "kelas", k.ID, 0, 0, tanggal, "eskalasi", "admin", notifikasi.EskalasiAbsensiKelas(p), adminIDs(db))
+		}
+	}
+	return nil
+}
+
+// tandaiReminder mencatat reminder sekali per (tipe, kelas, mapel, guru,
+// tanggal, level). Mengembalikan false jika reminder yang sama sudah pernah
+// dikirim.
+func tandaiReminder(db *gorm.DB, tipe string, kelasID, mapelID, guruID uint, tanggal time.Time, level string) bool {
+	r := models.AbsensiReminder{
+		TipeAbsensi: tipe,
+		KelasID:     kelasID,
+		MapelID:     mapelID,
+		GuruID:      guruID,
+		Tanggal:     tanggal,
+		Level:       level,
+	}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&r)
+	if res.Error != nil {
+		log.Printf("reminder: gagal mencatat reminder %s/%d/%d: %v", tipe, kelasID, mapelID, res.Error)
+		return false
+	}
+	return res.RowsAffected > 0
+}
+
+// kirimReminder mencatat reminder dan memasukkan notifikasinya ke outbox
+// dalam satu transaksi, sehingga reminder yang tercatat pasti terkirim.
+func kirimReminder(db *gorm.DB, tipe string, kelasID, mapelID, guruID uint, tanggal time.Time, level, role string, p notifikasi.Payload, ids []uint) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !tandaiReminder(tx, tipe, kelasID, mapelID, guruID, tanggal, level) {
+			return nil
+		}
+		return outbox.Enqueue(tx, role, p, ids)
+	})
+	if err != nil {
+		log.Printf("reminder: gagal menyimpan %s %s %d/%d: %v", level, tipe, kelasID, mapelID, err)
+	}
+}
+
+func adminIDs(db *gorm.DB) []uint {
+	var ids []uint
+	if err := db.Table("admins").Pluck("id", &ids).Error; err != nil {
+		log.Printf("reminder: gagal ambil admin ids: %v", err)
+	}
+	return ids
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context, now time.Time) error
+}
+
+var (
+	mu         sync.Mutex
+	registered []job
+)
+
+// Every mendaftarkan job periodik. Dipanggil sebelum Start.
+func Every(name string, interval time.Duration, run func(ctx context.Context, now time.Time) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered = append(registered, job{name: name, interval: interval, run: run})
+}
+
+func Start(ctx context.Context) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, j := range registered {
+		go loop(ctx, j)
+	}
+	log.Printf("jobs: %d job terjadwal dijalankan", len(registered))
+}
+
+func loop(ctx context.Context, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			runOnce(ctx, j, now)
+		}
+	}
+}
+
+func runOnce(ctx context.Context, j job, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("jobs: panic di job %s: %v", j.name, r)
+		}
+	}()
+	if err := j.run(ctx, now); err != nil {
+		log.Printf("jobs: job %s gagal: %v", j.name, err)
+	}
+}
+
+func envMenit(key string, def int) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Minute
+		}
+		log.Printf("jobs: nilai %s tidak valid (%q), memakai default %d menit", key, v, def)
+	}
+	return time.Duration(def) * time.Minute
+}
+
+func envDetik(key string, def int) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+		log.Printf("jobs: nilai %s tidak valid (%q), memakai default %d detik", key, v, def)
+	}
+	return time.Duration(def) * time.Second
+}
+
+func envHari(key string, def int) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour
+		}
+		log.Printf("jobs: nilai %s tidak valid (%q), memakai default %d hari", key, v, def)
+	}
+	return time.Duration(def) * 24 * time.Hour
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/utils"
+)
+
+// RegisterBersihkanSesi menghapus sesi yang access token-nya (claim exp)
+// sudah lewat dan tidak lagi punya refresh token yang bisa dipakai, setiap
+// SESSION_PURGE_INTERVAL_MENIT (default 60) menit. Refresh token yang sudah
+// kadaluarsa lebih dari REFRESH_TOKEN_RETENSI_HARI (default 7) hari dan
+// tiket stream yang tidak terpakai ikut dihapus.
+func RegisterBersihkanSesi() {
+	interval := envMenit("SESSION_PURGE_INTERVAL_MENIT", 60)
+	retensi := envHari("REFRESH_TOKEN_RETENSI_HARI", 7)
+	Every("bersihkan_sesi", interval, func(ctx context.Context, now time.Time) error {
+		if err := isiExpSesi(); err != nil {
+			return err
+		}
+		n, err := hapusSesiBerakhir(now)
+		if n > 0 {
+			log.Printf("jobs: %d sesi berakhir dihapus", n)
+		}
+		if err != nil {
+			return err
+		}
+		if err := database.DB.Where("expires_at < ?", now).Delete(&models.StreamTicket{}).Error; err != nil {
+			return err
+		}
+		return database.DB.Where("expires_at < ?", now.Add(-retensi)).
+			Delete(&models.RefreshToken{}).Error
+	})
+}
+
+// isiExpSesi mengisi expires_at sesi lama dari claim exp token-nya.
+func isiExpSesi() error {
+	for {
+		var rows []models.Session
+		if err := database.DB.Select("id", "token").
+			Where("expires_at IS NULL").Limit(500).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, s := range rows {
+			exp, err := utils.ExpToken(s.Token)
+			if err != nil {
+				// Token tanpa exp yang terbaca dianggap sudah berakhir.
+				exp = time.Unix(0, 0)
+			}
+			if err := database.DB.Model(&models.Session{}).Where("id = ?", s.ID).
+				UpdateColumn("expires_at", exp).Error; err != nil {
+				return err
+			}
+		}
+		if len(rows) < 500 {
+			return nil
+		}
+	}
+}
+
+func hapusSesiBerakhir(now time.Time) (int, error) {
+	total := 0
+	for {
+		var ids []uint
+		if err := database.DB.Model(&models.Session{}).
+			Where("expires_at < ?", now).
+			Where(`NOT EXISTS (SELECT 1 FROM refresh_tokens rt WHERE rt.session_id = sessions.id
+				AND rt.used_at IS NULL AND rt.revoked_at IS NULL AND rt.expires_at > ?)`, now).
+			Limit(500).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		if err := database.DB.Model(&models.RefreshToken{}).
+			Where("session_id IN ? AND revoked_at IS NULL", ids).
+			Update("revoked_at", now).Error; err != nil {
+			return total, err
+		}
+		if err := database.DB.Where("id IN ?", ids).Delete(&models.Session{}).Error; err != nil {
+			return total, err
+		}
+		total += len(ids)
+		if len(ids) < 500 {
+			return total, nil
+		}
+	}
+}
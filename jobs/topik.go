@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"abs-be/firebaseclient"
+)
+
+// RegisterSinkronTopik merekonsiliasi langganan topic FCM setiap
+// NOTIF_TOPIK_INTERVAL_MENIT (default 60) menit, menutup sinkronisasi
+// langsung yang gagal atau perubahan data di luar API.
+func RegisterSinkronTopik() {
+	interval := envMenit("NOTIF_TOPIK_INTERVAL_MENIT", 60)
+	Every("sinkron_topik", interval, func(ctx context.Context, now time.Time) error {
+		return firebaseclient.SinkronSemuaTopik(ctx)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"abs-be/database"
 	"abs-be/models"
@@ -13,6 +14,13 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// bolehSaatWajibGantiPassword adalah endpoint yang tetap bisa dipakai sesi
+// yang wajib mengganti password.
+var bolehSaatWajibGantiPassword = map[string]bool{
+	"/api/password/change": true,
+	"/api/logout":          true,
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -58,6 +66,38 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Sesi akun yang dinonaktifkan sudah dicabut saat penonaktifan; cek
+		// ini menutup sesi yang terbuat bersamaan dengan penonaktifan.
+		p := models.NewPrincipal(session.Role, session.UserID)
+		var nonaktif int64
+		if err := database.DB.Table(models.TabelAkun(p.UserType)).
+			Where("id = ? AND disabled_at IS NOT NULL", p.ID).
+			Count(&nonaktif).Error; err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa status akun")
+			c.Abort()
+			return
+		}
+		if nonaktif > 0 {
+			utils.ErrorResponse(c, http.StatusForbidden, "akun dinonaktifkan, hubungi admin")
+			c.Abort()
+			return
+		}
+
+		// Aktivitas terakhir dicatat paling sering sekali per menit agar
+		// tidak menulis ke database di setiap request.
+		now := time.Now()
+		if session.LastSeenAt == nil || now.Sub(*session.LastSeenAt) >= time.Minute || session.IP != c.ClientIP() {
+			database.DB.Model(&models.Session{}).Where("id = ?", session.ID).
+				UpdateColumns(map[string]interface{}{"last_seen_at": now, "ip": c.ClientIP()})
+			session.LastSeenAt, session.IP = &now, c.ClientIP()
+		}
+
+		if session.MustChangePassword && !bolehSaatWajibGantiPassword[c.FullPath()] {
+			utils.ErrorResponse(c, http.StatusForbidden, "password harus diganti terlebih dahulu")
+			c.Abort()
+			return
+		}
+
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
 			c.Set("user", claims)
 			c.Set("tokenString", tokenString)
@@ -65,6 +105,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Set("tokenString", tokenString)
 			c.Set("user_id", session.UserID)
 			c.Set("role", session.Role)
+			c.Set("roles", session.DaftarRole())
 			c.Set("session", session)
 			c.Next()
 		}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"abs-be/akses"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionMiddleware meloloskan request bila salah satu role sesi
+// memiliki salah satu izin perms. Harus dipasang setelah AuthMiddleware.
+func PermissionMiddleware(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var roles []string
+		if v, ok := c.Get("roles"); ok {
+			roles, _ = v.([]string)
+		}
+		if len(roles) == 0 {
+			utils.ErrorResponse(c, http.StatusForbidden, "akses ditolak: role tidak tersedia")
+			c.Abort()
+			return
+		}
+
+		boleh, err := akses.Punya(roles, perms...)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "gagal memeriksa izin akses")
+			c.Abort()
+			return
+		}
+		if !boleh {
+			utils.ErrorResponse(c, http.StatusForbidden, "akses ditolak: membutuhkan izin "+strings.Join(perms, " atau "))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
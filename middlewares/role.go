@@ -35,10 +35,20 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 
 		allowedRolesStr := strings.Join(allowedRoles, ", ")
 
-		for _, role := range allowedRoles {
-			if userRole == role {
-				c.Next()
-				return
+		// Sesi bisa memiliki beberapa role (mis. guru sekaligus wali kelas);
+		// cukup salah satunya yang diizinkan.
+		userRoles := []string{userRole}
+		if roles, ok := c.Get("roles"); ok {
+			if rs, ok := roles.([]string); ok && len(rs) > 0 {
+				userRoles = rs
+			}
+		}
+		for _, r := range userRoles {
+			for _, role := range allowedRoles {
+				if r == role {
+					c.Next()
+					return
+				}
 			}
 		}
 
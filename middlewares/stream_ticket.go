@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StreamTicketMiddleware menukar query ticket dengan sesi pemiliknya untuk
+// endpoint stream, karena EventSource di browser tidak bisa mengatur header
+// Authorization. Tiket dihapus saat dipakai sehingga hanya berlaku sekali;
+// access token tidak pernah muncul di URL maupun log request. Dipasang
+// sebelum AuthMiddleware, yang tetap memeriksa sesi dan token-nya.
+func StreamTicketMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+		tiket := c.Query("ticket")
+		if tiket == "" {
+			c.Next()
+			return
+		}
+
+		var session models.Session
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			var t models.StreamTicket
+			if err := tx.Where("ticket_hash = ? AND expires_at > ?", utils.HashToken(tiket), time.Now()).
+				First(&t).Error; err != nil {
+				return err
+			}
+			// RowsAffected memastikan tiket tidak dipakai dua kali oleh
+			// request yang bersamaan.
+			res := tx.Where("id = ?", t.ID).Delete(&models.StreamTicket{})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			return tx.Where("id = ?", t.SessionID).First(&session).Error
+		})
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "tiket stream tidak valid atau sudah kadaluarsa")
+			c.Abort()
+			return
+		}
+		c.Request.Header.Set("Authorization", "Bearer "+session.Token)
+		c.Next()
+	}
+}
@@ -2,6 +2,9 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AbsensiSiswa struct {
@@ -39,3 +42,50 @@ type AbsensiResult struct {
 	TahunAjaran string  `json:"tahun_ajaran"`
 	Semester    string  `json:"semester"`
 }
+
+// AbsensiNotifAntrian menampung status alpa/terlambat yang belum diberitahukan
+// ke orang tua, supaya satu siswa hanya menerima satu pesan per hari.
+type AbsensiNotifAntrian struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	AbsensiID   uint       `gorm:"not null;uniqueIndex" json:"absensi_id"`
+	SiswaID     uint       `gorm:"not null;index:idx_antrian_siswa_tanggal" json:"siswa_id"`
+	Tanggal     time.Time  `gorm:"type:date;not null;index:idx_antrian_siswa_tanggal" json:"tanggal"`
+	KelasID     uint       `gorm:"not null" json:"kelas_id"`
+	MapelID     *uint      `json:"mapel_id,omitempty"`
+	TipeAbsensi string     `gorm:"type:enum('kelas','mapel');not null" json:"tipe_absensi"`
+	Status      string     `gorm:"type:enum('alpa','terlambat');not null" json:"status"`
+	SentAt      *time.Time `gorm:"index" json:"sent_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func perluDiberitahukan(status string) bool {
+	return status == "alpa" || status == "terlambat"
+}
+
+func (a *AbsensiSiswa) AfterSave(tx *gorm.DB) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	if !perluDiberitahukan(a.Status) {
+		return db.Where("absensi_id = ? AND sent_at IS NULL", a.ID).Delete(&AbsensiNotifAntrian{}).Error
+	}
+	row := AbsensiNotifAntrian{
+		AbsensiID:   a.ID,
+		SiswaID:     a.SiswaID,
+		Tanggal:     a.Tanggal,
+		KelasID:     a.KelasID,
+		MapelID:     a.MapelID,
+		TipeAbsensi: a.TipeAbsensi,
+		Status:      a.Status,
+	}
+	// Perubahan status membuat entri dikirim ulang (sebagai susulan) walau
+	// status lamanya sudah terkirim. sent_at diset lebih dulu karena MySQL
+	// mengevaluasi assignment berurutan.
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "absensi_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "sent_at"}, Value: gorm.Expr("IF(status <> VALUES(status), NULL, sent_at)")},
+			{Column: clause.Column{Name: "status"}, Value: gorm.Expr("VALUES(status)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("VALUES(updated_at)")},
+		},
+	}).Create(&row).Error
+}
@@ -5,10 +5,25 @@ import (
 )
 
 type Admin struct {
-	ID        uint   `gorm:"primaryKey"`
-	Nama      string `gorm:"type:varchar(100);not null"`
-	Email     string `gorm:"type:varchar(100);unique;not null"`
-	Password  string `gorm:"type:varchar(255);not null"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Nama               string     `gorm:"type:varchar(100);not null" json:"nama"`
+	Email              string     `gorm:"type:varchar(100);unique;not null" json:"email"`
+	Password           string     `gorm:"type:varchar(255);not null" json:"-"`
+	MustChangePassword bool       `gorm:"not null;default:false" json:"must_change_password"`
+	DisabledAt         *time.Time `json:"disabled_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// AdminInvite adalah tautan undangan sekali pakai untuk mendaftar sebagai
+// admin. Hanya hash token yang disimpan.
+type AdminInvite struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	TokenHash  string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	Email      string     `gorm:"type:varchar(100)" json:"email,omitempty"`
+	DibuatOleh uint       `gorm:"not null" json:"dibuat_oleh"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at"`
+	UsedBy     *uint      `json:"used_by"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
@@ -3,10 +3,20 @@ package models
 import "time"
 
 type DeviceToken struct {
-	ID        uint   `gorm:"primaryKey" json:"id"`
-	UserID    uint   `gorm:"index" json:"user_id"` 
-	Token     string `gorm:"size:512;uniqueIndex:idx_user_token" json:"token"`
-	Platform  string `gorm:"size:50" json:"platform,omitempty"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index:idx_device_tokens_principal,priority:2" json:"user_id"`
+	UserType   string     `gorm:"size:20;index:idx_device_tokens_principal,priority:1" json:"user_type"`
+	Token      string     `gorm:"size:512;uniqueIndex:idx_user_token" json:"token"`
+	Platform   string     `gorm:"size:50" json:"platform,omitempty"`
+	AppVersion string     `gorm:"size:50" json:"app_version,omitempty"`
+	LastSeenAt *time.Time `gorm:"index:idx_device_tokens_last_seen" json:"last_seen_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// DeviceTokenTopik mencatat topic FCM yang sudah dilanggan sebuah token.
+type DeviceTokenTopik struct {
+	Token     string    `gorm:"primaryKey;size:512" json:"token"`
+	Topic     string    `gorm:"primaryKey;size:200;index:idx_device_token_topiks_topic" json:"topic"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EmailLog mencatat hasil pengiriman email per penerima.
+type EmailLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Type      string    `gorm:"size:100;index" json:"type"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	UserType  string    `gorm:"size:20" json:"user_type"`
+	Role      string    `gorm:"size:20" json:"role,omitempty"`
+	Email     string    `gorm:"size:100" json:"email"`
+	Subject   string    `gorm:"size:255" json:"subject"`
+	Lampiran  int       `json:"lampiran"`
+	Status    string    `gorm:"type:enum('terkirim','gagal');not null" json:"status"`
+	Error     string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -5,19 +5,21 @@ import (
 )
 
 type Guru struct {
-	ID           uint       `gorm:"primaryKey" json:"id"`
-	Nama         string     `gorm:"type:varchar(100);not null" json:"nama"`
-	NIP          string     `gorm:"column:nip;type:varchar(30);unique;not null" json:"nip"`
-	NIK          string     `gorm:"column:nik;type:varchar(30);unique;not null" json:"nik"`
-	Email        string     `gorm:"type:varchar(100);unique;not null" json:"email"`
-	Telepon      string     `gorm:"type:varchar(20)" json:"telepon,omitempty"`
-	Alamat       string     `gorm:"type:text" json:"alamat,omitempty"`
-	JenisKelamin string     `gorm:"type:enum('L','P');not null" json:"jenis_kelamin"`
-	Password     string     `gorm:"type:varchar(255);not null" json:"-"`
-	GuruRoles    []GuruRole `gorm:"foreignKey:GuruID" json:"guru_roles,omitempty"`
-	KelasWali    []Kelas    `gorm:"foreignKey:WaliKelasID" json:"kelas_wali,omitempty"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Nama               string     `gorm:"type:varchar(100);not null" json:"nama"`
+	NIP                string     `gorm:"column:nip;type:varchar(30);unique;not null" json:"nip"`
+	NIK                string     `gorm:"column:nik;type:varchar(30);unique;not null" json:"nik"`
+	Email              string     `gorm:"type:varchar(100);unique;not null" json:"email"`
+	Telepon            string     `gorm:"type:varchar(20)" json:"telepon,omitempty"`
+	Alamat             string     `gorm:"type:text" json:"alamat,omitempty"`
+	JenisKelamin       string     `gorm:"type:enum('L','P');not null" json:"jenis_kelamin"`
+	Password           string     `gorm:"type:varchar(255);not null" json:"-"`
+	MustChangePassword bool       `gorm:"not null;default:false" json:"must_change_password"`
+	DisabledAt         *time.Time `json:"disabled_at"`
+	GuruRoles          []GuruRole `gorm:"foreignKey:GuruID" json:"guru_roles,omitempty"`
+	KelasWali          []Kelas    `gorm:"foreignKey:WaliKelasID" json:"kelas_wali,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 type GuruRole struct {
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type HariLibur struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	TanggalMulai   time.Time `gorm:"type:date;not null;index" json:"tanggal_mulai"`
+	TanggalSelesai time.Time `gorm:"type:date;not null;index" json:"tanggal_selesai"`
+	Keterangan     string    `gorm:"type:varchar(255);not null" json:"keterangan"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type PembatalanJadwal struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MapelID   uint      `gorm:"not null;uniqueIndex:idx_pembatalan" json:"mapel_id"`
+	KelasID   uint      `gorm:"not null;uniqueIndex:idx_pembatalan" json:"kelas_id"`
+	Tanggal   time.Time `gorm:"type:date;not null;uniqueIndex:idx_pembatalan" json:"tanggal"`
+	Alasan    string    `gorm:"type:varchar(255)" json:"alasan,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CalendarFeedToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Role      string     `gorm:"type:enum('guru','siswa');not null" json:"role"`
+	TokenHash string     `gorm:"type:char(64);unique;not null" json:"-"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// LoginAttempt mencatat setiap percobaan login, berhasil maupun gagal.
+// UserType/UserID kosong bila identifier tidak cocok dengan akun mana pun.
+type LoginAttempt struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserType   string    `gorm:"size:20;index:idx_login_attempts_principal,priority:1" json:"user_type,omitempty"`
+	UserID     *uint     `gorm:"index:idx_login_attempts_principal,priority:2" json:"user_id,omitempty"`
+	Identifier string    `gorm:"size:100;not null" json:"identifier"`
+	IP         string    `gorm:"column:ip;size:45;not null;index:idx_login_attempts_ip,priority:1" json:"ip"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+	Berhasil   bool      `gorm:"not null" json:"berhasil"`
+	Alasan     string    `gorm:"size:30" json:"alasan,omitempty"`
+	CreatedAt  time.Time `gorm:"index:idx_login_attempts_ip,priority:2" json:"created_at"`
+}
+
+// LoginLockout menyimpan jumlah gagal login berturut-turut sebuah identifier
+// (email, NISN atau NIP, huruf kecil) dan sampai kapan identifier tsb tidak
+// bisa dipakai login. Identifier yang tidak terdaftar ikut dicatat agar
+// penguncian tidak membedakan akun yang ada dan yang tidak.
+type LoginLockout struct {
+	Identifier     string     `gorm:"primaryKey;size:100" json:"identifier"`
+	Gagal          int        `gorm:"not null;default:0" json:"gagal"`
+	TerkunciSampai *time.Time `json:"terkunci_sampai"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// NotifDeliveryReceipt mencatat satu upaya kirim FCM ke satu token atau
+// topic. OutboxID terisi bila pengiriman berasal dari outbox, dan
+// NotificationID menunjuk baris inbox penerima bila diketahui.
+type NotifDeliveryReceipt struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OutboxID       *uint     `gorm:"index:idx_receipt_outbox" json:"outbox_id,omitempty"`
+	NotificationID *uint     `gorm:"index:idx_receipt_notification" json:"notification_id,omitempty"`
+	Type           string    `gorm:"size:100" json:"type"`
+	UserType       string    `gorm:"size:20;index:idx_receipt_principal,priority:1" json:"user_type,omitempty"`
+	UserID         uint      `gorm:"index:idx_receipt_principal,priority:2" json:"user_id,omitempty"`
+	Token          string    `gorm:"size:512" json:"token,omitempty"`
+	Topic          string    `gorm:"size:200" json:"topic,omitempty"`
+	Sender         string    `gorm:"size:20" json:"sender"`
+	MessageID      string    `gorm:"size:255" json:"message_id,omitempty"`
+	Success        bool      `json:"success"`
+	ErrorCode      string    `gorm:"size:50" json:"error_code,omitempty"`
+	ErrorMessage   string    `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt      time.Time `gorm:"index:idx_receipt_created" json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	OutboxPending  = "pending"
+	OutboxProses   = "proses"
+	OutboxTerkirim = "terkirim"
+	OutboxDead     = "dead"
+)
+
+// NotifOutbox adalah notifikasi yang menunggu dikirim. Baris ditulis dalam
+// transaksi yang sama dengan perubahan data, lalu dikirim oleh worker.
+type NotifOutbox struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Type          string     `gorm:"size:100;not null;index" json:"type"`
+	Role          string     `gorm:"size:20" json:"role,omitempty"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Recipients    string     `gorm:"type:text;not null" json:"recipients"`
+	Lampiran      string     `gorm:"type:longtext" json:"-"`
+	Selesai       string     `gorm:"type:text" json:"selesai,omitempty"`
+	Status        string     `gorm:"type:enum('pending','proses','terkirim','dead');not null;default:'pending';index:idx_outbox_status_next" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_status_next" json:"next_attempt_at"`
+	LockedAt      *time.Time `json:"locked_at,omitempty"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
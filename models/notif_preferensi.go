@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// NotifPreferensi menyimpan pilihan user per type dan channel. Channel
+// "semua" berlaku untuk seluruh channel type tsb; baris yang lebih spesifik
+// menang. Tanpa baris apa pun notifikasi dianggap aktif.
+type NotifPreferensi struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserType  string    `gorm:"size:20;not null;uniqueIndex:idx_notif_pref" json:"user_type"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_notif_pref" json:"user_id"`
+	Type      string    `gorm:"size:100;not null;uniqueIndex:idx_notif_pref" json:"type"`
+	Channel   string    `gorm:"size:20;not null;uniqueIndex:idx_notif_pref" json:"channel"`
+	Aktif     bool      `gorm:"not null" json:"aktif"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotifJamTenang adalah rentang jam (HH:MM, boleh melewati tengah malam)
+// di mana push ditahan dan baru dikirim setelah rentang berakhir.
+type NotifJamTenang struct {
+	UserType  string    `gorm:"primaryKey;size:20" json:"user_type"`
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Mulai     string    `gorm:"type:char(5);not null" json:"mulai"`
+	Selesai   string    `gorm:"type:char(5);not null" json:"selesai"`
+	Aktif     bool      `gorm:"not null" json:"aktif"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotifTipeWajib adalah type yang diwajibkan admin: preferensi dan jam
+// tenang user diabaikan dan tidak dapat diubah.
+type NotifTipeWajib struct {
+	Type      string    `gorm:"primaryKey;size:100" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotifPushTertunda adalah push yang ditahan selama jam tenang penerima.
+type NotifPushTertunda struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserType     string     `gorm:"size:20;not null" json:"user_type"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Type         string     `gorm:"size:100;not null" json:"type"`
+	Title        string     `gorm:"size:255" json:"title"`
+	Body         string     `gorm:"type:text" json:"body"`
+	Payload      string     `gorm:"type:text" json:"payload"`
+	KirimSetelah time.Time  `gorm:"not null;index" json:"kirim_setelah"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// NotifPengaturan adalah pengaturan notifikasi umum seorang user.
+type NotifPengaturan struct {
+	UserType string `gorm:"primaryKey;size:20" json:"user_type"`
+	UserID   uint   `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Bahasa   string `gorm:"type:enum('id','en');not null;default:'id'" json:"bahasa"`
+	// DigestFrekuensi dan DigestJam mengatur kapan ringkasan dikirim: harian
+	// pada jam tsb, atau per_jam pada menit tsb.
+	DigestFrekuensi string    `gorm:"type:enum('harian','per_jam');not null;default:'harian'" json:"digest_frekuensi"`
+	DigestJam       string    `gorm:"type:char(5);not null;default:'07:00'" json:"digest_jam"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// NotifDigestType adalah type yang dipilih user untuk dikumpulkan ke
+// ringkasan. Notifikasinya tetap masuk inbox, tetapi tidak dikirim lewat
+// push/email/sms satu per satu.
+type NotifDigestType struct {
+	UserType  string    `gorm:"primaryKey;size:20" json:"user_type"`
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Type      string    `gorm:"primaryKey;size:100" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotifDigestAntrian adalah notifikasi yang menunggu masuk ringkasan.
+type NotifDigestAntrian struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserType  string     `gorm:"size:20;not null" json:"user_type"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	Type      string     `gorm:"size:100;not null" json:"type"`
+	Title     string     `gorm:"size:255" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	DigestAt  *time.Time `json:"digest_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type OrangTua struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Nama               string     `gorm:"type:varchar(100);not null" json:"nama"`
+	Email              string     `gorm:"type:varchar(100);unique;not null" json:"email"`
+	Telepon            string     `gorm:"type:varchar(20)" json:"telepon,omitempty"`
+	Password           string     `gorm:"type:varchar(255);not null" json:"-"`
+	MustChangePassword bool       `gorm:"not null;default:false" json:"must_change_password"`
+	DisabledAt         *time.Time `json:"disabled_at"`
+	Anak               []*Siswa   `gorm:"many2many:orang_tua_siswas;joinForeignKey:OrangTuaID;joinReferences:SiswaID" json:"anak,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
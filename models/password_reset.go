@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PasswordReset adalah kode reset password sekali pakai. Hanya hash kode
+// yang disimpan.
+type PasswordReset struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserType  string     `gorm:"size:20;not null;index:idx_password_resets_principal,priority:1" json:"user_type"`
+	UserID    uint       `gorm:"not null;index:idx_password_resets_principal,priority:2" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	Percobaan int        `gorm:"not null;default:0" json:"percobaan"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
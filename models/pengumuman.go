@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Pengumuman adalah pengumuman sekolah. Isi disimpan sebagai HTML yang
+// sudah dibersihkan. Pengumuman berstatus terjadwal diterbitkan oleh job
+// saat TerbitPada tiba.
+type Pengumuman struct {
+	ID              uint                 `gorm:"primaryKey" json:"id"`
+	Judul           string               `gorm:"size:200;not null" json:"judul"`
+	Isi             string               `gorm:"type:mediumtext;not null" json:"isi"`
+	AuthorType      string               `gorm:"size:20;not null;index:idx_pengumuman_author,priority:1" json:"author_type"`
+	AuthorID        uint                 `gorm:"not null;index:idx_pengumuman_author,priority:2" json:"author_id"`
+	AuthorRole      string               `gorm:"size:20;not null" json:"author_role"`
+	AuthorNama      string               `gorm:"size:100" json:"author_nama"`
+	Status          string               `gorm:"type:enum('terjadwal','terbit');not null;default:'terbit';index:idx_pengumuman_status,priority:1" json:"status"`
+	TerbitPada      *time.Time           `gorm:"index:idx_pengumuman_status,priority:2" json:"terbit_pada,omitempty"`
+	DiterbitkanPada *time.Time           `json:"diterbitkan_pada,omitempty"`
+	Target          []PengumumanTarget   `gorm:"foreignKey:PengumumanID" json:"target,omitempty"`
+	Lampiran        []PengumumanLampiran `gorm:"foreignKey:PengumumanID" json:"lampiran,omitempty"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// TableName mencegah pluralisasi gorm menjadi "pengumumen".
+func (Pengumuman) TableName() string { return "pengumumans" }
+
+// PengumumanTarget adalah satu kelompok audiens:
+//   - role:    Nilai = admin, guru, wali_kelas, siswa atau orang_tua
+//   - kelas:   Nilai = id kelas, Peran = siswa, orang_tua, guru atau wali_kelas
+//   - tingkat: Nilai = SD/SMP/SMA, Peran seperti kelas
+//   - user:    Nilai = id user, Peran = jenis user
+type PengumumanTarget struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	PengumumanID uint   `gorm:"not null;index" json:"pengumuman_id"`
+	Jenis        string `gorm:"type:enum('role','kelas','tingkat','user');not null" json:"jenis"`
+	Nilai        string `gorm:"size:50;not null" json:"nilai"`
+	Peran        string `gorm:"size:20" json:"peran,omitempty"`
+}
+
+type PengumumanLampiran struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	PengumumanID uint      `gorm:"not null;index" json:"pengumuman_id"`
+	Nama         string    `gorm:"size:255;not null" json:"nama"`
+	ContentType  string    `gorm:"size:100" json:"content_type"`
+	Ukuran       int64     `json:"ukuran"`
+	Path         string    `gorm:"size:255;not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PengumumanPenerima dibuat saat pengumuman terbit, satu baris per
+// penerima, dan mencatat kapan penerima membacanya.
+type PengumumanPenerima struct {
+	PengumumanID uint       `gorm:"primaryKey;autoIncrement:false" json:"pengumuman_id"`
+	UserType     string     `gorm:"primaryKey;size:20" json:"user_type"`
+	UserID       uint       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	DibacaPada   *time.Time `json:"dibaca_pada,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
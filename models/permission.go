@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Permission adalah satu izin, mis. "absensi.update.own".
+type Permission struct {
+	Kode      string `gorm:"primaryKey;size:60" json:"kode"`
+	Deskripsi string `gorm:"size:255;not null" json:"deskripsi"`
+}
+
+// RolePermission memberikan satu izin kepada satu role.
+type RolePermission struct {
+	Role       string    `gorm:"primaryKey;size:20" json:"role"`
+	Permission string    `gorm:"primaryKey;size:60" json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+// Jenis user sesuai tabel akunnya. ID hanya unik di dalam satu jenis,
+// jadi admin #3, guru #3 dan siswa #3 adalah akun yang berbeda.
+const (
+	UserTypeAdmin    = "admin"
+	UserTypeGuru     = "guru"
+	UserTypeSiswa    = "siswa"
+	UserTypeOrangTua = "orang_tua"
+)
+
+// Principal mengidentifikasi satu akun: jenis user dan id di tabelnya.
+type Principal struct {
+	UserType string `json:"user_type"`
+	ID       uint   `json:"id"`
+}
+
+// UserTypeDariRole memetakan role sesi ke jenis user. wali_kelas adalah
+// guru; role yang tidak dikenal menghasilkan "".
+func UserTypeDariRole(role string) string {
+	switch role {
+	case "admin":
+		return UserTypeAdmin
+	case "guru", "wali_kelas":
+		return UserTypeGuru
+	case "siswa":
+		return UserTypeSiswa
+	case "orang_tua":
+		return UserTypeOrangTua
+	}
+	return ""
+}
+
+// TabelAkun mengembalikan tabel akun untuk jenis user, atau "" bila tidak
+// dikenal.
+func TabelAkun(userType string) string {
+	switch userType {
+	case UserTypeAdmin:
+		return "admins"
+	case UserTypeGuru:
+		return "gurus"
+	case UserTypeSiswa:
+		return "siswas"
+	case UserTypeOrangTua:
+		return "orang_tuas"
+	}
+	return ""
+}
+
+// RoleSesi adalah role sesi yang mungkin dimiliki jenis user tsb.
+func RoleSesi(userType string) []string {
+	if userType == UserTypeGuru {
+		return []string{"guru", "wali_kelas"}
+	}
+	return []string{userType}
+}
+
+func NewPrincipal(role string, id uint) Principal {
+	return Principal{UserType: UserTypeDariRole(role), ID: id}
+}
+
+// PrincipalsDariRole membuat principal untuk sekumpulan id dengan role yang sama.
+func PrincipalsDariRole(role string, ids []uint) []Principal {
+	hasil := make([]Principal, 0, len(ids))
+	for _, id := range ids {
+		hasil = append(hasil, NewPrincipal(role, id))
+	}
+	return hasil
+}
+
+func (p Principal) Valid() bool {
+	return p.UserType != "" && p.ID != 0
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RefreshToken disimpan dalam bentuk hash. Semua refresh token hasil rotasi
+// dari satu login berbagi SessionID (satu keluarga), sehingga pemakaian
+// ulang token lama bisa mencabut seluruh keluarga sekaligus.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	SessionID uint       `gorm:"not null;index:idx_refresh_tokens_session" json:"session_id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	Role      string     `gorm:"size:20;not null" json:"role"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex:idx_refresh_tokens_hash" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index:idx_refresh_tokens_expires" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
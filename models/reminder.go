@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type AbsensiReminder struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TipeAbsensi string    `gorm:"type:enum('kelas','mapel');not null;uniqueIndex:idx_absensi_reminder" json:"tipe_absensi"`
+	KelasID     uint      `gorm:"not null;uniqueIndex:idx_absensi_reminder" json:"kelas_id"`
+	MapelID     uint      `gorm:"not null;default:0;uniqueIndex:idx_absensi_reminder" json:"mapel_id"` // 0 untuk absensi kelas
+	GuruID      uint      `gorm:"not null;default:0;uniqueIndex:idx_absensi_reminder" json:"guru_id"`  // 0 untuk absensi kelas
+	Tanggal     time.Time `gorm:"type:date;not null;uniqueIndex:idx_absensi_reminder" json:"tanggal"`
+	Level       string    `gorm:"type:enum('pengingat','eskalasi');not null;uniqueIndex:idx_absensi_reminder" json:"level"`
+	CreatedAt   time.Time `json:"created_at"`
+}
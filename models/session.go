@@ -1,13 +1,50 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Session struct {
-	ID        uint       `gorm:"primaryKey" json:"id"`
-	UserID    uint       `gorm:"not null" json:"user_id"`
-	Token     string     `gorm:"type:varchar(512);unique;not null" json:"token"`
-	Role      string     `gorm:"type:enum('guru','admin','wali_kelas','siswa');not null" json:"role"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `gorm:"index" json:"deleted_at,omitempty"`
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	UserID uint   `gorm:"not null" json:"user_id"`
+	Token  string `gorm:"type:varchar(512);unique;not null" json:"-"`
+	// Role adalah role aktif; Roles berisi semua role akun, dipisah koma
+	// (mis. "guru,wali_kelas").
+	Role  string `gorm:"type:enum('guru','admin','wali_kelas','siswa','orang_tua');not null" json:"role"`
+	Roles string `gorm:"type:varchar(100)" json:"roles"`
+	// MustChangePassword disalin dari akun saat login agar AuthMiddleware
+	// tidak perlu membaca tabel akun di setiap request.
+	MustChangePassword bool `gorm:"not null;default:false" json:"must_change_password"`
+	// Metadata perangkat saat login; IP dan LastSeenAt diperbarui saat
+	// sesi dipakai. ExpiresAt adalah exp access token terakhir.
+	IP         string     `gorm:"column:ip;type:varchar(45)" json:"ip"`
+	UserAgent  string     `gorm:"type:varchar(255)" json:"user_agent"`
+	Device     string     `gorm:"type:varchar(100)" json:"device"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// DaftarRole mengembalikan semua role sesi. Sesi lama tanpa Roles hanya
+// memiliki role aktifnya.
+func (s Session) DaftarRole() []string {
+	if s.Roles == "" {
+		return []string{s.Role}
+	}
+	return strings.Split(s.Roles, ",")
+}
+
+// PunyaRole melaporkan apakah salah satu role sesi ada di roles.
+func (s Session) PunyaRole(roles ...string) bool {
+	for _, r := range s.DaftarRole() {
+		for _, want := range roles {
+			if r == want {
+				return true
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// StreamTicket adalah tiket sekali pakai berumur pendek untuk membuka stream
+// notifikasi, sehingga access token tidak perlu dikirim di URL. Hanya hash
+// tiket yang disimpan.
+type StreamTicket struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SessionID  uint      `gorm:"not null;index:idx_stream_tickets_session" json:"session_id"`
+	TicketHash string    `gorm:"size:64;not null;uniqueIndex:idx_stream_tickets_hash" json:"-"`
+	ExpiresAt  time.Time `gorm:"not null;index:idx_stream_tickets_expires" json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
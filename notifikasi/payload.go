@@ -0,0 +1,259 @@
+package notifikasi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Payload adalah data terstruktur satu type notifikasi. Field ID memakai
+// tag ",string" supaya JSON-nya konsisten (ID selalu string) di semua type.
+type Payload interface {
+	Type() string
+}
+
+type AssignGuruMapel struct {
+	GuruID      uint   `json:"guru_id,string"`
+	GuruNama    string `json:"guru_nama"`
+	MapelID     uint   `json:"mapel_id,string"`
+	MapelNama   string `json:"mapel_nama"`
+	KelasID     uint   `json:"kelas_id,string"`
+	KelasNama   string `json:"kelas_nama"`
+	TahunAjaran string `json:"tahun_ajaran"`
+	Semester    string `json:"semester"`
+}
+
+type DeleteGuruMapel AssignGuruMapel
+
+type AssignWaliKelas struct {
+	GuruID    uint   `json:"guru_id,string"`
+	GuruNama  string `json:"guru_nama"`
+	KelasID   uint   `json:"kelas_id,string"`
+	KelasNama string `json:"kelas_nama"`
+}
+
+type UnassignWaliKelas AssignWaliKelas
+
+type AssignSiswaMapel struct {
+	SiswaID   uint   `json:"siswa_id,string"`
+	SiswaNama string `json:"siswa_nama"`
+	MapelID   uint   `json:"mapel_id,string"`
+	MapelNama string `json:"mapel_nama"`
+}
+
+type UnassignSiswaMapel AssignSiswaMapel
+
+type AssignSiswaKelas struct {
+	SiswaID   uint   `json:"siswa_id,string"`
+	SiswaNama string `json:"siswa_nama"`
+	KelasID   uint   `json:"kelas_id,string"`
+	KelasNama string `json:"kelas_nama"`
+	Actor     string `json:"actor,omitempty"`
+}
+
+type UnassignSiswaKelas AssignSiswaKelas
+
+type CreateKelas struct {
+	KelasID     uint   `json:"kelas_id,string"`
+	KelasNama   string `json:"kelas_nama"`
+	Tingkat     string `json:"tingkat"`
+	TahunAjaran string `json:"tahun_ajaran"`
+}
+
+type DeleteKelas struct {
+	KelasID   uint   `json:"kelas_id,string"`
+	KelasNama string `json:"kelas_nama"`
+}
+
+type CreateMapel struct {
+	MapelID    uint   `json:"mapel_id,string"`
+	MapelNama  string `json:"mapel_nama"`
+	MapelKode  string `json:"mapel_kode"`
+	Tingkat    string `json:"tingkat"`
+	Semester   string `json:"semester"`
+	Hari       string `json:"hari"`
+	JamMulai   string `json:"jam_mulai"`
+	JamSelesai string `json:"jam_selesai"`
+}
+
+type DeleteMapel struct {
+	MapelID   uint   `json:"mapel_id,string"`
+	MapelNama string `json:"mapel_nama"`
+	MapelKode string `json:"mapel_kode"`
+}
+
+type CreateTodo struct {
+	TodoID    uint   `json:"todo_id,string"`
+	Role      string `json:"role"`
+	Tanggal   string `json:"tanggal"`
+	Deskripsi string `json:"deskripsi"`
+	Jam       string `json:"jam,omitempty"`
+	ActorID   uint   `json:"actor_id,string"`
+}
+
+type DeleteTodo CreateTodo
+
+type CreateSiswa struct {
+	SiswaID uint   `json:"siswa_id,string"`
+	Nama    string `json:"nama"`
+	NISN    string `json:"nisn"`
+}
+
+type CreateGuru struct {
+	GuruID uint   `json:"guru_id,string"`
+	Nama   string `json:"nama"`
+	Email  string `json:"email"`
+}
+
+type ExportRekapMapel struct {
+	MapelID     uint   `json:"mapel_id,string"`
+	KelasID     uint   `json:"kelas_id,string"`
+	Tanggal     string `json:"tanggal"`
+	Filename    string `json:"filename"`
+	RecordCount int    `json:"record_count,string"`
+}
+
+type ExportRekapKelas struct {
+	KelasID     uint   `json:"kelas_id,string"`
+	Tanggal     string `json:"tanggal"`
+	Filename    string `json:"filename"`
+	RecordCount int    `json:"record_count,string"`
+}
+
+type ReminderAbsensiMapel struct {
+	KelasID    uint                   `json:"kelas_id,string"`
+	KelasNama  string                 `json:"kelas_nama"`
+	MapelID    uint                   `json:"mapel_id,string"`
+	MapelNama  string                 `json:"mapel_nama"`
+	GuruID     uint                   `json:"guru_id,string"`
+	GuruNama   string                 `json:"guru_nama"`
+	Tanggal    string                 `json:"tanggal"`
+	JamMulai   string                 `json:"jam_mulai"`
+	JamSelesai string                 `json:"jam_selesai"`
+	DeepLink   map[string]interface{} `json:"deep_link"`
+}
+
+type EskalasiAbsensiMapel ReminderAbsensiMapel
+
+type ReminderAbsensiKelas struct {
+	KelasID       uint                   `json:"kelas_id,string"`
+	KelasNama     string                 `json:"kelas_nama"`
+	WaliKelasID   uint                   `json:"wali_kelas_id,string"`
+	WaliKelasNama string                 `json:"wali_kelas_nama"`
+	Tanggal       string                 `json:"tanggal"`
+	DeepLink      map[string]interface{} `json:"deep_link"`
+}
+
+type EskalasiAbsensiKelas ReminderAbsensiKelas
+
+type AbsensiOrtuItem struct {
+	AbsensiID   uint   `json:"absensi_id,string"`
+	TipeAbsensi string `json:"tipe_absensi"`
+	KelasID     uint   `json:"kelas_id,string"`
+	Kelas       string `json:"kelas"`
+	MapelID     uint   `json:"mapel_id,string,omitempty"`
+	Mapel       string `json:"mapel,omitempty"`
+	Status      string `json:"status"`
+}
+
+type AbsensiSiswaOrtu struct {
+	SiswaID   uint              `json:"siswa_id,string"`
+	SiswaNama string            `json:"siswa_nama"`
+	Tanggal   string            `json:"tanggal"`
+	Detail    []AbsensiOrtuItem `json:"detail"`
+	// Susulan menandai pesan lanjutan berisi entri yang dicatat atau diubah
+	// setelah rangkuman tanggal tsb terkirim.
+	Susulan bool `json:"susulan,omitempty"`
+}
+
+// DigestItem merangkum notifikasi satu type dalam satu periode ringkasan.
+type DigestItem struct {
+	Type   string   `json:"type"`
+	Judul  string   `json:"judul"`
+	Jumlah int      `json:"jumlah"`
+	Contoh []string `json:"contoh,omitempty"`
+}
+
+type DigestNotifikasi struct {
+	Frekuensi string       `json:"frekuensi"`
+	Mulai     string       `json:"mulai"`
+	Sampai    string       `json:"sampai"`
+	Total     int          `json:"total"`
+	Ringkasan []DigestItem `json:"ringkasan"`
+}
+
+// Pengumuman dikirim ke setiap penerima saat pengumuman terbit. Ringkasan
+// adalah potongan isi dalam teks polos.
+type Pengumuman struct {
+	PengumumanID uint                   `json:"pengumuman_id,string"`
+	Judul        string                 `json:"judul"`
+	Ringkasan    string                 `json:"ringkasan"`
+	Penulis      string                 `json:"penulis"`
+	DeepLink     map[string]interface{} `json:"deep_link"`
+}
+
+func (AssignGuruMapel) Type() string      { return "assign_guru_mapel" }
+func (DeleteGuruMapel) Type() string      { return "delete_guru_mapel" }
+func (AssignWaliKelas) Type() string      { return "assign_wali_kelas" }
+func (UnassignWaliKelas) Type() string    { return "unassign_wali_kelas" }
+func (AssignSiswaMapel) Type() string     { return "assign_siswa_mapel" }
+func (UnassignSiswaMapel) Type() string   { return "unassign_siswa_mapel" }
+func (AssignSiswaKelas) Type() string     { return "assign_siswa_kelas" }
+func (UnassignSiswaKelas) Type() string   { return "unassign_siswa_kelas" }
+func (CreateKelas) Type() string          { return "create_kelas" }
+func (DeleteKelas) Type() string          { return "delete_kelas" }
+func (CreateMapel) Type() string          { return "create_mapel" }
+func (DeleteMapel) Type() string          { return "delete_mapel" }
+func (CreateTodo) Type() string           { return "create_todo" }
+func (DeleteTodo) Type() string           { return "delete_todo" }
+func (CreateSiswa) Type() string          { return "create_siswa" }
+func (CreateGuru) Type() string           { return "create_guru" }
+func (ExportRekapMapel) Type() string     { return "export_rekap_mapel" }
+func (ExportRekapKelas) Type() string     { return "export_rekap_kelas" }
+func (ReminderAbsensiMapel) Type() string { return "reminder_absensi_mapel" }
+func (EskalasiAbsensiMapel) Type() string { return "eskalasi_absensi_mapel" }
+func (ReminderAbsensiKelas) Type() string { return "reminder_absensi_kelas" }
+func (EskalasiAbsensiKelas) Type() string { return "eskalasi_absensi_kelas" }
+func (AbsensiSiswaOrtu) Type() string     { return "absensi_siswa_ortu" }
+func (DigestNotifikasi) Type() string     { return "digest_notifikasi" }
+func (Pengumuman) Type() string           { return "pengumuman" }
+
+var konstruktor = map[string]func() Payload{
+	"assign_guru_mapel":      func() Payload { return &AssignGuruMapel{} },
+	"delete_guru_mapel":      func() Payload { return &DeleteGuruMapel{} },
+	"assign_wali_kelas":      func() Payload { return &AssignWaliKelas{} },
+	"unassign_wali_kelas":    func() Payload { return &UnassignWaliKelas{} },
+	"assign_siswa_mapel":     func() Payload { return &AssignSiswaMapel{} },
+	"unassign_siswa_mapel":   func() Payload { return &UnassignSiswaMapel{} },
+	"assign_siswa_kelas":     func() Payload { return &AssignSiswaKelas{} },
+	"unassign_siswa_kelas":   func() Payload { return &UnassignSiswaKelas{} },
+	"create_kelas":           func() Payload { return &CreateKelas{} },
+	"delete_kelas":           func() Payload { return &DeleteKelas{} },
+	"create_mapel":           func() Payload { return &CreateMapel{} },
+	"delete_mapel":           func() Payload { return &DeleteMapel{} },
+	"create_todo":            func() Payload { return &CreateTodo{} },
+	"delete_todo":            func() Payload { return &DeleteTodo{} },
+	"create_siswa":           func() Payload { return &CreateSiswa{} },
+	"create_guru":            func() Payload { return &CreateGuru{} },
+	"export_rekap_mapel":     func() Payload { return &ExportRekapMapel{} },
+	"export_rekap_kelas":     func() Payload { return &ExportRekapKelas{} },
+	"reminder_absensi_mapel": func() Payload { return &ReminderAbsensiMapel{} },
+	"eskalasi_absensi_mapel": func() Payload { return &EskalasiAbsensiMapel{} },
+	"reminder_absensi_kelas": func() Payload { return &ReminderAbsensiKelas{} },
+	"eskalasi_absensi_kelas": func() Payload { return &EskalasiAbsensiKelas{} },
+	"absensi_siswa_ortu":     func() Payload { return &AbsensiSiswaOrtu{} },
+	"digest_notifikasi":      func() Payload { return &DigestNotifikasi{} },
+	"pengumuman":             func() Payload { return &Pengumuman{} },
+}
+
+// Decode membentuk kembali Payload dari type dan JSON-nya (mis. dari outbox).
+func Decode(typeStr string, data []byte) (Payload, error) {
+	baru, ok := konstruktor[typeStr]
+	if !ok {
+		return nil, fmt.Errorf("type notifikasi %s tidak dikenal", typeStr)
+	}
+	p := baru()
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("decode payload %s: %w", typeStr, err)
+	}
+	return p, nil
+}
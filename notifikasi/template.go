@@ -0,0 +1,282 @@
+package notifikasi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	BahasaID = "id"
+	BahasaEN = "en"
+)
+
+// BahasaDefault dipakai untuk user yang belum memilih bahasa
+// (NOTIF_BAHASA_DEFAULT, default "id").
+func BahasaDefault() string {
+	if BahasaValid(os.Getenv("NOTIF_BAHASA_DEFAULT")) {
+		return os.Getenv("NOTIF_BAHASA_DEFAULT")
+	}
+	return BahasaID
+}
+
+func BahasaValid(b string) bool {
+	return b == BahasaID || b == BahasaEN
+}
+
+type teks struct {
+	title string
+	body  string
+}
+
+// registry berisi judul dan isi per type dan bahasa. Template dieksekusi
+// terhadap struct Payload type tsb.
+var registry = map[string]map[string]teks{
+	"assign_guru_mapel": {
+		BahasaID: {"Penugasan Guru ke Mata Pelajaran",
+			"Guru {{.GuruNama}} telah resmi ditugaskan untuk mengajar mata pelajaran {{.MapelNama}} di kelas {{.KelasNama}} pada Tahun Ajaran {{.TahunAjaran}} (Semester {{semester .Semester}})."},
+		BahasaEN: {"Teacher Assigned to Subject",
+			"Teacher {{.GuruNama}} has been assigned to teach {{.MapelNama}} in class {{.KelasNama}} for academic year {{.TahunAjaran}} ({{semester .Semester}} semester)."},
+	},
+	"delete_guru_mapel": {
+		BahasaID: {"Penghapusan Penugasan Guru",
+			"Penugasan Guru {{.GuruNama}} untuk mata pelajaran {{.MapelNama}} di kelas {{.KelasNama}} pada Tahun Ajaran {{.TahunAjaran}} (Semester {{semester .Semester}}) telah dihapus."},
+		BahasaEN: {"Teacher Assignment Removed",
+			"The assignment of teacher {{.GuruNama}} to {{.MapelNama}} in class {{.KelasNama}} for academic year {{.TahunAjaran}} ({{semester .Semester}} semester) has been removed."},
+	},
+	"assign_wali_kelas": {
+		BahasaID: {"Penetapan Wali Kelas",
+			"Guru {{.GuruNama}} telah resmi ditetapkan sebagai wali kelas {{.KelasNama}}."},
+		BahasaEN: {"Homeroom Teacher Assigned",
+			"Teacher {{.GuruNama}} has been appointed homeroom teacher of class {{.KelasNama}}."},
+	},
+	"unassign_wali_kelas": {
+		BahasaID: {"Pencabutan Wali Kelas",
+			"Penetapan Wali Kelas untuk kelas {{.KelasNama}} dibatalkan. Guru {{.GuruNama}} tidak lagi menjabat wali kelas {{.KelasNama}}."},
+		BahasaEN: {"Homeroom Teacher Removed",
+			"Teacher {{.GuruNama}} is no longer the homeroom teacher of class {{.KelasNama}}."},
+	},
+	"assign_siswa_mapel": {
+		BahasaID: {"Penambahan Siswa ke Mapel",
+			"Siswa {{.SiswaNama}} berhasil ditambahkan ke mata pelajaran {{.MapelNama}}."},
+		BahasaEN: {"Student Added to Subject",
+			"Student {{.SiswaNama}} has been enrolled in {{.MapelNama}}."},
+	},
+	"unassign_siswa_mapel": {
+		BahasaID: {"Penghapusan dari Mata Pelajaran",
+			"Siswa {{.SiswaNama}} telah dihapus dari mata pelajaran {{.MapelNama}}."},
+		BahasaEN: {"Student Removed from Subject",
+			"Student {{.SiswaNama}} has been removed from {{.MapelNama}}."},
+	},
+	"assign_siswa_kelas": {
+		BahasaID: {"Penambahan Siswa ke Kelas",
+			"Siswa {{.SiswaNama}} telah ditambahkan ke kelas {{.KelasNama}}."},
+		BahasaEN: {"Student Added to Class",
+			"Student {{.SiswaNama}} has been added to class {{.KelasNama}}."},
+	},
+	"unassign_siswa_kelas": {
+		BahasaID: {"Penghapusan Siswa dari Kelas",
+			"Siswa {{.SiswaNama}} telah dihapus dari kelas {{.KelasNama}}."},
+		BahasaEN: {"Student Removed from Class",
+			"Student {{.SiswaNama}} has been removed from class {{.KelasNama}}."},
+	},
+	"create_kelas": {
+		BahasaID: {"Kelas Baru Dibuat",
+			"Kelas {{.KelasNama}} ({{.Tingkat}}, TA {{.TahunAjaran}}) telah dibuat."},
+		BahasaEN: {"New Class Created",
+			"Class {{.KelasNama}} ({{.Tingkat}}, academic year {{.TahunAjaran}}) has been created."},
+	},
+	"delete_kelas": {
+		BahasaID: {"Kelas Dihapus",
+			"Kelas {{.KelasNama}} telah dihapus dari sistem."},
+		BahasaEN: {"Class Deleted",
+			"Class {{.KelasNama}} has been deleted from the system."},
+	},
+	"create_mapel": {
+		BahasaID: {"Mata Pelajaran Baru",
+			"Mata pelajaran {{.MapelNama}} ({{.MapelKode}}) berhasil dibuat."},
+		BahasaEN: {"New Subject",
+			"Subject {{.MapelNama}} ({{.MapelKode}}) has been created."},
+	},
+	"delete_mapel": {
+		BahasaID: {"Mata Pelajaran Dihapus",
+			"Mata pelajaran {{.MapelNama}} ({{.MapelKode}}) telah dihapus."},
+		BahasaEN: {"Subject Deleted",
+			"Subject {{.MapelNama}} ({{.MapelKode}}) has been deleted."},
+	},
+	"create_todo": {
+		BahasaID: {"To-Do Baru Dibuat",
+			"To-Do: {{.Deskripsi}} (tanggal {{.Tanggal}})"},
+		BahasaEN: {"New To-Do",
+			"To-Do: {{.Deskripsi}} (date {{.Tanggal}})"},
+	},
+	"delete_todo": {
+		BahasaID: {"To-Do Dihapus",
+			"To-Do: {{.Deskripsi}} (tanggal {{.Tanggal}}) telah dihapus."},
+		BahasaEN: {"To-Do Deleted",
+			"To-Do: {{.Deskripsi}} (date {{.Tanggal}}) has been deleted."},
+	},
+	"create_siswa": {
+		BahasaID: {"Akun Siswa Baru Dibuat",
+			"Akun siswa {{.Nama}} ({{.NISN}}) berhasil dibuat."},
+		BahasaEN: {"New Student Account",
+			"Student account {{.Nama}} ({{.NISN}}) has been created."},
+	},
+	"create_guru": {
+		BahasaID: {"Akun Guru Baru Dibuat",
+			"Akun guru {{.Nama}} ({{.Email}}) berhasil dibuat."},
+		BahasaEN: {"New Teacher Account",
+			"Teacher account {{.Nama}} ({{.Email}}) has been created."},
+	},
+	"export_rekap_mapel": {
+		BahasaID: {"Export Rekap Mapel Selesai",
+			"Rekap absensi mapel untuk tanggal {{.Tanggal}} telah selesai ({{.Filename}})."},
+		BahasaEN: {"Subject Attendance Export Ready",
+			"The subject attendance recap for {{.Tanggal}} is ready ({{.Filename}})."},
+	},
+	"export_rekap_kelas": {
+		BahasaID: {"Export Rekap Kelas Selesai",
+			"Rekap absensi kelas untuk tanggal {{.Tanggal}} telah selesai ({{.Filename}})."},
+		BahasaEN: {"Class Attendance Export Ready",
+			"The class attendance recap for {{.Tanggal}} is ready ({{.Filename}})."},
+	},
+	"reminder_absensi_mapel": {
+		BahasaID: {"Absensi Mapel Belum Diisi",
+			"Absensi {{.MapelNama}} di kelas {{.KelasNama}} hari ini ({{.JamMulai}}-{{.JamSelesai}}) belum diisi."},
+		BahasaEN: {"Subject Attendance Not Filled",
+			"Attendance for {{.MapelNama}} in class {{.KelasNama}} today ({{.JamMulai}}-{{.JamSelesai}}) has not been filled in."},
+	},
+	"eskalasi_absensi_mapel": {
+		BahasaID: {"Eskalasi: Absensi Mapel Belum Diisi",
+			"Guru {{.GuruNama}} belum mengisi absensi {{.MapelNama}} di kelas {{.KelasNama}} tanggal {{.Tanggal}}."},
+		BahasaEN: {"Escalation: Subject Attendance Not Filled",
+			"Teacher {{.GuruNama}} has not filled in attendance for {{.MapelNama}} in class {{.KelasNama}} on {{.Tanggal}}."},
+	},
+	"reminder_absensi_kelas": {
+		BahasaID: {"Absensi Kelas Belum Diisi",
+			"Absensi harian kelas {{.KelasNama}} tanggal {{.Tanggal}} belum diisi."},
+		BahasaEN: {"Class Attendance Not Filled",
+			"Daily attendance for class {{.KelasNama}} on {{.Tanggal}} has not been filled in."},
+	},
+	"eskalasi_absensi_kelas": {
+		BahasaID: {"Eskalasi: Absensi Kelas Belum Diisi",
+			"Wali kelas {{.WaliKelasNama}} belum mengisi absensi kelas {{.KelasNama}} tanggal {{.Tanggal}}."},
+		BahasaEN: {"Escalation: Class Attendance Not Filled",
+			"Homeroom teacher {{.WaliKelasNama}} has not filled in attendance for class {{.KelasNama}} on {{.Tanggal}}."},
+	},
+	"absensi_siswa_ortu": {
+		BahasaID: {"{{if .Susulan}}Susulan: {{end}}Info Kehadiran {{.SiswaNama}}",
+			"{{.SiswaNama}} tercatat {{range $i, $d := .Detail}}{{if $i}}; {{end}}{{status $d.Status}} di {{if $d.Mapel}}{{$d.Mapel}} ({{$d.Kelas}}){{else}}absensi harian kelas {{$d.Kelas}}{{end}}{{end}} pada {{.Tanggal}}."},
+		BahasaEN: {"{{if .Susulan}}Update: {{end}}Attendance Info: {{.SiswaNama}}",
+			"On {{.Tanggal}}, {{.SiswaNama}} was recorded {{range $i, $d := .Detail}}{{if $i}}; {{end}}{{status $d.Status}} in {{if $d.Mapel}}{{$d.Mapel}} ({{$d.Kelas}}){{else}}daily attendance of class {{$d.Kelas}}{{end}}{{end}}."},
+	},
+	"digest_notifikasi": {
+		BahasaID: {"Ringkasan {{frekuensi .Frekuensi}}: {{.Total}} notifikasi",
+			"{{range $i, $r := .Ringkasan}}{{if $i}}; {{end}}{{$r.Judul}} ({{$r.Jumlah}}){{end}}."},
+		BahasaEN: {"{{frekuensi .Frekuensi}} digest: {{.Total}} notifications",
+			"{{range $i, $r := .Ringkasan}}{{if $i}}; {{end}}{{$r.Judul}} ({{$r.Jumlah}}){{end}}."},
+	},
+	"pengumuman": {
+		BahasaID: {"Pengumuman: {{.Judul}}",
+			"{{.Penulis}}: {{.Ringkasan}}"},
+		BahasaEN: {"Announcement: {{.Judul}}",
+			"{{.Penulis}}: {{.Ringkasan}}"},
+	},
+}
+
+var funcs = map[string]template.FuncMap{
+	BahasaID: {
+		"semester": func(s string) string { return s },
+		"status":   func(s string) string { return s },
+		"frekuensi": func(s string) string {
+			return map[string]string{"harian": "harian", "per_jam": "per jam"}[s]
+		},
+	},
+	BahasaEN: {
+		"semester": func(s string) string {
+			return map[string]string{"ganjil": "odd", "genap": "even"}[s]
+		},
+		"status": func(s string) string {
+			if v, ok := map[string]string{
+				"masuk": "present", "izin": "excused", "sakit": "sick",
+				"terlambat": "late", "alpa": "absent",
+			}[s]; ok {
+				return v
+			}
+			return s
+		},
+		"frekuensi": func(s string) string {
+			return map[string]string{"harian": "Daily", "per_jam": "Hourly"}[s]
+		},
+	},
+}
+
+type compiled struct {
+	title *template.Template
+	body  *template.Template
+}
+
+var templates = map[string]map[string]compiled{}
+
+func init() {
+	for typ, perBahasa := range registry {
+		templates[typ] = map[string]compiled{}
+		for bhs, t := range perBahasa {
+			name := typ + "." + bhs
+			templates[typ][bhs] = compiled{
+				title: template.Must(template.New(name + ".title").Funcs(funcs[bhs]).Option("missingkey=error").Parse(t.title)),
+				body:  template.Must(template.New(name + ".body").Funcs(funcs[bhs]).Option("missingkey=error").Parse(t.body)),
+			}
+		}
+	}
+}
+
+// Types mengembalikan semua type yang terdaftar, terurut.
+func Types() []string {
+	out := make([]string, 0, len(registry))
+	for t := range registry {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func Dikenal(typeStr string) bool {
+	_, ok := registry[typeStr]
+	return ok
+}
+
+// Render menghasilkan judul dan isi notifikasi dalam bahasa yang diminta.
+// Bahasa yang tidak tersedia jatuh ke bahasa Indonesia.
+func Render(p Payload, bahasa string) (string, string, error) {
+	perBahasa, ok := templates[p.Type()]
+	if !ok {
+		return "", "", fmt.Errorf("template notifikasi %s tidak terdaftar", p.Type())
+	}
+	t, ok := perBahasa[bahasa]
+	if !ok {
+		t = perBahasa[BahasaID]
+	}
+	var title, body bytes.Buffer
+	if err := t.title.Execute(&title, p); err != nil {
+		return "", "", fmt.Errorf("render judul %s: %w", p.Type(), err)
+	}
+	if err := t.body.Execute(&body, p); err != nil {
+		return "", "", fmt.Errorf("render isi %s: %w", p.Type(), err)
+	}
+	return strings.TrimSpace(title.String()), strings.TrimSpace(body.String()), nil
+}
+
+// ToMap mengubah payload menjadi map JSON beserta key "type".
+func ToMap(p Payload) map[string]interface{} {
+	m := map[string]interface{}{}
+	if b, err := json.Marshal(p); err == nil {
+		_ = json.Unmarshal(b, &m)
+	}
+	m["type"] = p.Type()
+	return m
+}
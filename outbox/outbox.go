@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"abs-be/firebaseclient"
+	"abs-be/models"
+	"abs-be/notifikasi"
+
+	"gorm.io/gorm"
+)
+
+// Enqueue menulis notifikasi ke outbox memakai tx yang sama dengan
+// perubahan datanya, sehingga notifikasi hanya ada jika transaksi commit.
+func Enqueue(tx *gorm.DB, role string, p notifikasi.Payload, userIDs []uint, lampiran ...firebaseclient.Lampiran) error {
+	ids := make([]uint, 0, len(userIDs))
+	seen := make(map[uint]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		if id == 0 {
+			continue
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal payload %s: %w", p.Type(), err)
+	}
+	recipients, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	row := models.NotifOutbox{
+		Type:          p.Type(),
+		Role:          role,
+		Payload:       string(payload),
+		Recipients:    string(recipients),
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}
+	if len(lampiran) > 0 {
+		b, err := json.Marshal(lampiran)
+		if err != nil {
+			return fmt.Errorf("marshal lampiran: %w", err)
+		}
+		row.Lampiran = string(b)
+	}
+	if err := tx.Create(&row).Error; err != nil {
+		return fmt.Errorf("simpan outbox %s: %w", p.Type(), err)
+	}
+	return nil
+}
+
+// EnqueuePerRole memanggil Enqueue untuk setiap kelompok role penerima.
+func EnqueuePerRole(tx *gorm.DB, p notifikasi.Payload, perRole map[string][]uint, lampiran ...firebaseclient.Lampiran) error {
+	for role, ids := range perRole {
+		if err := Enqueue(tx, role, p, ids, lampiran...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redrive mengembalikan entri dead (atau pending yang sedang menunggu
+// backoff) agar segera dicoba lagi dengan hitungan percobaan dari nol.
+func Redrive(db *gorm.DB, id uint) error {
+	res := db.Model(&models.NotifOutbox{}).
+		Where("id = ? AND status IN ?", id, []string{models.OutboxDead, models.OutboxPending}).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"locked_at":       nil,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"abs-be/firebaseclient"
+	"abs-be/models"
+	"abs-be/notifikasi"
+
+	"gorm.io/gorm"
+)
+
+type Config struct {
+	Workers      int
+	Batch        int
+	MaxAttempts  int
+	BackoffDasar time.Duration
+	BackoffMaks  time.Duration
+	LockTimeout  time.Duration
+}
+
+// LoadConfig membaca OUTBOX_WORKERS (4), OUTBOX_MAX_ATTEMPTS (8),
+// OUTBOX_BACKOFF_DETIK (30), OUTBOX_BACKOFF_MAKS_DETIK (3600) dan
+// OUTBOX_LOCK_TIMEOUT_DETIK (600).
+func LoadConfig() Config {
+	workers := envInt("OUTBOX_WORKERS", 4)
+	return Config{
+		Workers:      workers,
+		Batch:        workers * 10,
+		MaxAttempts:  envInt("OUTBOX_MAX_ATTEMPTS", 8),
+		BackoffDasar: time.Duration(envInt("OUTBOX_BACKOFF_DETIK", 30)) * time.Second,
+		BackoffMaks:  time.Duration(envInt("OUTBOX_BACKOFF_MAKS_DETIK", 3600)) * time.Second,
+		LockTimeout:  time.Duration(envInt("OUTBOX_LOCK_TIMEOUT_DETIK", 600)) * time.Second,
+	}
+}
+
+// ProsesBatch mengklaim entri yang jatuh tempo lalu mengirimkannya dengan
+// paling banyak cfg.Workers goroutine sekaligus. Entri "proses" yang
+// terkunci lebih lama dari LockTimeout (mis. server mati saat mengirim)
+// diklaim ulang.
+func ProsesBatch(ctx context.Context, db *gorm.DB, cfg Config, now time.Time) error {
+	basi := now.Add(-cfg.LockTimeout)
+	var kandidat []models.NotifOutbox
+	if err := db.
+		Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?)",
+			models.OutboxPending, now, models.OutboxProses, basi).
+		Order("next_attempt_at").
+		Limit(cfg.Batch).
+		Find(&kandidat).Error; err != nil {
+		return fmt.Errorf("ambil outbox: %w", err)
+	}
+
+	sem := make(chan struct{}, cfg.Workers)
+	var wg sync.WaitGroup
+	for _, row := range kandidat {
+		res := db.Model(&models.NotifOutbox{}).
+			Where("id = ? AND (status = ? OR (status = ? AND locked_at < ?))",
+				row.ID, models.OutboxPending, models.OutboxProses, basi).
+			Updates(map[string]interface{}{"status": models.OutboxProses, "locked_at": now})
+		if res.Error != nil {
+			log.Printf("outbox: gagal klaim %d: %v", row.ID, res.Error)
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue // sudah diambil worker lain
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(row models.NotifOutbox) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("outbox: panic saat mengirim %d: %v", row.ID, r)
+					selesaiGagal(db, cfg, row, nil, fmt.Errorf("panic: %v", r), false)
+				}
+				<-sem
+				wg.Done()
+			}()
+			kirimSatu(ctx, db, cfg, row)
+		}(row)
+	}
+	wg.Wait()
+	return nil
+}
+
+func kirimSatu(ctx context.Context, db *gorm.DB, cfg Config, row models.NotifOutbox) {
+	p, err := notifikasi.Decode(row.Type, []byte(row.Payload))
+	if err != nil {
+		selesaiGagal(db, cfg, row, nil, err, true)
+		return
+	}
+	var ids []uint
+	if err := json.Unmarshal([]byte(row.Recipients), &ids); err != nil {
+		selesaiGagal(db, cfg, row, nil, fmt.Errorf("decode recipients: %w", err), true)
+		return
+	}
+	var lampiran []firebaseclient.Lampiran
+	if row.Lampiran != "" {
+		if err := json.Unmarshal([]byte(row.Lampiran), &lampiran); err != nil {
+			selesaiGagal(db, cfg, row, nil, fmt.Errorf("decode lampiran: %w", err), true)
+			return
+		}
+	}
+	selesai := map[string]bool{}
+	if row.Selesai != "" {
+		_ = json.Unmarshal([]byte(row.Selesai), &selesai)
+	}
+
+	ctx = firebaseclient.DenganOutboxID(ctx, row.ID)
+	if err := firebaseclient.Kirim(ctx, row.Role, p, ids, selesai, lampiran...); err != nil {
+		selesaiGagal(db, cfg, row, selesai, err, false)
+		return
+	}
+
+	now := time.Now()
+	b, _ := json.Marshal(selesai)
+	if err := db.Model(&models.NotifOutbox{}).Where("id = ?", row.ID).Updates(map[string]interface{}{
+		"status":     models.OutboxTerkirim,
+		"attempts":   row.Attempts + 1,
+		"selesai":    string(b),
+		"sent_at":    now,
+		"locked_at":  nil,
+		"last_error": "",
+	}).Error; err != nil {
+		log.Printf("outbox: gagal menandai %d terkirim: %v", row.ID, err)
+	}
+}
+
+// selesaiGagal menjadwalkan percobaan berikutnya dengan backoff eksponensial,
+// atau memindahkan entri ke dead jika percobaan habis / error permanen.
+func selesaiGagal(db *gorm.DB, cfg Config, row models.NotifOutbox, selesai map[string]bool, sendErr error, permanen bool) {
+	attempts := row.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": sendErr.Error(),
+		"locked_at":  nil,
+	}
+	if selesai != nil {
+		b, _ := json.Marshal(selesai)
+		updates["selesai"] = string(b)
+	}
+	if permanen || attempts >= cfg.MaxAttempts {
+		updates["status"] = models.OutboxDead
+		log.Printf("outbox: %d (%s) dead setelah %d percobaan: %v", row.ID, row.Type, attempts, sendErr)
+	} else {
+		updates["status"] = models.OutboxPending
+		updates["next_attempt_at"] = time.Now().Add(backoff(cfg, attempts))
+	}
+	if err := db.Model(&models.NotifOutbox{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		log.Printf("outbox: gagal menyimpan status %d: %v", row.ID, err)
+	}
+}
+
+func backoff(cfg Config, attempts int) time.Duration {
+	d := cfg.BackoffDasar
+	for i := 1; i < attempts && d < cfg.BackoffMaks; i++ {
+		d *= 2
+	}
+	if d > cfg.BackoffMaks {
+		d = cfg.BackoffMaks
+	}
+	// jitter hingga 20% supaya entri yang gagal bersamaan tidak serentak dicoba
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("outbox: nilai %s tidak valid (%q), memakai default %d", key, v, def)
+	}
+	return def
+}
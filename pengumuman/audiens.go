@@ -0,0 +1,151 @@
+package pengumuman
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"abs-be/jadwal"
+	"abs-be/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	JenisRole    = "role"
+	JenisKelas   = "kelas"
+	JenisTingkat = "tingkat"
+	JenisUser    = "user"
+)
+
+var roleAudiens = map[string]bool{
+	"admin": true, "guru": true, "wali_kelas": true, "siswa": true, "orang_tua": true,
+}
+
+// peranKelas adalah anggota kelas/tingkat yang bisa dituju.
+var peranKelas = map[string]bool{
+	"siswa": true, "orang_tua": true, "guru": true, "wali_kelas": true,
+}
+
+var tingkatValid = map[string]bool{"SD": true, "SMP": true, "SMA": true}
+
+// ValidasiTarget memeriksa bentuk target tanpa menyentuh database.
+func ValidasiTarget(t models.PengumumanTarget) error {
+	switch t.Jenis {
+	case JenisRole:
+		if !roleAudiens[t.Nilai] {
+			return fmt.Errorf("role %q tidak dikenal", t.Nilai)
+		}
+	case JenisKelas:
+		if id, err := strconv.ParseUint(t.Nilai, 10, 64); err != nil || id == 0 {
+			return fmt.Errorf("id kelas %q tidak valid", t.Nilai)
+		}
+		if !peranKelas[t.Peran] {
+			return fmt.Errorf("peran %q tidak valid untuk target kelas", t.Peran)
+		}
+	case JenisTingkat:
+		if !tingkatValid[t.Nilai] {
+			return fmt.Errorf("tingkat %q tidak dikenal", t.Nilai)
+		}
+		if !peranKelas[t.Peran] {
+			return fmt.Errorf("peran %q tidak valid untuk target tingkat", t.Peran)
+		}
+	case JenisUser:
+		if id, err := strconv.ParseUint(t.Nilai, 10, 64); err != nil || id == 0 {
+			return fmt.Errorf("id user %q tidak valid", t.Nilai)
+		}
+		if models.UserTypeDariRole(t.Peran) != t.Peran {
+			return fmt.Errorf("jenis user %q tidak dikenal", t.Peran)
+		}
+	default:
+		return fmt.Errorf("jenis target %q tidak dikenal", t.Jenis)
+	}
+	return nil
+}
+
+// Penerima menurunkan daftar user dari target pada saat terbit.
+func Penerima(db *gorm.DB, targets []models.PengumumanTarget, now time.Time) ([]models.Principal, error) {
+	seen := map[models.Principal]struct{}{}
+	var hasil []models.Principal
+	tambah := func(userType string, ids []uint) {
+		for _, id := range ids {
+			p := models.Principal{UserType: userType, ID: id}
+			if _, ok := seen[p]; ok || id == 0 {
+				continue
+			}
+			seen[p] = struct{}{}
+			hasil = append(hasil, p)
+		}
+	}
+
+	for _, t := range targets {
+		switch t.Jenis {
+		case JenisRole:
+			ids, err := idsRole(db, t.Nilai)
+			if err != nil {
+				return nil, err
+			}
+			tambah(models.UserTypeDariRole(t.Nilai), ids)
+		case JenisKelas, JenisTingkat:
+			var kelasIDs []uint
+			if t.Jenis == JenisKelas {
+				id, _ := strconv.ParseUint(t.Nilai, 10, 64)
+				kelasIDs = []uint{uint(id)}
+			} else if err := db.Table("kelas").Where("tingkat = ?", t.Nilai).Pluck("id", &kelasIDs).Error; err != nil {
+				return nil, err
+			}
+			if len(kelasIDs) == 0 {
+				continue
+			}
+			ids, err := idsAnggotaKelas(db, kelasIDs, t.Peran, now)
+			if err != nil {
+				return nil, err
+			}
+			tambah(models.UserTypeDariRole(t.Peran), ids)
+		case JenisUser:
+			id, _ := strconv.ParseUint(t.Nilai, 10, 64)
+			tambah(t.Peran, []uint{uint(id)})
+		}
+	}
+	return hasil, nil
+}
+
+func idsRole(db *gorm.DB, role string) ([]uint, error) {
+	var ids []uint
+	var err error
+	switch role {
+	case "admin":
+		err = db.Table("admins").Pluck("id", &ids).Error
+	case "guru":
+		err = db.Table("gurus").Pluck("id", &ids).Error
+	case "wali_kelas":
+		err = db.Table("kelas").Where("wali_kelas_id IS NOT NULL").Distinct().Pluck("wali_kelas_id", &ids).Error
+	case "siswa":
+		err = db.Table("siswas").Pluck("id", &ids).Error
+	case "orang_tua":
+		err = db.Table("orang_tuas").Pluck("id", &ids).Error
+	}
+	return ids, err
+}
+
+func idsAnggotaKelas(db *gorm.DB, kelasIDs []uint, peran string, now time.Time) ([]uint, error) {
+	var ids []uint
+	var err error
+	switch peran {
+	case "siswa":
+		err = db.Raw("SELECT id FROM siswas WHERE kelas_id IN ? UNION SELECT siswa_id FROM kelas_siswas WHERE kelas_id IN ?", kelasIDs, kelasIDs).
+			Scan(&ids).Error
+	case "orang_tua":
+		err = db.Raw(`SELECT DISTINCT orang_tua_id FROM orang_tua_siswas
+			WHERE siswa_id IN (SELECT id FROM siswas WHERE kelas_id IN ?)
+			   OR siswa_id IN (SELECT siswa_id FROM kelas_siswas WHERE kelas_id IN ?)`, kelasIDs, kelasIDs).
+			Scan(&ids).Error
+	case "wali_kelas":
+		err = db.Table("kelas").Where("id IN ? AND wali_kelas_id IS NOT NULL", kelasIDs).Distinct().Pluck("wali_kelas_id", &ids).Error
+	case "guru":
+		err = db.Table("guru_mapel_kelas").
+			Where("kelas_id IN ? AND tahun_ajaran = ? AND semester = ?", kelasIDs, jadwal.TahunAjaran(now), jadwal.Semester(now)).
+			Distinct().Pluck("guru_id", &ids).Error
+	}
+	return ids, err
+}
@@ -0,0 +1,133 @@
+package pengumuman
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// tagDiizinkan adalah format teks yang boleh dipakai di isi pengumuman.
+// Tag lain dibuang tetapi teksnya dipertahankan.
+var tagDiizinkan = map[string]bool{
+	"p": true, "br": true, "b": true, "strong": true, "i": true, "em": true,
+	"u": true, "s": true, "ul": true, "ol": true, "li": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "a": true,
+}
+
+// tagDibuang dibuang bersama seluruh isinya.
+var tagDibuang = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true, "head": true, "title": true,
+}
+
+// tagBlok diberi jeda spasi saat diubah ke teks polos.
+var tagBlok = map[string]bool{
+	"p": true, "br": true, "li": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "div": true,
+}
+
+// BersihkanHTML menyisakan tag format dasar tanpa atribut, kecuali href
+// http/https/mailto pada tautan, sehingga isi aman ditampilkan apa adanya.
+func BersihkanHTML(s string) string {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	buang := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			if buang == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if tagDibuang[tok.Data] {
+				if tt == html.StartTagToken {
+					buang++
+				}
+				continue
+			}
+			if buang > 0 || !tagDiizinkan[tok.Data] {
+				continue
+			}
+			b.WriteString("<" + tok.Data)
+			if tok.Data == "a" {
+				for _, a := range tok.Attr {
+					if a.Key == "href" && tautanAman(a.Val) {
+						b.WriteString(` href="` + html.EscapeString(a.Val) + `" rel="noopener noreferrer"`)
+						break
+					}
+				}
+			}
+			b.WriteString(">")
+		case html.EndTagToken:
+			tok := z.Token()
+			if tagDibuang[tok.Data] {
+				if buang > 0 {
+					buang--
+				}
+				continue
+			}
+			if buang > 0 || !tagDiizinkan[tok.Data] || tok.Data == "br" {
+				continue
+			}
+			b.WriteString("</" + tok.Data + ">")
+		}
+	}
+}
+
+func tautanAman(href string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// TeksPolos mengubah isi HTML menjadi teks satu baris, dipotong ke maks
+// karakter, untuk isi notifikasi.
+func TeksPolos(s string, maks int) string {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	buang := 0
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break loop
+		case html.TextToken:
+			if buang == 0 {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tagDibuang[tag] {
+				if tt == html.StartTagToken {
+					buang++
+				} else if tt == html.EndTagToken && buang > 0 {
+					buang--
+				}
+				continue
+			}
+			if tagBlok[tag] {
+				b.WriteString(" ")
+			}
+		}
+	}
+
+	teks := strings.Join(strings.Fields(b.String()), " ")
+	r := []rune(teks)
+	if maks > 0 && len(r) > maks {
+		return strings.TrimSpace(string(r[:maks])) + "…"
+	}
+	return teks
+}
@@ -0,0 +1,108 @@
+package pengumuman
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"abs-be/models"
+)
+
+// DirLampiran adalah folder penyimpanan lampiran
+// (PENGUMUMAN_LAMPIRAN_DIR, default storage/pengumuman).
+func DirLampiran() string {
+	if v := os.Getenv("PENGUMUMAN_LAMPIRAN_DIR"); v != "" {
+		return v
+	}
+	return filepath.Join("storage", "pengumuman")
+}
+
+// MaksLampiran adalah jumlah lampiran per pengumuman
+// (PENGUMUMAN_LAMPIRAN_MAKS, default 5).
+func MaksLampiran() int {
+	return envInt("PENGUMUMAN_LAMPIRAN_MAKS", 5)
+}
+
+// MaksUkuranLampiran adalah ukuran maksimal satu lampiran dalam byte
+// (PENGUMUMAN_LAMPIRAN_MAKS_MB, default 10).
+func MaksUkuranLampiran() int64 {
+	return int64(envInt("PENGUMUMAN_LAMPIRAN_MAKS_MB", 10)) << 20
+}
+
+// SimpanLampiran menyalin file upload ke DirLampiran dengan nama acak.
+// Baris models.PengumumanLampiran yang dikembalikan belum disimpan.
+func SimpanLampiran(fh *multipart.FileHeader) (models.PengumumanLampiran, error) {
+	if fh.Size > MaksUkuranLampiran() {
+		return models.PengumumanLampiran{}, fmt.Errorf("lampiran %s melebihi %d MB", fh.Filename, MaksUkuranLampiran()>>20)
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return models.PengumumanLampiran{}, err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(DirLampiran(), 0o750); err != nil {
+		return models.PengumumanLampiran{}, err
+	}
+	acak := make([]byte, 16)
+	if _, err := rand.Read(acak); err != nil {
+		return models.PengumumanLampiran{}, err
+	}
+	nama := hex.EncodeToString(acak) + filepath.Ext(fh.Filename)
+	dst, err := os.OpenFile(filepath.Join(DirLampiran(), nama), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return models.PengumumanLampiran{}, err
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, MaksUkuranLampiran()+1))
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > MaksUkuranLampiran() {
+		err = fmt.Errorf("lampiran %s melebihi %d MB", fh.Filename, MaksUkuranLampiran()>>20)
+	}
+	if err != nil {
+		os.Remove(filepath.Join(DirLampiran(), nama))
+		return models.PengumumanLampiran{}, err
+	}
+
+	contentType := fh.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return models.PengumumanLampiran{
+		Nama:        filepath.Base(fh.Filename),
+		ContentType: contentType,
+		Ukuran:      n,
+		Path:        nama,
+	}, nil
+}
+
+// PathLampiran mengembalikan lokasi file lampiran di disk.
+func PathLampiran(l models.PengumumanLampiran) string {
+	return filepath.Join(DirLampiran(), filepath.Base(l.Path))
+}
+
+// HapusLampiran menghapus file lampiran; kegagalan hanya dicatat.
+func HapusLampiran(ls []models.PengumumanLampiran) {
+	for _, l := range ls {
+		if err := os.Remove(PathLampiran(l)); err != nil && !os.IsNotExist(err) {
+			log.Printf("pengumuman: gagal hapus lampiran %s: %v", l.Path, err)
+		}
+	}
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("pengumuman: nilai %s tidak valid (%q), memakai default %d", key, v, def)
+	}
+	return def
+}
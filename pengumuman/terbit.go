@@ -0,0 +1,105 @@
+package pengumuman
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"abs-be/models"
+	"abs-be/notifikasi"
+	"abs-be/outbox"
+
+	"gorm.io/gorm"
+)
+
+// Panjang ringkasan isi yang ikut di notifikasi.
+const ringkasanMaks = 160
+
+// Sebarkan mencatat penerima pengumuman yang baru terbit dan memasukkan
+// notifikasinya ke outbox, dalam tx yang sama dengan perubahan statusnya.
+// Penerima dihitung saat terbit sehingga anggota kelas yang berubah
+// setelahnya tidak ikut menerima.
+func Sebarkan(tx *gorm.DB, p *models.Pengumuman, now time.Time) (int, error) {
+	targets := p.Target
+	if targets == nil {
+		if err := tx.Where("pengumuman_id = ?", p.ID).Find(&targets).Error; err != nil {
+			return 0, fmt.Errorf("ambil target: %w", err)
+		}
+	}
+	penerima, err := Penerima(tx, targets, now)
+	if err != nil {
+		return 0, fmt.Errorf("hitung penerima: %w", err)
+	}
+	if len(penerima) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]models.PengumumanPenerima, 0, len(penerima))
+	perRole := map[string][]uint{}
+	for _, pr := range penerima {
+		rows = append(rows, models.PengumumanPenerima{PengumumanID: p.ID, UserType: pr.UserType, UserID: pr.ID})
+		perRole[pr.UserType] = append(perRole[pr.UserType], pr.ID)
+	}
+	if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+		return 0, fmt.Errorf("simpan penerima: %w", err)
+	}
+
+	if err := outbox.EnqueuePerRole(tx, notifikasi.Pengumuman{
+		PengumumanID: p.ID,
+		Judul:        p.Judul,
+		Ringkasan:    TeksPolos(p.Isi, ringkasanMaks),
+		Penulis:      p.AuthorNama,
+		DeepLink:     DeepLink(p.ID),
+	}, perRole); err != nil {
+		return 0, err
+	}
+	return len(penerima), nil
+}
+
+// DeepLink membangun payload navigasi aplikasi ke detail pengumuman.
+func DeepLink(id uint) map[string]interface{} {
+	scheme := os.Getenv("APP_DEEPLINK_SCHEME")
+	if scheme == "" {
+		scheme = "absapp"
+	}
+	return map[string]interface{}{
+		"screen": "pengumuman",
+		"params": map[string]interface{}{"pengumuman_id": id},
+		"uri":    fmt.Sprintf("%s://pengumuman?id=%d", scheme, id),
+	}
+}
+
+// TerbitkanTerjadwal menerbitkan pengumuman terjadwal yang waktunya sudah
+// tiba. Status diklaim dengan UPDATE bersyarat sehingga aman dijalankan
+// bersamaan oleh beberapa instance.
+func TerbitkanTerjadwal(db *gorm.DB, now time.Time) error {
+	var ids []uint
+	if err := db.Model(&models.Pengumuman{}).
+		Where("status = ? AND terbit_pada <= ?", "terjadwal", now).
+		Order("terbit_pada").Limit(100).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.Pengumuman{}).
+				Where("id = ? AND status = ?", id, "terjadwal").
+				Updates(map[string]interface{}{"status": "terbit", "diterbitkan_pada": now})
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			var p models.Pengumuman
+			if err := tx.Preload("Target").First(&p, id).Error; err != nil {
+				return err
+			}
+			_, err := Sebarkan(tx, &p, now)
+			return err
+		})
+		if err != nil {
+			log.Printf("pengumuman: gagal menerbitkan %d: %v", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Package realtime menyalurkan event notifikasi ke klien yang sedang
+// terhubung (SSE). Broker hanya hidup di dalam satu proses: klien yang
+// terhubung ke instance lain tidak menerima event dari instance ini.
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"abs-be/models"
+)
+
+const (
+	EventNotifikasi  = "notification"
+	EventDibaca      = "read"
+	EventDihapus     = "deleted"
+	EventUnreadCount = "unread_count"
+	// EventResync dikirim bila event sejak Last-Event-ID tidak lagi
+	// tersedia; klien sebaiknya memuat ulang daftar notifikasi.
+	EventResync = "resync"
+)
+
+type Event struct {
+	ID    uint64
+	Jenis string
+	Data  interface{}
+	waktu time.Time
+}
+
+// Langganan adalah satu koneksi klien. Events ditutup jika broker memutus
+// koneksi karena klien terlalu lambat membaca; klien lalu menyambung ulang
+// dengan Last-Event-ID.
+type Langganan struct {
+	pemilik models.Principal
+	ch      chan Event
+	broker  *Broker
+	once    sync.Once
+}
+
+func (l *Langganan) Events() <-chan Event { return l.ch }
+
+func (l *Langganan) Close() {
+	l.broker.lepas(l)
+}
+
+type riwayat struct {
+	events []Event
+	// dipangkas adalah ID terbesar yang sudah dibuang dari riwayat.
+	dipangkas uint64
+}
+
+type Broker struct {
+	mu         sync.Mutex
+	seq        uint64
+	mulai      uint64
+	subs       map[models.Principal]map[*Langganan]struct{}
+	riwayat    map[models.Principal]*riwayat
+	maxRiwayat int
+	umur       time.Duration
+	buffer     int
+	pangkasan  time.Time
+}
+
+// NewBroker menyimpan paling banyak maxRiwayat event per akun selama umur
+// untuk replay. ID event diawali dari waktu start (milidetik x 1000)
+// sehingga ID setelah restart selalu lebih besar dari sebelumnya.
+func NewBroker(maxRiwayat int, umur time.Duration, buffer int) *Broker {
+	seq := uint64(time.Now().UnixMilli()) * 1000
+	return &Broker{
+		seq:        seq,
+		mulai:      seq,
+		subs:       map[models.Principal]map[*Langganan]struct{}{},
+		riwayat:    map[models.Principal]*riwayat{},
+		maxRiwayat: maxRiwayat,
+		umur:       umur,
+		buffer:     buffer,
+	}
+}
+
+// Seq adalah ID event terakhir yang diterbitkan.
+func (b *Broker) Seq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Subscribe mendaftarkan koneksi baru. Jika punyaLast, event setelah
+// lastID dikembalikan untuk di-replay; lengkap bernilai false bila sebagian
+// event sudah tidak tersimpan (mis. setelah restart).
+func (b *Broker) Subscribe(p models.Principal, lastID uint64, punyaLast bool) (l *Langganan, replay []Event, lengkap bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	l = &Langganan{pemilik: p, ch: make(chan Event, b.buffer), broker: b}
+	if b.subs[p] == nil {
+		b.subs[p] = map[*Langganan]struct{}{}
+	}
+	b.subs[p][l] = struct{}{}
+
+	if !punyaLast {
+		return l, nil, true
+	}
+	if lastID < b.mulai || lastID > b.seq {
+		return l, nil, false
+	}
+	r := b.riwayat[p]
+	if r == nil {
+		return l, nil, true
+	}
+	if lastID < r.dipangkas {
+		return l, nil, false
+	}
+	for _, ev := range r.events {
+		if ev.ID > lastID {
+			replay = append(replay, ev)
+		}
+	}
+	return l, replay, true
+}
+
+func (b *Broker) lepas(l *Langganan) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lepasLocked(l)
+}
+
+func (b *Broker) lepasLocked(l *Langganan) {
+	if set, ok := b.subs[l.pemilik]; ok {
+		delete(set, l)
+		if len(set) == 0 {
+			delete(b.subs, l.pemilik)
+		}
+	}
+	l.once.Do(func() { close(l.ch) })
+}
+
+// Publish menerbitkan event ke semua koneksi milik p dan menyimpannya
+// untuk replay.
+func (b *Broker) Publish(p models.Principal, jenis string, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.seq++
+	ev := Event{ID: b.seq, Jenis: jenis, Data: data, waktu: now}
+
+	r := b.riwayat[p]
+	if r == nil {
+		r = &riwayat{}
+		b.riwayat[p] = r
+	}
+	r.events = append(r.events, ev)
+	if len(r.events) > b.maxRiwayat {
+		buang := len(r.events) - b.maxRiwayat
+		r.dipangkas = r.events[buang-1].ID
+		r.events = append([]Event(nil), r.events[buang:]...)
+	}
+	b.pangkasLocked(now)
+
+	for l := range b.subs[p] {
+		select {
+		case l.ch <- ev:
+		default:
+			// klien lambat: putuskan, ia akan replay dari Last-Event-ID
+			b.lepasLocked(l)
+		}
+	}
+}
+
+// pangkasLocked membuang riwayat yang lebih tua dari umur, paling sering
+// sekali per menit.
+func (b *Broker) pangkasLocked(now time.Time) {
+	if now.Sub(b.pangkasan) < time.Minute {
+		return
+	}
+	b.pangkasan = now
+	batas := now.Add(-b.umur)
+	for _, r := range b.riwayat {
+		i := 0
+		for i < len(r.events) && r.events[i].waktu.Before(batas) {
+			i++
+		}
+		if i == 0 {
+			continue
+		}
+		r.dipangkas = r.events[i-1].ID
+		// entri tetap disimpan walau kosong agar dipangkas tetap terbaca
+		r.events = append([]Event(nil), r.events[i:]...)
+	}
+}
@@ -0,0 +1,118 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"abs-be/database"
+	"abs-be/models"
+	"abs-be/requests"
+)
+
+var (
+	defaultOnce   sync.Once
+	defaultBroker *Broker
+)
+
+// Default adalah broker yang dipakai aplikasi, dibuat saat pertama dipakai
+// (setelah .env dimuat). Dikonfigurasi lewat NOTIF_SSE_RIWAYAT (event per
+// akun, default 100), NOTIF_SSE_RIWAYAT_MENIT (default 15) dan
+// NOTIF_SSE_BUFFER (antrean event per koneksi, default 64).
+func Default() *Broker {
+	defaultOnce.Do(func() {
+		defaultBroker = NewBroker(
+			envInt("NOTIF_SSE_RIWAYAT", 100),
+			time.Duration(envInt("NOTIF_SSE_RIWAYAT_MENIT", 15))*time.Minute,
+			envInt("NOTIF_SSE_BUFFER", 64),
+		)
+	})
+	return defaultBroker
+}
+
+func Publish(p models.Principal, jenis string, data interface{}) {
+	Default().Publish(p, jenis, data)
+}
+
+func PublishNotifikasi(notifs []models.Notification) {
+	penerima := map[models.Principal]struct{}{}
+	for _, n := range notifs {
+		p := models.Principal{UserType: n.RecipientType, ID: n.Recipient}
+		Publish(p, EventNotifikasi, ItemNotifikasi(n))
+		penerima[p] = struct{}{}
+	}
+	for p := range penerima {
+		PublishUnread(p)
+	}
+}
+
+// PublishUnread menghitung ulang notifikasi belum dibaca milik p lalu
+// menerbitkannya.
+func PublishUnread(p models.Principal) {
+	n, err := UnreadCount(p)
+	if err != nil {
+		log.Printf("realtime: gagal hitung unread %s/%d: %v", p.UserType, p.ID, err)
+		return
+	}
+	Publish(p, EventUnreadCount, map[string]int64{"unread": n})
+}
+
+func UnreadCount(p models.Principal) (int64, error) {
+	var n int64
+	err := database.DB.Model(&models.Notification{}).
+		Where("recipient_type = ? AND recipient = ? AND `read` = ?", p.UserType, p.ID, false).
+		Count(&n).Error
+	return n, err
+}
+
+// ItemNotifikasi mengubah baris notifications ke bentuk yang sama dengan
+// GET /api/notifications.
+func ItemNotifikasi(n models.Notification) requests.NotificationItemResponse {
+	var payload map[string]interface{}
+	if n.Payload != "" {
+		if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+			payload = map[string]interface{}{"_raw": n.Payload}
+		}
+	}
+	return requests.NotificationItemResponse{
+		ID:        n.ID,
+		Title:     n.Title,
+		Body:      n.Body,
+		Type:      n.Type,
+		Payload:   payload,
+		Read:      n.Read,
+		CreatedAt: n.CreatedAt,
+		UpdatedAt: n.UpdatedAt,
+	}
+}
+
+// TulisSSE menulis satu event dalam format text/event-stream.
+func TulisSSE(w io.Writer, ev Event) error {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Jenis, data)
+	return err
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("realtime: nilai %s tidak valid (%q), memakai default %d", key, v, def)
+	}
+	return def
+}
+
+// Heartbeat adalah jeda komentar ping pada stream agar proxy tidak memutus
+// koneksi yang diam (NOTIF_SSE_HEARTBEAT_DETIK, default 25).
+func Heartbeat() time.Duration {
+	return time.Duration(envInt("NOTIF_SSE_HEARTBEAT_DETIK", 25)) * time.Second
+}
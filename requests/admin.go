@@ -1,8 +1,35 @@
 package requests
 
+// AdminRegisterRequest dipakai pendaftaran admin lewat undangan.
 type AdminRegisterRequest struct {
 	Nama            string `json:"nama" binding:"required"`
 	Email           string `json:"email" binding:"required,email"`
 	Password        string `json:"password" binding:"required,min=6"`
 	ConfirmPassword string `json:"confirm_password" binding:"required"`
+	InviteToken     string `json:"invite_token" binding:"required"`
+}
+
+// AdminBootstrapRequest dipakai untuk membuat admin pertama.
+type AdminBootstrapRequest struct {
+	Nama            string `json:"nama" binding:"required"`
+	Email           string `json:"email" binding:"required,email"`
+	Password        string `json:"password" binding:"required,min=6"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+	BootstrapToken  string `json:"bootstrap_token" binding:"required"`
+}
+
+type CreateAdminRequest struct {
+	Nama     string `json:"nama" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type UpdateAdminRequest struct {
+	Nama  string `json:"nama"`
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+type AdminInviteRequest struct {
+	Email      string `json:"email" binding:"omitempty,email"`
+	BerlakuJam int    `json:"berlaku_jam" binding:"omitempty,min=1,max=168"`
 }
@@ -0,0 +1,5 @@
+package requests
+
+type RolePermissionRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
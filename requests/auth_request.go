@@ -15,3 +15,48 @@ type AllLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 	Role     string `json:"role" binding:"required"`
 }
+
+// LogoutRequest bersifat opsional; device_token diisi agar perangkat yang
+// logout berhenti menerima push.
+type LogoutRequest struct {
+	DeviceToken string `json:"device_token"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ActiveRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	PasswordLama       string `json:"password_lama" binding:"required"`
+	PasswordBaru       string `json:"password_baru" binding:"required,min=8,max=72"`
+	KonfirmasiPassword string `json:"konfirmasi_password" binding:"required"`
+}
+
+// ForgotPasswordRequest dan ResetPasswordRequest: user_type hanya perlu
+// diisi bila email yang sama terdaftar di lebih dari satu jenis akun.
+type ForgotPasswordRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	UserType string `json:"user_type" binding:"omitempty,oneof=admin guru siswa orang_tua"`
+}
+
+type ResetPasswordRequest struct {
+	Email              string `json:"email" binding:"required,email"`
+	UserType           string `json:"user_type" binding:"omitempty,oneof=admin guru siswa orang_tua"`
+	Kode               string `json:"kode" binding:"required"`
+	PasswordBaru       string `json:"password_baru" binding:"required,min=8,max=72"`
+	KonfirmasiPassword string `json:"konfirmasi_password" binding:"required"`
+}
+
+// LoginRequest menerima identifier berupa email, NISN (siswa) atau NIP
+// (guru). Field email tetap diterima untuk klien lama. user_type hanya
+// perlu diisi bila identifier cocok dengan lebih dari satu akun.
+type LoginRequest struct {
+	Identifier string `json:"identifier" binding:"max=100"`
+	Email      string `json:"email" binding:"omitempty,email"`
+	Password   string `json:"password" binding:"required"`
+	UserType   string `json:"user_type" binding:"omitempty,oneof=admin guru siswa orang_tua"`
+}
@@ -0,0 +1,7 @@
+package requests
+
+type DeviceTokenRequest struct {
+	Token      string `json:"token" binding:"required,max=512"`
+	Platform   string `json:"platform" binding:"max=50"`    // android, ios, web
+	AppVersion string `json:"app_version" binding:"max=50"` // mis. 1.4.2
+}
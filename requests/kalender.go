@@ -0,0 +1,14 @@
+package requests
+
+type HariLiburRequest struct {
+	TanggalMulai   string `json:"tanggal_mulai" binding:"required"`    // format: YYYY-MM-DD
+	TanggalSelesai string `json:"tanggal_selesai" binding:"omitempty"` // default: sama dengan tanggal_mulai
+	Keterangan     string `json:"keterangan" binding:"required"`
+}
+
+type PembatalanJadwalRequest struct {
+	MapelID uint   `json:"mapel_id" binding:"required"`
+	KelasID uint   `json:"kelas_id" binding:"required"`
+	Tanggal string `json:"tanggal" binding:"required"` // format: YYYY-MM-DD
+	Alasan  string `json:"alasan"`
+}
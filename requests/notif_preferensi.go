@@ -0,0 +1,37 @@
+package requests
+
+type NotifPreferensiItem struct {
+	Type    string `json:"type" binding:"required"`
+	Channel string `json:"channel" binding:"required"` // inapp, push, email, sms, whatsapp, atau semua
+	Aktif   *bool  `json:"aktif" binding:"required"`
+}
+
+type NotifPreferensiRequest struct {
+	Items []NotifPreferensiItem `json:"items" binding:"required,min=1,dive"`
+}
+
+type NotifJamTenangRequest struct {
+	Mulai   string `json:"mulai" binding:"required"`   // format: HH:MM
+	Selesai string `json:"selesai" binding:"required"` // format: HH:MM
+	Aktif   *bool  `json:"aktif" binding:"required"`
+}
+
+type NotifBahasaRequest struct {
+	Bahasa string `json:"bahasa" binding:"required"` // id atau en
+}
+
+type NotifTipeWajibRequest struct {
+	Type string `json:"type" binding:"required"`
+}
+
+type NotifPreferensiTypeResponse struct {
+	Type     string          `json:"type"`
+	Wajib    bool            `json:"wajib"`
+	Channels map[string]bool `json:"channels"`
+}
+
+type NotifDigestRequest struct {
+	Frekuensi string   `json:"frekuensi" binding:"required,oneof=harian per_jam"`
+	Jam       string   `json:"jam" binding:"required"` // format: HH:MM; untuk per_jam hanya menitnya yang dipakai
+	Types     []string `json:"types"`                  // kosong = digest dimatikan
+}
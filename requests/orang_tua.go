@@ -0,0 +1,29 @@
+package requests
+
+type CreateOrangTuaRequest struct {
+	Nama     string `json:"nama" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Telepon  string `json:"telepon"`
+	Password string `json:"password" binding:"required,min=6"`
+	SiswaIDs []uint `json:"siswa_ids"`
+}
+
+type UpdateOrangTuaRequest struct {
+	Nama    string `json:"nama" binding:"required"`
+	Email   string `json:"email" binding:"required,email"`
+	Telepon string `json:"telepon"`
+}
+
+type LinkOrangTuaSiswaRequest struct {
+	SiswaID uint `json:"siswa_id" binding:"required"`
+}
+
+type RekapAbsensiResponse struct {
+	SiswaID   uint                        `json:"siswa_id"`
+	Dari      string                      `json:"dari"`
+	Sampai    string                      `json:"sampai"`
+	Total     int64                       `json:"total"`
+	PerStatus map[string]int64            `json:"per_status"`
+	PerTipe   map[string]map[string]int64 `json:"per_tipe"`
+	Kehadiran float64                     `json:"persentase_kehadiran"`
+}
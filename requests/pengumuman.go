@@ -0,0 +1,49 @@
+package requests
+
+import "time"
+
+type PengumumanTargetRequest struct {
+	Jenis string `json:"jenis" binding:"required"` // role, kelas, tingkat, user
+	Nilai string `json:"nilai" binding:"required"`
+	Peran string `json:"peran"` // wajib untuk kelas/tingkat (siswa, orang_tua, guru, wali_kelas) dan user (jenis user)
+}
+
+// CreatePengumumanRequest dikirim sebagai multipart/form-data agar bisa
+// membawa file pada field "lampiran".
+type CreatePengumumanRequest struct {
+	Judul      string `form:"judul" binding:"required,max=200"`
+	Isi        string `form:"isi" binding:"required"`    // HTML
+	Target     string `form:"target" binding:"required"` // JSON array PengumumanTargetRequest
+	TerbitPada string `form:"terbit_pada"`               // format: YYYY-MM-DD HH:MM; kosong = terbit sekarang
+}
+
+type PengumumanItemResponse struct {
+	ID              uint       `json:"id"`
+	Judul           string     `json:"judul"`
+	Ringkasan       string     `json:"ringkasan"`
+	AuthorNama      string     `json:"author_nama"`
+	AuthorRole      string     `json:"author_role"`
+	DiterbitkanPada *time.Time `json:"diterbitkan_pada"`
+	Dibaca          bool       `json:"dibaca"`
+	DibacaPada      *time.Time `json:"dibaca_pada,omitempty"`
+}
+
+type PengumumanDibuatResponse struct {
+	ID              uint       `json:"id"`
+	Judul           string     `json:"judul"`
+	AuthorNama      string     `json:"author_nama"`
+	AuthorRole      string     `json:"author_role"`
+	Status          string     `json:"status"`
+	TerbitPada      *time.Time `json:"terbit_pada,omitempty"`
+	DiterbitkanPada *time.Time `json:"diterbitkan_pada,omitempty"`
+	TotalPenerima   int64      `json:"total_penerima"`
+	TotalDibaca     int64      `json:"total_dibaca"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type PembacaPengumumanResponse struct {
+	UserType   string     `json:"user_type"`
+	UserID     uint       `json:"user_id"`
+	Nama       string     `json:"nama"`
+	DibacaPada *time.Time `json:"dibaca_pada"`
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"abs-be/akses"
 	tc "abs-be/controllers"
 	"abs-be/middlewares"
 
@@ -12,9 +13,24 @@ func Api(r *gin.Engine) {
 	api.POST("/login", tc.LoginAutoRole)    // dipake
 	api.POST("/guru/login", tc.LoginGuru)   // ga dipake (cuma testing)
 	api.POST("/admin/login", tc.LoginAdmin) // ga dipake (cuma testing)
-	api.POST("/admin/register", tc.RegisterAdmin)
+	api.POST("/admin/register", tc.RegisterAdmin)   // wajib invite_token
+	api.POST("/admin/bootstrap", tc.BootstrapAdmin) // admin pertama
 	api.POST("/wali-kelas/login", tc.LoginWaliKelas) // ga dipake (cuma testing)
 	api.POST("/logout", middlewares.AuthMiddleware(), tc.Logout)
+	api.POST("/token/refresh", tc.RefreshToken)
+	api.POST("/session/active-role", middlewares.AuthMiddleware(), tc.SwitchActiveRole)
+	api.GET("/sessions", middlewares.AuthMiddleware(), tc.GetMySessions)
+	api.DELETE("/sessions", middlewares.AuthMiddleware(), tc.DeleteOtherSessions)
+	api.DELETE("/sessions/:id", middlewares.AuthMiddleware(), tc.DeleteMySession)
+	api.POST("/password/change", middlewares.AuthMiddleware(), tc.ChangePassword)
+	api.POST("/password/forgot", tc.ForgotPassword)
+	api.POST("/password/reset", tc.ResetPassword)
+	api.POST("/admin/users/:user_type/:id/reset-password", middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.AkunResetPassword), tc.AdminResetPassword)
+	api.POST("/admin/users/:user_type/:id/unlock", middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.AkunResetPassword), tc.AdminUnlockAkun)
+	api.POST("/admin/users/:user_type/:id/disable", middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.AkunDisable), tc.DisableAkun)
+	api.POST("/admin/users/:user_type/:id/enable", middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.AkunDisable), tc.EnableAkun)
+	api.DELETE("/admin/users/:user_type/:id/sessions", middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.SesiRevoke), tc.AdminRevokeSessions)
+	api.GET("/login-history", middlewares.AuthMiddleware(), tc.GetLoginHistory)
 
 	api.GET("/dashboard-guru", middlewares.AuthMiddleware(), middlewares.RoleMiddleware("guru"), func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "dashboard Guru"}) // ga dipake (cuma testing)
@@ -34,7 +50,7 @@ func Api(r *gin.Engine) {
 	})
 
 	guru := api.Group("/guru")
-	guru.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin"))
+	guru.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.GuruManage))
 	{
 		guru.GET("/", tc.GetAllGurus)
 		guru.GET("/:id", tc.GetGuruByID)
@@ -54,6 +70,7 @@ func Api(r *gin.Engine) {
 	pengajar.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("guru"))
 	{
 		pengajar.GET("/list-kelas", tc.GetPengajaranGuru)
+		pengajar.GET("/sesi-hari-ini", tc.GetSesiHariIni)
 	}
 
 	waliKelas := api.Group("/wali-kelas")
@@ -65,7 +82,7 @@ func Api(r *gin.Engine) {
 	}
 
 	kelas := api.Group("/kelas")
-	kelas.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin"))
+	kelas.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.KelasManage))
 	{
 		kelas.POST("/", tc.CreateKelas)
 		kelas.GET("/", tc.GetAllKelas)
@@ -75,7 +92,7 @@ func Api(r *gin.Engine) {
 	}
 
 	mapel := api.Group("/mapel")
-	mapel.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin"))
+	mapel.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.MapelManage))
 	{
 		mapel.POST("/", tc.CreateMataPelajaran)
 		mapel.GET("/", tc.GetAllMataPelajaran)
@@ -85,7 +102,7 @@ func Api(r *gin.Engine) {
 	}
 
 	siswa := api.Group("/siswa")
-	siswa.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin"))
+	siswa.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.SiswaManage))
 	{
 		siswa.POST("/", tc.CreateSiswa)
 		siswa.GET("/", tc.GetAllSiswa)
@@ -93,10 +110,15 @@ func Api(r *gin.Engine) {
 		siswa.GET("/:id", tc.GetSiswaByID)
 		siswa.PUT("/:id", tc.UpdateSiswa)
 		siswa.DELETE("/:id", tc.DeleteSiswa)
-		siswa.POST("/assign-kelas", tc.AssignSiswaToKelas)
-		siswa.POST("/unassign-kelas", tc.UnassignSiswaFromKelas)
-		siswa.POST("/assign-mapel", tc.AssignSiswaToMapel)
-		siswa.POST("/unassign-mapel", tc.UnassignSiswaFromMapel)
+	}
+
+	penempatanSiswa := api.Group("/siswa")
+	penempatanSiswa.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.SiswaAssign))
+	{
+		penempatanSiswa.POST("/assign-kelas", tc.AssignSiswaToKelas)
+		penempatanSiswa.POST("/unassign-kelas", tc.UnassignSiswaFromKelas)
+		penempatanSiswa.POST("/assign-mapel", tc.AssignSiswaToMapel)
+		penempatanSiswa.POST("/unassign-mapel", tc.UnassignSiswaFromMapel)
 	}
 
 	siswaaja := api.Group("/siswa")
@@ -106,20 +128,43 @@ func Api(r *gin.Engine) {
 		siswaaja.GET("/absensi", tc.GetAbsensiSiswa)
 	}
 
+	orangTua := api.Group("/orang-tua")
+	orangTua.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.OrangTuaManage))
+	{
+		orangTua.POST("/", tc.CreateOrangTua)
+		orangTua.GET("/", tc.GetAllOrangTua)
+		orangTua.GET("/:id", tc.GetOrangTuaByID)
+		orangTua.PUT("/:id", tc.UpdateOrangTua)
+		orangTua.DELETE("/:id", tc.DeleteOrangTua)
+		orangTua.POST("/:id/siswa", tc.LinkOrangTuaSiswa)
+		orangTua.DELETE("/:id/siswa/:siswa_id", tc.UnlinkOrangTuaSiswa)
+	}
+
+	ortuaja := api.Group("/orang-tua/anak")
+	ortuaja.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("orang_tua"))
+	{
+		ortuaja.GET("/", tc.GetAnakOrangTua)
+		ortuaja.GET("/:siswa_id/profil", tc.GetProfilAnak)
+		ortuaja.GET("/:siswa_id/absensi", tc.GetAbsensiAnak)
+		ortuaja.GET("/:siswa_id/rekap", tc.GetRekapAbsensiAnak)
+	}
+
+	// Cakupan baca (kelas/mapel mana) diperiksa lagi di handler.
+	bacaAbsensi := middlewares.PermissionMiddleware(akses.AbsensiReadAll, akses.AbsensiReadKelas, akses.AbsensiReadMapel)
 	absensi := api.Group("/absensi")
-	absensi.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin", "guru", "wali_kelas"))
+	absensi.Use(middlewares.AuthMiddleware())
 	{
-		absensi.POST("/", tc.CreateAbsensiSiswa)
-		absensi.GET("/", tc.GetAbsensi)
-		absensi.GET("/:id", tc.GetAbsensiByID)
-		absensi.PUT("/:id", tc.UpdateAbsensiSiswa)
-		absensi.DELETE("/:id", tc.DeleteAbsensiSiswa)
-		absensi.GET("/list/mapel", tc.ListStudentsForMapel)
-		absensi.GET("/list/kelas", tc.ListStudentsForKelas)
-		absensi.GET("/rekap/mapel", tc.RecapAbsensiMapel)
-		absensi.GET("/rekap/kelas", tc.RecapAbsensiKelas)
-		absensi.GET("/rekap/mapel/export", tc.ExportRecapAbsensiMapelCSV)
-		absensi.GET("/rekap/kelas/export", tc.ExportRecapAbsensiKelasCSV)
+		absensi.POST("/", middlewares.PermissionMiddleware(akses.AbsensiCreateMapel, akses.AbsensiCreateKelas), tc.CreateAbsensiSiswa)
+		absensi.GET("/", bacaAbsensi, tc.GetAbsensi)
+		absensi.GET("/:id", bacaAbsensi, tc.GetAbsensiByID)
+		absensi.PUT("/:id", middlewares.PermissionMiddleware(akses.AbsensiUpdateAll, akses.AbsensiUpdateOwn, akses.AbsensiUpdateKelas), tc.UpdateAbsensiSiswa)
+		absensi.DELETE("/:id", middlewares.PermissionMiddleware(akses.AbsensiDeleteAll, akses.AbsensiDeleteOwn, akses.AbsensiDeleteKelas), tc.DeleteAbsensiSiswa)
+		absensi.GET("/list/mapel", bacaAbsensi, tc.ListStudentsForMapel)
+		absensi.GET("/list/kelas", bacaAbsensi, tc.ListStudentsForKelas)
+		absensi.GET("/rekap/mapel", bacaAbsensi, tc.RecapAbsensiMapel)
+		absensi.GET("/rekap/kelas", bacaAbsensi, tc.RecapAbsensiKelas)
+		absensi.GET("/rekap/mapel/export", middlewares.PermissionMiddleware(akses.AbsensiExport), tc.ExportRecapAbsensiMapelCSV)
+		absensi.GET("/rekap/kelas/export", middlewares.PermissionMiddleware(akses.AbsensiExport), tc.ExportRecapAbsensiKelasCSV)
 	}
 
 	todo := api.Group("/todo")
@@ -132,20 +177,119 @@ func Api(r *gin.Engine) {
 	}
 
 	testnotif := api.Group("/user")
-	testnotif.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin", "guru", "wali_kelas", "siswa"))
+	testnotif.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("admin", "guru", "wali_kelas", "siswa", "orang_tua"))
 	{
 		testnotif.POST("/device-tokens", tc.RegisterDeviceToken)
+		testnotif.GET("/device-tokens", tc.GetMyDeviceTokens)
+		testnotif.DELETE("/device-tokens/:id", tc.DeleteMyDeviceToken)
 	}
 
+	api.GET("/kalender/feed/:token", tc.GetKalenderFeed)
+
+	kalender := api.Group("/kalender")
+	kalender.Use(middlewares.AuthMiddleware(), middlewares.RoleMiddleware("guru", "wali_kelas", "siswa"))
+	{
+		kalender.POST("/token", tc.CreateKalenderToken)
+		kalender.DELETE("/token", tc.RevokeKalenderToken)
+	}
+
+	kalenderAdmin := api.Group("/kalender")
+	kalenderAdmin.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.KalenderManage))
+	{
+		kalenderAdmin.POST("/hari-libur", tc.CreateHariLibur)
+		kalenderAdmin.GET("/hari-libur", tc.GetHariLibur)
+		kalenderAdmin.DELETE("/hari-libur/:id", tc.DeleteHariLibur)
+		kalenderAdmin.POST("/pembatalan", tc.CreatePembatalanJadwal)
+		kalenderAdmin.GET("/pembatalan", tc.GetPembatalanJadwal)
+		kalenderAdmin.DELETE("/pembatalan/:id", tc.DeletePembatalanJadwal)
+	}
+
+	pengumumanAPI := api.Group("/pengumuman")
+	pengumumanAPI.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.PengumumanRead))
+	{
+		pengumumanAPI.GET("/", tc.GetPengumuman)
+		pengumumanAPI.GET("/:id", tc.GetPengumumanByID)
+		pengumumanAPI.PATCH("/:id/read", tc.MarkPengumumanDibaca)
+		pengumumanAPI.GET("/:id/lampiran/:lampiranID", tc.DownloadLampiranPengumuman)
+	}
+
+	pengumumanPenulis := api.Group("/pengumuman")
+	pengumumanPenulis.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.PengumumanAll, akses.PengumumanKelas))
+	{
+		pengumumanPenulis.POST("/", tc.CreatePengumuman)
+		pengumumanPenulis.GET("/dibuat", tc.GetPengumumanDibuat)
+		pengumumanPenulis.GET("/:id/pembaca", tc.GetPembacaPengumuman)
+		pengumumanPenulis.DELETE("/:id", tc.DeletePengumuman)
+	}
+
+	api.GET("/notifications/stream", middlewares.StreamTicketMiddleware(), middlewares.AuthMiddleware(), tc.StreamNotifications)
+
 	notif := api.Group("/notifications")
 	notif.Use(middlewares.AuthMiddleware())
 	{
 		notif.GET("/", tc.GetNotifications)
+		notif.POST("/stream/ticket", tc.CreateStreamTicket)
 		notif.PATCH("/:id/read", tc.MarkNotificationRead)
 		notif.PATCH("/mark-read", tc.MarkNotificationsRead)
 		notif.PATCH("/mark-all-read", tc.MarkAllRead)
 		notif.DELETE("/:id", tc.DeleteNotification) 
 		notif.DELETE("/", tc.DeleteNotificationsBulk)
 		notif.DELETE("/all", tc.DeleteAllNotifications)
+		notif.GET("/preferensi", tc.GetNotifPreferensi)
+		notif.PUT("/preferensi", tc.UpdateNotifPreferensi)
+		notif.PUT("/jam-tenang", tc.UpdateNotifJamTenang)
+		notif.PUT("/bahasa", tc.UpdateNotifBahasa)
+		notif.GET("/digest", tc.GetNotifDigest)
+		notif.PUT("/digest", tc.UpdateNotifDigest)
+	}
+
+	notifWajib := api.Group("/notifications/wajib")
+	notifWajib.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.NotifManage))
+	{
+		notifWajib.GET("/", tc.GetNotifTipeWajib)
+		notifWajib.POST("/", tc.CreateNotifTipeWajib)
+		notifWajib.DELETE("/:type", tc.DeleteNotifTipeWajib)
+	}
+
+	notifOutbox := api.Group("/notifications/outbox")
+	notifOutbox.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.NotifManage))
+	{
+		notifOutbox.GET("/", tc.GetNotifOutbox)
+		notifOutbox.GET("/:id", tc.GetNotifOutboxByID)
+		notifOutbox.POST("/:id/redrive", tc.RedriveNotifOutbox)
+		notifOutbox.POST("/redrive-dead", tc.RedriveNotifOutboxDead)
+		notifOutbox.GET("/:id/receipts", tc.GetNotifOutboxReceipts)
+	}
+
+	notifReceipt := api.Group("/notifications/receipts")
+	notifReceipt.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.NotifManage))
+	{
+		notifReceipt.GET("/", tc.GetNotifReceipts)
+	}
+
+	rbac := api.Group("/admin")
+	rbac.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.RBACManage))
+	{
+		rbac.GET("/permissions", tc.GetPermissions)
+		rbac.GET("/roles/permissions", tc.GetRolePermissions)
+		rbac.PUT("/roles/:role/permissions", tc.SetRolePermissions)
+	}
+
+	admins := api.Group("/admins")
+	admins.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.AdminManage))
+	{
+		admins.GET("/", tc.GetAdmins)
+		admins.GET("/:id", tc.GetAdminByID)
+		admins.POST("/", tc.CreateAdmin)
+		admins.PUT("/:id", tc.UpdateAdmin)
+		admins.DELETE("/:id", tc.DeleteAdmin)
+	}
+
+	adminInvites := api.Group("/admin/invites")
+	adminInvites.Use(middlewares.AuthMiddleware(), middlewares.PermissionMiddleware(akses.AdminManage))
+	{
+		adminInvites.GET("/", tc.GetAdminInvites)
+		adminInvites.POST("/", tc.CreateAdminInvite)
+		adminInvites.DELETE("/:id", tc.DeleteAdminInvite)
 	}
 }
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Data zona waktu ikut dibundel agar TZID feed tetap bisa dimuat di
+	// container tanpa paket tzdata.
+	_ "time/tzdata"
+)
+
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	RRule       string
+	ExDates     []time.Time
+}
+
+func icalEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// icalFold memotong baris lebih dari 75 oktet sesuai RFC 5545.
+func icalFold(line string) string {
+	if len(line) <= 75 {
+		return line + "\r\n"
+	}
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// icalVTimezone menulis VTIMEZONE untuk loc dengan satu komponen STANDARD
+// memakai offset loc saat acuan. Cukup untuk zona tanpa DST seperti zona
+// waktu Indonesia.
+func icalVTimezone(write func(string), loc *time.Location, acuan time.Time) {
+	nama, detik := acuan.In(loc).Zone()
+	tanda := "+"
+	if detik < 0 {
+		tanda, detik = "-", -detik
+	}
+	offset := fmt.Sprintf("%s%02d%02d", tanda, detik/3600, detik%3600/60)
+
+	write("BEGIN:VTIMEZONE")
+	write("TZID:" + loc.String())
+	write("BEGIN:STANDARD")
+	write("DTSTART:19700101T000000")
+	write("TZOFFSETFROM:" + offset)
+	write("TZOFFSETTO:" + offset)
+	write("TZNAME:" + nama)
+	write("END:STANDARD")
+	write("END:VTIMEZONE")
+}
+
+// BuildICalendar menyusun feed iCalendar. Waktu event ditulis dengan TZID
+// loc (disertai VTIMEZONE-nya) agar tidak dibaca sebagai waktu lokal
+// perangkat pelanggan; RRULE dengan UNTIL harus memakai waktu UTC.
+func BuildICalendar(nama string, loc *time.Location, events []ICalEvent) string {
+	const lokal = "20060102T150405"
+	const tanggal = "20060102"
+	tzid := ";TZID=" + loc.String()
+
+	var b strings.Builder
+	write := func(line string) { b.WriteString(icalFold(line)) }
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//abs-be//Jadwal Sekolah//ID")
+	write("CALSCALE:GREGORIAN")
+	write("METHOD:PUBLISH")
+	write("X-WR-CALNAME:" + icalEscape(nama))
+	write("X-WR-TIMEZONE:" + loc.String())
+	icalVTimezone(write, loc, time.Now())
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, ev := range events {
+		write("BEGIN:VEVENT")
+		write("UID:" + ev.UID)
+		write("DTSTAMP:" + stamp)
+		if ev.AllDay {
+			write("DTSTART;VALUE=DATE:" + ev.Start.In(loc).Format(tanggal))
+			write("DTEND;VALUE=DATE:" + ev.End.In(loc).Format(tanggal))
+			write("TRANSP:TRANSPARENT")
+		} else {
+			write("DTSTART" + tzid + ":" + ev.Start.In(loc).Format(lokal))
+			write("DTEND" + tzid + ":" + ev.End.In(loc).Format(lokal))
+		}
+		if ev.RRule != "" {
+			write("RRULE:" + ev.RRule)
+		}
+		for _, ex := range ev.ExDates {
+			write("EXDATE" + tzid + ":" + ex.In(loc).Format(lokal))
+		}
+		write("SUMMARY:" + icalEscape(ev.Summary))
+		if ev.Description != "" {
+			write("DESCRIPTION:" + icalEscape(ev.Description))
+		}
+		if ev.Location != "" {
+			write("LOCATION:" + icalEscape(ev.Location))
+		}
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return b.String()
+}
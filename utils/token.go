@@ -1,17 +1,52 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(userID uint, role string) (string, error) {
+// AccessTokenTTL adalah umur access token, diatur lewat
+// JWT_ACCESS_TTL_MENIT (default 15 menit).
+func AccessTokenTTL() time.Duration {
+	return envDurasi("JWT_ACCESS_TTL_MENIT", 15, time.Minute)
+}
+
+// RefreshTokenTTL adalah umur refresh token, diatur lewat
+// JWT_REFRESH_TTL_HARI (default 30 hari).
+func RefreshTokenTTL() time.Duration {
+	return envDurasi("JWT_REFRESH_TTL_HARI", 30, 24*time.Hour)
+}
+
+func envDurasi(key string, def int, satuan time.Duration) time.Duration {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return time.Duration(n) * satuan
+	}
+	return time.Duration(def) * satuan
+}
+
+// GenerateToken membuat access token untuk role aktif. roles, bila diisi,
+// adalah semua role sesi dan ikut dimasukkan ke claims.
+func GenerateToken(userID uint, role string, roles ...string) (string, error) {
+	// jti membuat setiap token unik walau dibuat pada detik yang sama,
+	// karena token disimpan sebagai kunci unik di tabel sessions.
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"role":    role,
-		"exp":     time.Now().Add(1 * time.Hour).Unix(),
+		"jti":     jti,
+		"exp":     time.Now().Add(AccessTokenTTL()).Unix(),
+	}
+	if len(roles) > 0 {
+		claims["roles"] = roles
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -22,3 +57,42 @@ func GenerateToken(userID uint, role string) (string, error) {
 
 	return token.SignedString([]byte(secret))
 }
+
+// ExpToken membaca claim exp sebuah access token tanpa memverifikasi tanda
+// tangannya; hanya untuk token yang sudah tersimpan di sesi.
+func ExpToken(tokenString string) (time.Time, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return time.Time{}, err
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if exp == nil {
+		return time.Time{}, jwt.ErrTokenInvalidClaims
+	}
+	return exp.Time, nil
+}
+
+// GenerateNumericCode menghasilkan kode angka acak sepanjang digits, mis.
+// untuk kode reset password.
+func GenerateNumericCode(digits int) (string, error) {
+	b := make([]byte, digits)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + n.Int64())
+	}
+	return string(b), nil
+}
+
+func GenerateRandomToken(nBytes int) (string, error) {
+	b := make([]byte, nBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}